@@ -0,0 +1,44 @@
+// Command backfill-arr-dates stores the parsed start time of arr whose free
+// form start_date has not been interpreted yet. Run it from the repository
+// root after applying db/2026-10-18-arr-starts-at.sql:
+//
+//	go run ./cmd/backfill-arr-dates [-all]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/logger"
+)
+
+func main() {
+	all := flag.Bool("all", false, "re-parse every arr, not only those without a start time")
+	flag.Parse()
+
+	logger.SetupLogging()
+	config.Init()
+
+	db, err := data.NewDatabase()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	updated, unparsed, err := db.BackfillArrStartsAt(*all)
+	if err != nil {
+		log.Fatalf("backfill stopped after %d arr: %v", updated, err)
+	}
+
+	fmt.Printf("%d arr given a start time\n", updated)
+	if len(unparsed) > 0 {
+		fmt.Printf("%d start dates could not be interpreted:\n", len(unparsed))
+		for _, arr := range unparsed {
+			fmt.Printf("  %d\t%q\n", arr.Id, *arr.StartDate)
+		}
+	}
+}
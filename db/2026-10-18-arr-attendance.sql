@@ -0,0 +1,41 @@
+-- Attendance of members at arr. The deltagare, kanske and hetsade columns
+-- are still written for old clients, and also list guests who are not
+-- members.
+CREATE TABLE IF NOT EXISTS `cl_arr_attendance` (
+    `arr_id`        INT         NOT NULL,
+    `member_number` BIGINT      NOT NULL,
+    `status`        VARCHAR(8)  NOT NULL,
+    `updated_at`    DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`arr_id`, `member_number`),
+    INDEX `idx_member_number` (`member_number`),
+    CONSTRAINT `chk_arr_attendance_status` CHECK (`status` IN ('yes', 'maybe', 'hetsad'))
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- The lists overflowed varchar(255)
+ALTER TABLE `cl2015_arrsidan`
+    MODIFY COLUMN `deltagare` TEXT COLLATE utf8_swedish_ci,
+    MODIFY COLUMN `kanske` TEXT COLLATE utf8_swedish_ci,
+    MODIFY COLUMN `hetsade` TEXT COLLATE utf8_swedish_ci;
+
+-- Copy the members ("#N") in the lists. A member in several lists keeps the
+-- first of yes, maybe and hetsad, since INSERT IGNORE skips later rows.
+INSERT IGNORE INTO `cl_arr_attendance` (`arr_id`, `member_number`, `status`)
+WITH RECURSIVE `n` AS (
+    SELECT 1 AS `i` UNION ALL SELECT `i` + 1 FROM `n` WHERE `i` < 200
+),
+`lists` AS (
+    SELECT `id`, 1 AS `prio`, 'yes' AS `status`, `deltagare` AS `sigs` FROM `cl2015_arrsidan`
+    UNION ALL SELECT `id`, 2, 'maybe', `kanske` FROM `cl2015_arrsidan`
+    UNION ALL SELECT `id`, 3, 'hetsad', `hetsade` FROM `cl2015_arrsidan`
+),
+`sigs` AS (
+    SELECT `lists`.`id`, `lists`.`prio`, `lists`.`status`,
+           TRIM(SUBSTRING_INDEX(SUBSTRING_INDEX(`lists`.`sigs`, ',', `n`.`i`), ',', -1)) AS `sig`
+    FROM `lists`
+    JOIN `n` ON `n`.`i` <= 1 + LENGTH(`lists`.`sigs`) - LENGTH(REPLACE(`lists`.`sigs`, ',', ''))
+    WHERE `lists`.`sigs` IS NOT NULL AND `lists`.`sigs` <> ''
+)
+SELECT `id`, CAST(SUBSTRING(`sig`, 2) AS UNSIGNED), `status`
+FROM `sigs`
+WHERE `sig` REGEXP '^#[0-9]+$'
+ORDER BY `prio`;
@@ -0,0 +1,15 @@
+-- Reminders mailed to members before arr, so each is sent only once
+CREATE TABLE IF NOT EXISTS `cl_arr_reminders` (
+    `arr_id`         INT      NOT NULL,
+    `member_number`  BIGINT   NOT NULL,
+    `offset_minutes` INT      NOT NULL,
+    `sent_at`        DATETIME NOT NULL,
+    PRIMARY KEY (`arr_id`, `member_number`, `offset_minutes`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Members who do not want reminders of arr
+CREATE TABLE IF NOT EXISTS `cl_reminder_optouts` (
+    `member_number` BIGINT   NOT NULL,
+    `created_at`    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`member_number`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,6 @@
+-- The start of an arr parsed from the free form start_date, for filtering
+-- and ordering. Filled in by the API when start_date is written; run
+-- cmd/backfill-arr-dates once for existing arr.
+ALTER TABLE `cl2015_arrsidan`
+    ADD COLUMN `starts_at` DATETIME NULL AFTER `start_date`,
+    ADD INDEX `idx_starts_at` (`starts_at`);
@@ -0,0 +1,14 @@
+-- Failed classic logins per member, for locking the account after too many
+-- failures in a row
+CREATE TABLE IF NOT EXISTS `login_lockouts` (
+    `member_number`   BIGINT   NOT NULL,
+    `failed_attempts` INT      NOT NULL DEFAULT 0,
+    `last_failed_at`  DATETIME NULL,
+    `locked_until`    DATETIME NULL,
+    PRIMARY KEY (`member_number`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Classic passwords are moved to bcrypt in `password` on first login
+ALTER TABLE cl2007_members
+    MODIFY COLUMN password VARCHAR(255) COMMENT 'bcrypt hash, set on classic login or password change',
+    MODIFY COLUMN password_classic VARCHAR(255) COMMENT 'DEPRECATED: cleared when moved to password';
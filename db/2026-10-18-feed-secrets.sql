@@ -0,0 +1,9 @@
+-- Secrets of personal feeds (e.g. the arr calendar) for clients that cannot
+-- send a Bearer header. Replacing or deleting a row revokes the secret.
+CREATE TABLE IF NOT EXISTS `member_feed_secrets` (
+    `member_number` BIGINT      NOT NULL,
+    `feed`          VARCHAR(32) NOT NULL,
+    `secret`        VARCHAR(64) NOT NULL,
+    `created_at`    DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`member_number`, `feed`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
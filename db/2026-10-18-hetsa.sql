@@ -0,0 +1,27 @@
+-- Hetsa invitations answered through the API. mystring holds the code sent
+-- to the invitee; the old date, time and numeric code columns are no longer
+-- written.
+ALTER TABLE `hetsa` ENGINE=InnoDB;
+ALTER TABLE `hetsa` CONVERT TO CHARACTER SET utf8mb4;
+
+ALTER TABLE `hetsa`
+    MODIFY COLUMN `arr` VARCHAR(50) NOT NULL DEFAULT '',
+    MODIFY COLUMN `name1` VARCHAR(255) NOT NULL DEFAULT '',
+    MODIFY COLUMN `comment` VARCHAR(255) NOT NULL DEFAULT '',
+    MODIFY COLUMN `date` DATE NULL,
+    MODIFY COLUMN `time` TIME NULL,
+    MODIFY COLUMN `code` INT NULL,
+    MODIFY COLUMN `code2` INT NULL,
+    MODIFY COLUMN `lastdate` DATE NULL,
+    MODIFY COLUMN `lasttime` TIME NULL,
+    ADD COLUMN `arr_id` INT NULL AFTER `arr`,
+    ADD COLUMN `member_number` BIGINT NULL AFTER `arr_id`,
+    ADD COLUMN `status` VARCHAR(8) NOT NULL DEFAULT 'pending',
+    ADD COLUMN `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN `responded_at` DATETIME NULL,
+    ADD INDEX `idx_arr_id` (`arr_id`),
+    ADD INDEX `idx_hetsa_code` (`mystring`),
+    ADD CONSTRAINT `chk_hetsa_status` CHECK (`status` IN ('pending', 'accepted', 'declined'));
+
+-- Old rows referred to the arr by id as text
+UPDATE `hetsa` SET `arr_id` = CAST(`arr` AS UNSIGNED) WHERE `arr` REGEXP '^[0-9]+$';
@@ -0,0 +1,12 @@
+-- Changes to the membership, e.g. prospects promoted to members, for
+-- consumers that act on them
+CREATE TABLE IF NOT EXISTS `cl_member_events` (
+    `id`            BIGINT      NOT NULL AUTO_INCREMENT,
+    `event`         VARCHAR(32) NOT NULL,
+    `member_number` BIGINT      NOT NULL,
+    `prospect_id`   INT         NULL,
+    `created_by`    BIGINT      NULL,
+    `created_at`    DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_member_events_member` (`member_number`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,19 @@
+-- Login identities linked to a member, so that a member can sign in with
+-- provider accounts whose email differs from cl2007_members.email
+CREATE TABLE IF NOT EXISTS `member_identities` (
+    `id`               BIGINT       NOT NULL AUTO_INCREMENT,
+    `member_number`    BIGINT       NOT NULL,
+    `provider`         VARCHAR(32)  NOT NULL,
+    `provider_user_id` VARCHAR(255) NOT NULL,
+    `email`            VARCHAR(255) NOT NULL DEFAULT '',
+    `created_at`       DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_login_at`    DATETIME     NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_provider_user` (`provider`, `provider_user_id`),
+    INDEX `idx_member_number` (`member_number`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Set on auth states started from /auth/identities/link, the callback then
+-- links the identity to this member instead of logging in
+ALTER TABLE `auth_states`
+    ADD COLUMN `link_member_number` BIGINT NULL AFTER `redirect_uri`;
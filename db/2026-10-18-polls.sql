@@ -0,0 +1,35 @@
+-- Polls through the API. Votes are limited to one per member instead of
+-- one per host; old votes have no member and still count. Options beyond
+-- yae (vote 1) and nay (vote 0) are numbered from 2 in cl_poll_options.
+
+-- The zero date default and the zero dates of old polls are rejected in
+-- strict mode, so they are cleared before anything rebuilds the table
+SET @saved_sql_mode = @@sql_mode;
+SET sql_mode = '';
+ALTER TABLE `cl2004_poll`
+    MODIFY COLUMN `date` DATE NULL DEFAULT NULL,
+    MODIFY COLUMN `time` TIME NULL DEFAULT NULL;
+UPDATE `cl2004_poll` SET `date` = NULL WHERE `date` = '0000-00-00';
+SET sql_mode = @saved_sql_mode;
+
+ALTER TABLE `cl2004_poll` ENGINE=InnoDB;
+ALTER TABLE `cl2004_poll` CONVERT TO CHARACTER SET utf8mb4;
+
+ALTER TABLE `cl2004_poll`
+    ADD COLUMN `created_by` BIGINT NULL AFTER `time`;
+
+ALTER TABLE `cl2004_poll_votes` ENGINE=InnoDB;
+ALTER TABLE `cl2004_poll_votes` CONVERT TO CHARACTER SET utf8mb4;
+
+ALTER TABLE `cl2004_poll_votes`
+    ADD COLUMN `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY FIRST,
+    ADD COLUMN `member_number` BIGINT NULL,
+    ADD COLUMN `created_at` DATETIME NULL,
+    ADD UNIQUE INDEX `uq_poll_member` (`poll_ID`, `member_number`);
+
+CREATE TABLE IF NOT EXISTS `cl_poll_options` (
+    `poll_id` INT         NOT NULL,
+    `vote`    TINYINT     NOT NULL,
+    `text`    VARCHAR(50) NOT NULL,
+    PRIMARY KEY (`poll_id`, `vote`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
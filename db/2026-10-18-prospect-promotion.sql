@@ -0,0 +1,4 @@
+-- Promoting a prospect creates the member and marks the prospect in one
+-- transaction, which MyISAM ignores
+ALTER TABLE `cl2007_members` ENGINE=InnoDB;
+ALTER TABLE `cl2007_prospects` ENGINE=InnoDB;
@@ -0,0 +1,20 @@
+-- Named RSQL filters for /db/entries?filter=<name>
+CREATE TABLE IF NOT EXISTS `saved_filters` (
+    `id`            BIGINT       NOT NULL AUTO_INCREMENT,
+    `member_number` BIGINT       NOT NULL,
+    `name`          VARCHAR(64)  NOT NULL,
+    `query`         TEXT         NOT NULL,
+    `sort`          VARCHAR(255) NOT NULL DEFAULT '',
+    `created_at`    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at`    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_member_name` (`member_number`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Who a saved filter is shared with (member_number = 0 means all members)
+CREATE TABLE IF NOT EXISTS `saved_filter_shares` (
+    `filter_id`     BIGINT NOT NULL,
+    `member_number` BIGINT NOT NULL,
+    PRIMARY KEY (`filter_id`, `member_number`),
+    INDEX `idx_member_number` (`member_number`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
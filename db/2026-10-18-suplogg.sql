@@ -0,0 +1,31 @@
+-- Suplogg through the API. A suplogg belongs to the arr with the same id and
+-- is closed once the final tally is in.
+ALTER TABLE `suplogg_arr` ENGINE=InnoDB;
+ALTER TABLE `suplogg_arr` CONVERT TO CHARACTER SET utf8mb4;
+
+ALTER TABLE `suplogg_arr`
+    ADD COLUMN `started_by` BIGINT NULL AFTER `Name`,
+    ADD COLUMN `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN `closed_at` DATETIME NULL,
+    ADD COLUMN `entry_id` INT NULL;
+
+-- Old logs are finished
+-- Timestamp is ON UPDATE CURRENT_TIMESTAMP, so it is set to itself to be kept
+UPDATE `suplogg_arr` SET `closed_at` = `Timestamp`, `created_at` = `Timestamp`, `Timestamp` = `Timestamp`;
+
+ALTER TABLE `suplogg_deltagare` ENGINE=InnoDB;
+ALTER TABLE `suplogg_deltagare` CONVERT TO CHARACTER SET utf8mb4;
+
+-- Enheter are counted per participant, so merge participants listed twice
+CREATE TEMPORARY TABLE `suplogg_deltagare_merged` AS
+    SELECT `Arr`, `Name`, SUM(`Enheter`) AS `Enheter`
+    FROM `suplogg_deltagare`
+    GROUP BY `Arr`, `Name`;
+DELETE FROM `suplogg_deltagare`;
+INSERT INTO `suplogg_deltagare` (`Arr`, `Name`, `Enheter`)
+    SELECT `Arr`, `Name`, `Enheter` FROM `suplogg_deltagare_merged`;
+DROP TEMPORARY TABLE `suplogg_deltagare_merged`;
+
+ALTER TABLE `suplogg_deltagare`
+    ADD COLUMN `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD PRIMARY KEY (`Arr`, `Name`);
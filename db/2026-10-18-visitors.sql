@@ -0,0 +1,4 @@
+-- cl_visitors is written by the request analytics recorder
+-- Widen ip for IPv6 and index ts for the retention job and analytics ranges
+ALTER TABLE `cl_visitors` MODIFY `ip` VARCHAR(45) DEFAULT NULL;
+ALTER TABLE `cl_visitors` ADD INDEX `idx_ts` (`ts`);
@@ -11,6 +11,7 @@ require (
 	github.com/sebastiw/go-rsql-mysql v0.0.0-20260121215516-2e9f902553be
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/crypto v0.45.0
 	golang.org/x/oauth2 v0.36.0
 	gorm.io/driver/mysql v1.5.7
 	gorm.io/driver/sqlite v1.6.0
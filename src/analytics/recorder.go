@@ -0,0 +1,242 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/hosts"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+// IP storage modes
+const (
+	IPFull       = "full"
+	IPAnonymized = "anonymized"
+	IPNone       = "none"
+)
+
+const maxUserAgent = 255
+
+var numericSegment = regexp.MustCompile(`/[0-9]+(/|$)`)
+
+// Store persists batches of visits
+type Store interface {
+	CreateVisits(visits []models.Visit) error
+}
+
+// Policy decides which personal data is stored
+type Policy struct {
+	IPMode       string
+	UserAgent    bool
+	Members      bool
+	ExcludePaths []string
+}
+
+// PolicyFromConfig builds the policy of the analytics configuration
+func PolicyFromConfig(c *config.AnalyticsConfiguration) Policy {
+	return Policy{
+		IPMode:       c.IPMode,
+		UserAgent:    c.UserAgent,
+		Members:      c.Members,
+		ExcludePaths: c.ExcludePaths,
+	}
+}
+
+// Recorder collects visits from the request path and writes them in
+// batches from a background goroutine. Record never blocks; when the queue is
+// full visits are dropped and counted. Host names are looked up by the
+// background goroutine, and only when full IPs are stored, since an
+// anonymized IP has no name of its own.
+type Recorder struct {
+	store     Store
+	policy    Policy
+	resolver  hosts.Resolver
+	batchSize int
+	interval  time.Duration
+
+	queue   chan models.Visit
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+// NewRecorder starts a recorder writing at most batchSize visits at a time,
+// and at least every interval. Without resolver the host is left empty.
+func NewRecorder(store Store, policy Policy, resolver hosts.Resolver, batchSize int, interval time.Duration) *Recorder {
+	rec := &Recorder{
+		store:     store,
+		policy:    policy,
+		resolver:  resolver,
+		batchSize: batchSize,
+		interval:  interval,
+		queue:     make(chan models.Visit, batchSize*10),
+		done:      make(chan struct{}),
+	}
+	go rec.run()
+	return rec
+}
+
+// Record queues the request for logging
+func (rec *Recorder) Record(r *http.Request) {
+	if r.Method == http.MethodOptions || rec.excluded(r.URL.Path) {
+		return
+	}
+	select {
+	case rec.queue <- rec.visit(r, time.Now()):
+	default:
+		rec.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of visits lost because the queue was full
+func (rec *Recorder) Dropped() uint64 {
+	return rec.dropped.Load()
+}
+
+// Close writes the queued visits and stops the recorder
+func (rec *Recorder) Close() {
+	rec.once.Do(func() {
+		close(rec.queue)
+		<-rec.done
+	})
+}
+
+func (rec *Recorder) run() {
+	defer close(rec.done)
+
+	ticker := time.NewTicker(rec.interval)
+	defer ticker.Stop()
+
+	batch := make([]models.Visit, 0, rec.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := rec.store.CreateVisits(batch); err != nil {
+			slog.Warn("unable to write visits", "count", len(batch), "error", err.Error())
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case v, ok := <-rec.queue:
+			if !ok {
+				flush()
+				return
+			}
+			rec.lookupHost(&v)
+			batch = append(batch, v)
+			if len(batch) >= rec.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (rec *Recorder) excluded(path string) bool {
+	for _, prefix := range rec.policy.ExcludePaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rec *Recorder) visit(r *http.Request, now time.Time) models.Visit {
+	v := models.Visit{
+		Date:    now.Format("2006-01-02"),
+		Time:    now.Format("15:04:05"),
+		Ts:      now,
+		Comment: r.Method + " " + NormalisePath(r.URL.Path),
+	}
+
+	if ip := anonymizeIP(ru.ClientIP(r), rec.policy.IPMode); ip != "" {
+		v.Ip = &ip
+	}
+	if rec.policy.UserAgent {
+		if ua := r.Header.Get("User-Agent"); ua != "" {
+			if len(ua) > maxUserAgent {
+				ua = ua[:maxUserAgent]
+			}
+			v.Ua = &ua
+		}
+	}
+	if rec.policy.Members {
+		if number, ok := memberNumber(r); ok {
+			sig := fmt.Sprintf("#%d", number)
+			v.Sig = &sig
+		}
+	}
+	return v
+}
+
+// lookupHost sets the host name of a visit with a full IP
+func (rec *Recorder) lookupHost(v *models.Visit) {
+	if rec.resolver == nil || rec.policy.IPMode != IPFull || v.Ip == nil {
+		return
+	}
+	host := hosts.Lookup(context.Background(), rec.resolver, *v.Ip)
+	v.Host = &host
+}
+
+// memberNumber reads the member from the bearer token. LogHTTP runs outside
+// the auth middleware, so the request context has no claims yet. Arr tokens
+// belong to no member.
+func memberNumber(r *http.Request) (int64, bool) {
+	token := auth.ExtractBearer(r.Header.Get("Authorization"))
+	if token == "" {
+		return 0, false
+	}
+	claims, err := auth.ValidateJWT(token, config.GetJWTSecret())
+	if err != nil || claims.Provider == auth.ArrProvider {
+		return 0, false
+	}
+	return claims.MemberNumber, true
+}
+
+// NormalisePath replaces numeric path segments with {id} so that usage can
+// be grouped per endpoint, e.g. /db/entries/12/like -> /db/entries/{id}/like
+func NormalisePath(path string) string {
+	// Matches do not overlap, so replace until stable for adjacent ids
+	for {
+		next := numericSegment.ReplaceAllString(path, "/{id}$1")
+		if next == path {
+			return path
+		}
+		path = next
+	}
+}
+
+func anonymizeIP(ip string, mode string) string {
+	switch mode {
+	case IPFull:
+		return ip
+	case IPNone:
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 24
+	if addr.Is6() {
+		bits = 48
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.Addr().String()
+}
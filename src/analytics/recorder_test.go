@@ -0,0 +1,119 @@
+package analytics
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	batches [][]models.Visit
+}
+
+func (f *fakeStore) CreateVisits(visits []models.Visit) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, append([]models.Visit(nil), visits...))
+	return nil
+}
+
+func TestNormalisePath(t *testing.T) {
+	assert.Equal(t, "/db/entries", NormalisePath("/db/entries"))
+	assert.Equal(t, "/db/entries/{id}", NormalisePath("/db/entries/12"))
+	assert.Equal(t, "/db/entries/{id}/like", NormalisePath("/db/entries/12/like"))
+	assert.Equal(t, "/calendar/members/{id}/arr.ics", NormalisePath("/calendar/members/8/arr.ics"))
+	assert.Equal(t, "/a/{id}/{id}", NormalisePath("/a/1/2"))
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	assert.Equal(t, "192.0.2.17", anonymizeIP("192.0.2.17", IPFull))
+	assert.Equal(t, "192.0.2.0", anonymizeIP("192.0.2.17", IPAnonymized))
+	assert.Equal(t, "2001:db8:1::", anonymizeIP("2001:db8:1:2:3:4:5:6", IPAnonymized))
+	assert.Equal(t, "", anonymizeIP("192.0.2.17", IPNone))
+	// Unknown modes anonymize
+	assert.Equal(t, "192.0.2.0", anonymizeIP("192.0.2.17", ""))
+}
+
+func TestRecorder(t *testing.T) {
+	store := &fakeStore{}
+	rec := NewRecorder(store, Policy{IPMode: IPNone, ExcludePaths: []string{"/file/"}}, nil, 2, time.Hour)
+
+	for _, path := range []string{"/db/entries", "/file/a.png", "/db/entries/3", "/db/arr"} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("User-Agent", "curl/8")
+		rec.Record(r)
+	}
+	rec.Record(httptest.NewRequest("OPTIONS", "/db/entries", nil))
+	rec.Close()
+
+	assert.Len(t, store.batches, 2)
+	assert.Len(t, store.batches[0], 2)
+	assert.Len(t, store.batches[1], 1)
+
+	v := store.batches[0][1]
+	assert.Equal(t, "GET /db/entries/{id}", v.Comment)
+	assert.Nil(t, v.Ip)
+	assert.Nil(t, v.Host)
+	assert.Nil(t, v.Ua)
+	assert.Nil(t, v.Sig)
+	assert.Equal(t, uint64(0), rec.Dropped())
+}
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return []string{f[addr]}, nil
+}
+
+func TestRecorder_Host(t *testing.T) {
+	resolver := fakeResolver{"192.0.2.17": "losers.example.com.", "192.0.2.0": "net.example.com."}
+	visit := func(mode string) models.Visit {
+		store := &fakeStore{}
+		rec := NewRecorder(store, Policy{IPMode: mode}, resolver, 1, time.Hour)
+		r := httptest.NewRequest("GET", "/db/entries", nil)
+		r.RemoteAddr = "192.0.2.17:1234"
+		rec.Record(r)
+		rec.Close()
+		require.Len(t, store.batches, 1)
+		return store.batches[0][0]
+	}
+
+	v := visit(IPFull)
+	require.NotNil(t, v.Host)
+	assert.Equal(t, "losers.example.com", *v.Host)
+
+	// The name of the anonymized network would still point at the visitor
+	v = visit(IPAnonymized)
+	assert.Equal(t, "192.0.2.0", *v.Ip)
+	assert.Nil(t, v.Host)
+	assert.Nil(t, visit(IPNone).Host)
+}
+
+func TestMemberNumber(t *testing.T) {
+	t.Setenv("JWT_SECRET", "analytics-test-secret")
+	secret := []byte("analytics-test-secret")
+
+	member, err := auth.GenerateJWT(8, "member@example.com", nil, "test", secret)
+	require.NoError(t, err)
+	r := httptest.NewRequest("GET", "/db/entries", nil)
+	r.Header.Set("Authorization", "Bearer "+member)
+	number, ok := memberNumber(r)
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), number)
+
+	// Guests with an arr password are not member #0
+	guest, err := auth.GenerateArrJWT(3, time.Hour, secret)
+	require.NoError(t, err)
+	r.Header.Set("Authorization", "Bearer "+guest)
+	_, ok = memberNumber(r)
+	assert.False(t, ok)
+}
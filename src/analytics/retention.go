@@ -0,0 +1,33 @@
+package analytics
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Cleaner deletes visits older than a point in time
+type Cleaner interface {
+	DeleteVisitsBefore(t time.Time) (int64, error)
+}
+
+// StartRetentionJob deletes visits older than days every interval. Nothing
+// is deleted if days <= 0.
+func StartRetentionJob(db Cleaner, days int, interval time.Duration) {
+	if days <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for ; true; <-ticker.C {
+			n, err := db.DeleteVisitsBefore(time.Now().AddDate(0, 0, -days))
+			if err != nil {
+				slog.Error("failed to delete old visits", slog.String("error", err.Error()))
+				continue
+			}
+			if n > 0 {
+				slog.Info("deleted old visits", slog.Int64("count", n))
+			}
+		}
+	}()
+	slog.Info("visit retention job started", slog.Int("days", days), slog.Duration("interval", interval))
+}
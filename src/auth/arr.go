@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ArrProvider is the provider of tokens granted by an arr password. They
+// belong to no member and only carry the ArrScope of their arr.
+const ArrProvider = "arr"
+
+// ArrScope grants seeing the details of one arr
+func ArrScope(arrId int64) string {
+	return fmt.Sprintf("read:arr:%d", arrId)
+}
+
+// GenerateArrJWT creates a token for a guest who gave the password of an arr
+func GenerateArrJWT(arrId int64, ttl time.Duration, secret []byte) (string, error) {
+	now := time.Now()
+
+	claims := &JWTClaims{
+		Scopes:   []string{ArrScope(arrId)},
+		Provider: ArrProvider,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "sidan-backend",
+			Subject:   fmt.Sprintf("arr:%d", arrId),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateArrJWT(t *testing.T) {
+	secret := []byte("test-secret-key-at-least-32-bytes-long-12345678")
+
+	token, err := GenerateArrJWT(3, time.Hour, secret)
+	if err != nil {
+		t.Fatalf("GenerateArrJWT failed: %v", err)
+	}
+	claims, err := ValidateJWT(token, secret)
+	if err != nil {
+		t.Fatalf("ValidateJWT failed: %v", err)
+	}
+	if claims.Provider != ArrProvider || claims.MemberNumber != 0 {
+		t.Errorf("Arr token has provider %q and member %d", claims.Provider, claims.MemberNumber)
+	}
+	if len(claims.Scopes) != 1 || claims.Scopes[0] != ArrScope(3) {
+		t.Errorf("Arr token has scopes %v, want only %s", claims.Scopes, ArrScope(3))
+	}
+
+	expired, err := GenerateArrJWT(3, -time.Minute, secret)
+	if err != nil {
+		t.Fatalf("GenerateArrJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(expired, secret); err != ErrExpiredToken {
+		t.Errorf("Expired arr token gave %v", err)
+	}
+}
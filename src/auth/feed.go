@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// NewFeedSecret creates a random secret for subscribing to personal feeds
+// (e.g. calendars) from clients that cannot send a Bearer header. The secret
+// is stored, so that the member can regenerate or revoke it.
+func NewFeedSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// CheckFeedSecret compares a given secret with the stored one in constant
+// time. Nothing matches an empty stored secret.
+func CheckFeedSecret(given string, stored string) bool {
+	return stored != "" && subtle.ConstantTimeCompare([]byte(given), []byte(stored)) == 1
+}
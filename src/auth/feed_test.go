@@ -0,0 +1,31 @@
+package auth
+
+import "testing"
+
+func TestFeedSecret(t *testing.T) {
+	secret, err := NewFeedSecret()
+	if err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if len(secret) != 43 {
+		t.Errorf("Expected 43 characters, got %d", len(secret))
+	}
+
+	other, err := NewFeedSecret()
+	if err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if secret == other {
+		t.Error("Secrets are not random")
+	}
+
+	if !CheckFeedSecret(secret, secret) {
+		t.Error("Valid secret was rejected")
+	}
+	if CheckFeedSecret(other, secret) {
+		t.Error("Another secret was accepted")
+	}
+	if CheckFeedSecret("", "") {
+		t.Error("Empty secret was accepted")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/sebastiw/sidan-backend/src/config"
 	"github.com/sebastiw/sidan-backend/src/data"
 	"github.com/sebastiw/sidan-backend/src/models"
+	"github.com/sebastiw/sidan-backend/src/presence"
 )
 
 // Scope constants for authorization
@@ -23,6 +24,9 @@ const (
 	WriteArticleScope = "write:article"
 	FilteringScope    = "filtering"
 	WriteFDroidScope  = "write:apk"
+	ExportEntryScope  = "export:entry"
+	WritePollScope    = "write:poll"
+	AdminScope        = "admin"
 )
 
 // Context keys for storing auth data in request context
@@ -36,7 +40,8 @@ const (
 
 // Middleware is a wrapper that provides auth functionality
 type Middleware struct {
-	db data.Database
+	db       data.Database
+	presence *presence.Tracker
 }
 
 // NewMiddleware creates auth middleware
@@ -44,6 +49,11 @@ func NewMiddleware(db data.Database) *Middleware {
 	return &Middleware{db: db}
 }
 
+// TrackPresence marks members online in tracker on every authenticated request
+func (m *Middleware) TrackPresence(tracker *presence.Tracker) {
+	m.presence = tracker
+}
+
 // RequireAuth validates JWT Bearer token and injects member into context
 func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -71,6 +81,12 @@ func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// Arr tokens are only accepted where auth is optional
+		if claims.Provider == ArrProvider {
+			http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+
 		// Get member from database by member number
 		member, err := m.db.ReadMemberByNumber(claims.MemberNumber)
 		if err != nil {
@@ -78,6 +94,10 @@ func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		if m.presence != nil {
+			m.presence.Touch(member.Number)
+		}
+
 		// Inject into context
 		ctx := context.WithValue(r.Context(), claimsKey, claims)
 		ctx = context.WithValue(ctx, memberKey, member)
@@ -140,6 +160,13 @@ func (m *Middleware) OptionalAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// An arr token has no member, only the scope of its arr
+		if claims.Provider == ArrProvider {
+			ctx := context.WithValue(r.Context(), scopesKey, claims.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		// Get member by member number
 		member, err := m.db.ReadMemberByNumber(claims.MemberNumber)
 		if err != nil {
@@ -147,6 +174,10 @@ func (m *Middleware) OptionalAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		if m.presence != nil {
+			m.presence.Touch(member.Number)
+		}
+
 		// Inject into context
 		ctx := context.WithValue(r.Context(), claimsKey, claims)
 		ctx = context.WithValue(ctx, memberKey, member)
@@ -176,6 +207,16 @@ func GetScopes(r *http.Request) []string {
 	return scopes
 }
 
+// HasScope tells whether the request context has the scope
+func HasScope(r *http.Request, scope string) bool {
+	for _, s := range GetScopes(r) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // GetMember retrieves member from request context
 func GetMember(r *http.Request) *models.Member {
 	val := r.Context().Value(memberKey)
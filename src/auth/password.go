@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ClassicProvider is the provider recorded on sessions from password logins
+const ClassicProvider = "classic"
+
+// HashPassword returns the bcrypt hash stored in cl2007_members.password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// HasPassword tells whether the member can log in with a password
+func HasPassword(member *models.Member) bool {
+	return isBcrypt(member.Password) || (member.Password_classic != nil && *member.Password_classic != "")
+}
+
+// CheckPassword verifies password against the member's bcrypt password, or
+// the classic password if there is no bcrypt one. legacy is true when the
+// classic password matched and should be rehashed.
+func CheckPassword(member *models.Member, password string) (ok bool, legacy bool) {
+	if isBcrypt(member.Password) {
+		return bcrypt.CompareHashAndPassword([]byte(*member.Password), []byte(password)) == nil, false
+	}
+	if member.Password_classic == nil || *member.Password_classic == "" {
+		return false, false
+	}
+	ok = subtle.ConstantTimeCompare([]byte(*member.Password_classic), []byte(password)) == 1
+	return ok, ok
+}
+
+func isBcrypt(hash *string) bool {
+	return hash != nil && strings.HasPrefix(*hash, "$2")
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestCheckPassword(t *testing.T) {
+	classic := "hemligt"
+	member := &models.Member{Password_classic: &classic}
+
+	if ok, legacy := CheckPassword(member, "hemligt"); !ok || !legacy {
+		t.Errorf("classic password: ok=%v legacy=%v", ok, legacy)
+	}
+	if ok, _ := CheckPassword(member, "fel"); ok {
+		t.Error("wrong classic password accepted")
+	}
+
+	hash, err := HashPassword("Nytt1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	member.Password = &hash
+	if ok, legacy := CheckPassword(member, "Nytt1234"); !ok || legacy {
+		t.Errorf("bcrypt password: ok=%v legacy=%v", ok, legacy)
+	}
+	// The classic password stops working once there is a bcrypt one
+	if ok, _ := CheckPassword(member, "hemligt"); ok {
+		t.Error("classic password accepted over bcrypt")
+	}
+
+	if ok, _ := CheckPassword(&models.Member{}, ""); ok {
+		t.Error("member without password accepted")
+	}
+	if HasPassword(&models.Member{}) {
+		t.Error("member without password has password")
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerateResetToken creates a password reset token for the member. The
+// token goes to the member by mail; stored is kept in
+// cl2007_members.password_resetstring and holds the expiry and a hash of the
+// token, so a leaked database row cannot be used to reset the password.
+func GenerateResetToken(memberNumber int64, expires time.Time) (token string, stored string) {
+	token = fmt.Sprintf("%d.%s", memberNumber, GenerateState())
+	return token, fmt.Sprintf("%d:%s", expires.Unix(), hashResetToken(token))
+}
+
+// ParseResetToken returns the member number a reset token was created for
+func ParseResetToken(token string) (int64, bool) {
+	prefix, _, found := strings.Cut(token, ".")
+	if !found {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(prefix, 10, 64)
+	return number, err == nil && number > 0
+}
+
+// CheckResetToken tells whether token matches the stored reset string and
+// has not expired
+func CheckResetToken(token string, stored string, now time.Time) bool {
+	expiry, hash, found := strings.Cut(stored, ":")
+	if !found {
+		return false
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashResetToken(token))) == 1
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResetToken(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	token, stored := GenerateResetToken(8, now.Add(time.Hour))
+
+	if strings.Contains(stored, strings.TrimPrefix(token, "8.")) {
+		t.Error("stored reset string contains the token")
+	}
+	if number, ok := ParseResetToken(token); !ok || number != 8 {
+		t.Errorf("ParseResetToken = %d, %v", number, ok)
+	}
+	if !CheckResetToken(token, stored, now) {
+		t.Error("valid token rejected")
+	}
+	if CheckResetToken(token, stored, now.Add(time.Hour)) {
+		t.Error("expired token accepted")
+	}
+	if CheckResetToken(token+"x", stored, now) {
+		t.Error("wrong token accepted")
+	}
+	if CheckResetToken(token, "", now) {
+		t.Error("token accepted without reset string")
+	}
+	if _, ok := ParseResetToken("nonsense"); ok {
+		t.Error("nonsense token parsed")
+	}
+}
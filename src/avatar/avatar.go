@@ -0,0 +1,200 @@
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	// Register the decoders accepted for uploads
+	_ "image/gif"
+)
+
+// Sizes are the square variants written for every upload, in pixels
+var Sizes = []int{64, 256, 512}
+
+// Canonical is the variant stored in cl2007_members.picture
+const Canonical = 256
+
+// Dir is where avatars are stored, relative to the static path
+const Dir = "avatars"
+
+// MaxPixels bounds the decoded source image, so a small file cannot expand
+// into an enormous bitmap
+const MaxPixels = 4096 * 4096
+
+const jpegQuality = 90
+
+var (
+	ErrUnsupported = errors.New("image must be gif, png or jpeg")
+	ErrTooLarge    = errors.New("image dimensions too large")
+)
+
+// Result lists the written variants as paths relative to the static path
+type Result struct {
+	Canonical string
+	Variants  map[int]string
+
+	dir string
+}
+
+// Process decodes an uploaded image and writes square variants for member
+// under staticPath/avatars/<member>/. Images are re-encoded from pixels only,
+// which drops EXIF and other metadata. JPEG uploads stay JPEG, GIF and PNG
+// become PNG to keep transparency. Older variants are kept until RemoveOlder,
+// so the stored picture stays valid if saving the new one fails.
+func Process(src io.Reader, staticPath string, member int64) (*Result, error) {
+	raw, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if format != "gif" && format != "png" && format != "jpeg" {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	ext := "png"
+	if format == "jpeg" {
+		ext = "jpeg"
+	}
+
+	sum := sha256.Sum256(raw)
+	version := hex.EncodeToString(sum[:])[:12]
+	relDir := filepath.ToSlash(filepath.Join(Dir, fmt.Sprint(member)))
+	dir := filepath.Join(staticPath, relDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	square := Square(img)
+	res := &Result{Variants: make(map[int]string, len(Sizes)), dir: dir}
+	for _, size := range Sizes {
+		name := fmt.Sprintf("%s-%d.%s", version, size, ext)
+		if err := writeImage(filepath.Join(dir, name), Resize(square, size), ext); err != nil {
+			return nil, err
+		}
+		res.Variants[size] = relDir + "/" + name
+	}
+	res.Canonical = res.Variants[Canonical]
+	return res, nil
+}
+
+// Square crops img to its centred square
+func Square(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, image.Pt(x0, y0), draw.Src)
+	return dst
+}
+
+// Resize scales a square image to size x size. Each destination pixel is the
+// average of the source pixels it covers, which is good enough for
+// downscaling photos; upscaling repeats pixels.
+func Resize(src *image.RGBA, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	side := src.Bounds().Dx()
+
+	for y := 0; y < size; y++ {
+		sy0 := y * side / size
+		sy1 := max((y+1)*side/size, sy0+1)
+		for x := 0; x < size; x++ {
+			sx0 := x * side / size
+			sx1 := max((x+1)*side/size, sx0+1)
+
+			var r, g, b, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				i := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+func writeImage(dst string, img image.Image, ext string) error {
+	f, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if ext == "jpeg" {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(f, img)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), dst)
+}
+
+// RemoveOlder deletes the member's previous variants
+func (res *Result) RemoveOlder() error {
+	keep := make(map[string]bool, len(res.Variants))
+	for _, v := range res.Variants {
+		keep[path.Base(v)] = true
+	}
+
+	entries, err := os.ReadDir(res.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || keep[name] || strings.HasPrefix(name, ".upload-") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(res.dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package avatar
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+func TestProcess_WritesSquareVariants(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(600, 400)))
+
+	static := t.TempDir()
+	res, err := Process(&buf, static, 8)
+	require.NoError(t, err)
+
+	assert.Equal(t, res.Variants[Canonical], res.Canonical)
+	assert.Regexp(t, `^avatars/8/[0-9a-f]{12}-256\.png$`, res.Canonical)
+	for _, size := range Sizes {
+		f, err := os.Open(filepath.Join(static, res.Variants[size]))
+		require.NoError(t, err)
+		cfg, format, err := image.DecodeConfig(f)
+		f.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, size, cfg.Width)
+		assert.Equal(t, size, cfg.Height)
+	}
+}
+
+func TestProcess_JPEGStripsMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, testImage(100, 100), nil))
+	// Insert an EXIF segment after the SOI marker
+	exif := append([]byte{0xFF, 0xE1, 0x00, 0x10}, []byte("Exif\x00\x00GPSDATA!")...)
+	raw := append(append([]byte{0xFF, 0xD8}, exif...), buf.Bytes()[2:]...)
+
+	static := t.TempDir()
+	res, err := Process(bytes.NewReader(raw), static, 8)
+	require.NoError(t, err)
+
+	out, err := os.ReadFile(filepath.Join(static, res.Canonical))
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "Exif")
+	assert.NotContains(t, string(out), "GPSDATA")
+}
+
+func TestProcess_GIFBecomesPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, testImage(32, 32), nil))
+
+	res, err := Process(&buf, t.TempDir(), 8)
+	require.NoError(t, err)
+	assert.Regexp(t, `-256\.png$`, res.Canonical)
+}
+
+func TestProcess_Rejects(t *testing.T) {
+	_, err := Process(bytes.NewReader([]byte("not an image")), t.TempDir(), 8)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 5000, 5000))))
+	_, err = Process(&buf, t.TempDir(), 8)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestResult_RemoveOlder(t *testing.T) {
+	static := t.TempDir()
+	upload := func(w int) *Result {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, testImage(w, w)))
+		res, err := Process(&buf, static, 8)
+		require.NoError(t, err)
+		return res
+	}
+
+	old := upload(10)
+	res := upload(20)
+	require.NoError(t, res.RemoveOlder())
+
+	_, err := os.Stat(filepath.Join(static, old.Canonical))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(static, res.Canonical))
+	assert.NoError(t, err)
+}
+
+func TestResize_Averages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	src.Set(1, 1, color.RGBA{255, 255, 255, 255})
+	src.Set(0, 1, color.RGBA{0, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{0, 0, 0, 255})
+
+	dst := Resize(src, 1)
+	assert.Equal(t, color.RGBA{127, 127, 127, 255}, dst.RGBAAt(0, 0))
+}
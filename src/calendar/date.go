@@ -0,0 +1,80 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+var ErrUnparseableDate = errors.New("unparseable start date")
+
+// Location is the timezone arr start dates are written in
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		return time.FixedZone("CET", 1*60*60)
+	}
+	return loc
+}
+
+// Layouts with a time of day, tried in order
+var dateTimeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15.04",
+	"2006-01-02 kl 15:04",
+	"2006-01-02 kl. 15:04",
+	"2006-01-02, 15:04",
+	"06-01-02 15:04",
+	"2/1 2006 15:04",
+	"2/1-2006 15:04",
+	"2/1-06 15:04",
+	"2.1.2006 15:04",
+	"20060102 15:04",
+}
+
+// Layouts with only a date, which become all-day events
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-1-2",
+	"06-01-02",
+	"2/1 2006",
+	"2/1-2006",
+	"2/1-06",
+	"2.1.2006",
+	"20060102",
+}
+
+// ParseStartDate interprets the free-form varchar cl2015_arrsidan.start_date
+// Returns the start time in Location and whether only a date was given
+func ParseStartDate(raw string) (time.Time, bool, error) {
+	s := strings.Join(strings.Fields(raw), " ")
+	if s == "" {
+		return time.Time{}, false, ErrUnparseableDate
+	}
+
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, Location); err == nil {
+			return t, false, nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, Location); err == nil {
+			return t, true, nil
+		}
+	}
+
+	// "2001-07-22 ca 20" or other trailing garbage: fall back to the date part
+	if len(s) > 10 {
+		if t, err := time.ParseInLocation("2006-01-02", s[:10], Location); err == nil {
+			return t, true, nil
+		}
+	}
+
+	return time.Time{}, false, ErrUnparseableDate
+}
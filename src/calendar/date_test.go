@@ -0,0 +1,44 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStartDate(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect time.Time
+		allDay bool
+	}{
+		{"2001-07-22 12:00", time.Date(2001, 7, 22, 12, 0, 0, 0, Location), false},
+		{"2024-11-02 18:00:00", time.Date(2024, 11, 2, 18, 0, 0, 0, Location), false},
+		{"2020-06-23T17:53", time.Date(2020, 6, 23, 17, 53, 0, 0, Location), false},
+		{"  2020-06-27   13:37 ", time.Date(2020, 6, 27, 13, 37, 0, 0, Location), false},
+		{"2020-10-21 20.00", time.Date(2020, 10, 21, 20, 0, 0, 0, Location), false},
+		{"2020-10-21 kl 20:00", time.Date(2020, 10, 21, 20, 0, 0, 0, Location), false},
+		{"22/7 2001 19:00", time.Date(2001, 7, 22, 19, 0, 0, 0, Location), false},
+		{"2001-11-29", time.Date(2001, 11, 29, 0, 0, 0, 0, Location), true},
+		{"2001-1-9", time.Date(2001, 1, 9, 0, 0, 0, 0, Location), true},
+		{"24/11-2001", time.Date(2001, 11, 24, 0, 0, 0, 0, Location), true},
+		{"2001-11-24 sen kväll", time.Date(2001, 11, 24, 0, 0, 0, 0, Location), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, allDay, err := ParseStartDate(tt.input)
+			assert.NoError(t, err)
+			assert.True(t, tt.expect.Equal(got), "got %v, want %v", got, tt.expect)
+			assert.Equal(t, tt.allDay, allDay)
+		})
+	}
+}
+
+func TestParseStartDate_Invalid(t *testing.T) {
+	for _, input := range []string{"", "   ", "snart", "i höst", "2001-13-45"} {
+		_, _, err := ParseStartDate(input)
+		assert.ErrorIs(t, err, ErrUnparseableDate, input)
+	}
+}
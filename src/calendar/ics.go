@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ProdID          = "-//Chalmers Losers//sidan-backend//SV"
+	DefaultDuration = 4 * time.Hour
+	maxLineOctets   = 75
+)
+
+// PartStat is the RFC 5545 participation status of the feed owner
+type PartStat string
+
+const (
+	PartStatNone      PartStat = ""
+	PartStatAccepted  PartStat = "ACCEPTED"
+	PartStatTentative PartStat = "TENTATIVE"
+)
+
+// Event is one VEVENT in a feed
+type Event struct {
+	UID         string
+	Summary     string
+	Location    string
+	Description string
+	Start       time.Time
+	AllDay      bool
+	Duration    time.Duration
+	Organizer   string
+	// Attendee is set on personal feeds when the owner has signed up
+	Attendee     string
+	AttendeeStat PartStat
+}
+
+// Feed is a VCALENDAR with a name and a list of events
+type Feed struct {
+	Name   string
+	Stamp  time.Time
+	Events []Event
+}
+
+// WriteFeed renders the feed as an RFC 5545 iCalendar stream
+func WriteFeed(w io.Writer, f Feed) error {
+	bw := bufio.NewWriter(w)
+	stamp := f.Stamp.UTC().Format("20060102T150405Z")
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+ProdID)
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	if f.Name != "" {
+		writeLine(bw, "X-WR-CALNAME:"+EscapeText(f.Name))
+	}
+
+	for _, e := range f.Events {
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+e.UID)
+		writeLine(bw, "DTSTAMP:"+stamp)
+		if e.AllDay {
+			writeLine(bw, "DTSTART;VALUE=DATE:"+e.Start.Format("20060102"))
+			writeLine(bw, "DTEND;VALUE=DATE:"+e.Start.AddDate(0, 0, 1).Format("20060102"))
+		} else {
+			duration := e.Duration
+			if duration <= 0 {
+				duration = DefaultDuration
+			}
+			writeLine(bw, "DTSTART:"+e.Start.UTC().Format("20060102T150405Z"))
+			writeLine(bw, "DTEND:"+e.Start.Add(duration).UTC().Format("20060102T150405Z"))
+		}
+		writeLine(bw, "SUMMARY:"+EscapeText(e.Summary))
+		if e.Location != "" {
+			writeLine(bw, "LOCATION:"+EscapeText(e.Location))
+		}
+		if e.Description != "" {
+			writeLine(bw, "DESCRIPTION:"+EscapeText(e.Description))
+		}
+		if e.Organizer != "" {
+			writeLine(bw, fmt.Sprintf("ORGANIZER;CN=%s:urn:sidan:%s", quoteParam(e.Organizer), e.Organizer))
+		}
+		if e.AttendeeStat != PartStatNone {
+			writeLine(bw, fmt.Sprintf("ATTENDEE;CN=%s;PARTSTAT=%s:urn:sidan:%s", quoteParam(e.Attendee), e.AttendeeStat, e.Attendee))
+			writeLine(bw, "X-SIDAN-RSVP:"+string(e.AttendeeStat))
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// EscapeText escapes a TEXT value according to RFC 5545 section 3.3.11
+func EscapeText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return r.Replace(s)
+}
+
+func quoteParam(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+// writeLine folds content lines longer than 75 octets without splitting
+// UTF-8 sequences and terminates them with CRLF
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines lose one octet to the leading space
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
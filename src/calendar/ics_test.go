@@ -0,0 +1,78 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeText(t *testing.T) {
+	assert.Equal(t, `Supa\, Supa\; Supa`, EscapeText("Supa, Supa; Supa"))
+	assert.Equal(t, `rad ett\nrad två`, EscapeText("rad ett\r\nrad två"))
+	assert.Equal(t, `C:\\bastu`, EscapeText(`C:\bastu`))
+}
+
+func TestWriteFeed(t *testing.T) {
+	start := time.Date(2001, 7, 22, 12, 0, 0, 0, Location)
+	feed := Feed{
+		Name:  "Arr",
+		Stamp: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Events: []Event{
+			{
+				UID:          "arr-18@test",
+				Summary:      "Prag",
+				Location:     "Prag, Tjeckien",
+				Start:        start,
+				Organizer:    "#10",
+				Attendee:     "#8",
+				AttendeeStat: PartStatAccepted,
+			},
+			{
+				UID:     "arr-19@test",
+				Summary: "Heldag",
+				Start:   time.Date(2001, 8, 10, 0, 0, 0, 0, Location),
+				AllDay:  true,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteFeed(&buf, feed))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "DTSTAMP:20260101T000000Z\r\n")
+	// 12:00 CEST is 10:00 UTC
+	assert.Contains(t, out, "DTSTART:20010722T100000Z\r\n")
+	assert.Contains(t, out, "DTEND:20010722T140000Z\r\n")
+	assert.Contains(t, out, "LOCATION:Prag\\, Tjeckien\r\n")
+	assert.Contains(t, out, "ATTENDEE;CN=\"#8\";PARTSTAT=ACCEPTED:urn:sidan:#8\r\n")
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20010810\r\n")
+	assert.Contains(t, out, "DTEND;VALUE=DATE:20010811\r\n")
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VEVENT"))
+}
+
+func TestWriteFeed_FoldsLongLines(t *testing.T) {
+	feed := Feed{
+		Stamp: time.Now(),
+		Events: []Event{{
+			UID:         "arr-1@test",
+			Summary:     "x",
+			Start:       time.Now(),
+			Description: strings.Repeat("åäö", 60),
+		}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteFeed(&buf, feed))
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	assert.Contains(t, unfolded, "DESCRIPTION:"+strings.Repeat("åäö", 60)+"\r\n")
+}
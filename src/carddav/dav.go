@@ -0,0 +1,212 @@
+package carddav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// XML namespaces used by the read-only address book
+const (
+	NamespaceDAV     = "DAV:"
+	NamespaceCardDAV = "urn:ietf:params:xml:ns:carddav"
+	// NamespaceCalendarServer holds getctag, which clients poll for changes
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// Property names
+var (
+	ResourceType         = xml.Name{Space: NamespaceDAV, Local: "resourcetype"}
+	DisplayName          = xml.Name{Space: NamespaceDAV, Local: "displayname"}
+	GetETag              = xml.Name{Space: NamespaceDAV, Local: "getetag"}
+	GetContentType       = xml.Name{Space: NamespaceDAV, Local: "getcontenttype"}
+	CurrentUserPrincipal = xml.Name{Space: NamespaceDAV, Local: "current-user-principal"}
+	PrincipalURL         = xml.Name{Space: NamespaceDAV, Local: "principal-URL"}
+	SupportedReportSet   = xml.Name{Space: NamespaceDAV, Local: "supported-report-set"}
+	AddressbookHomeSet   = xml.Name{Space: NamespaceCardDAV, Local: "addressbook-home-set"}
+	AddressbookDesc      = xml.Name{Space: NamespaceCardDAV, Local: "addressbook-description"}
+	AddressData          = xml.Name{Space: NamespaceCardDAV, Local: "address-data"}
+	GetCTag              = xml.Name{Space: NamespaceCalendarServer, Local: "getctag"}
+)
+
+var ErrUnsupportedReport = errors.New("unsupported report")
+
+// PropRequest is the set of properties a PROPFIND or REPORT asks for
+type PropRequest struct {
+	AllProp bool
+	Names   []xml.Name
+}
+
+// Report is a parsed addressbook-multiget or addressbook-query. Hrefs are
+// only set for multiget; a query matches every card.
+type Report struct {
+	Multiget bool
+	Hrefs    []string
+	Props    PropRequest
+}
+
+// Resource is a href with its property values as raw inner XML
+type Resource struct {
+	Href  string
+	Props map[xml.Name]string
+}
+
+type propElement struct {
+	XMLName xml.Name
+	Inner   []byte `xml:",innerxml"`
+}
+
+type propContainer struct {
+	Props []propElement `xml:",any"`
+}
+
+type propfindBody struct {
+	XMLName xml.Name       `xml:"DAV: propfind"`
+	AllProp *struct{}      `xml:"DAV: allprop"`
+	Prop    *propContainer `xml:"DAV: prop"`
+}
+
+type reportBody struct {
+	XMLName xml.Name
+	Prop    *propContainer `xml:"DAV: prop"`
+	Hrefs   []string       `xml:"DAV: href"`
+}
+
+// ParsePropfind reads a PROPFIND body. An empty body means allprop.
+func ParsePropfind(r io.Reader) (PropRequest, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return PropRequest{}, err
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return PropRequest{AllProp: true}, nil
+	}
+
+	var body propfindBody
+	if err := xml.Unmarshal(raw, &body); err != nil {
+		return PropRequest{}, err
+	}
+	if body.Prop == nil {
+		return PropRequest{AllProp: true}, nil
+	}
+	return PropRequest{Names: propNames(body.Prop)}, nil
+}
+
+// ParseReport reads an addressbook-multiget or addressbook-query body
+func ParseReport(r io.Reader) (Report, error) {
+	var body reportBody
+	if err := xml.NewDecoder(r).Decode(&body); err != nil {
+		return Report{}, err
+	}
+	if body.XMLName.Space != NamespaceCardDAV {
+		return Report{}, ErrUnsupportedReport
+	}
+
+	props := PropRequest{AllProp: true}
+	if body.Prop != nil {
+		props = PropRequest{Names: propNames(body.Prop)}
+	}
+	switch body.XMLName.Local {
+	case "addressbook-multiget":
+		return Report{Multiget: true, Hrefs: body.Hrefs, Props: props}, nil
+	case "addressbook-query":
+		return Report{Props: props}, nil
+	}
+	return Report{}, ErrUnsupportedReport
+}
+
+func propNames(c *propContainer) []xml.Name {
+	names := make([]xml.Name, len(c.Props))
+	for i, p := range c.Props {
+		names[i] = p.XMLName
+	}
+	return names
+}
+
+// Text escapes s for use as a property value
+func Text(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Href is a property value holding a single DAV:href
+func Href(path string) string {
+	return `<href xmlns="DAV:">` + Text(path) + `</href>`
+}
+
+type multistatus struct {
+	XMLName   xml.Name   `xml:"DAV: multistatus"`
+	Responses []response `xml:"response"`
+}
+
+type response struct {
+	Href      string     `xml:"href"`
+	Propstats []propstat `xml:"propstat,omitempty"`
+	Status    string     `xml:"status,omitempty"`
+}
+
+type propstat struct {
+	Prop   propContainer `xml:"prop"`
+	Status string        `xml:"status"`
+}
+
+// expensive properties are only returned when asked for by name
+var expensive = map[xml.Name]bool{AddressData: true}
+
+// WriteMultistatus writes a 207 response with the requested properties of
+// each resource. Properties a resource does not have are reported as 404.
+// A nil Props marks a resource that was not found.
+func WriteMultistatus(w http.ResponseWriter, resources []Resource, req PropRequest) error {
+	ms := multistatus{}
+	for _, res := range resources {
+		resp := response{Href: res.Href}
+		if res.Props == nil {
+			resp.Status = status(http.StatusNotFound)
+			ms.Responses = append(ms.Responses, resp)
+			continue
+		}
+
+		var found, missing propContainer
+		if req.AllProp {
+			for name, value := range res.Props {
+				if !expensive[name] {
+					found.Props = append(found.Props, propElement{XMLName: name, Inner: []byte(value)})
+				}
+			}
+			sort.Slice(found.Props, func(i, j int) bool {
+				a, b := found.Props[i].XMLName, found.Props[j].XMLName
+				return a.Space+a.Local < b.Space+b.Local
+			})
+		} else {
+			for _, name := range req.Names {
+				if value, ok := res.Props[name]; ok {
+					found.Props = append(found.Props, propElement{XMLName: name, Inner: []byte(value)})
+				} else {
+					missing.Props = append(missing.Props, propElement{XMLName: name})
+				}
+			}
+		}
+		if len(found.Props) > 0 {
+			resp.Propstats = append(resp.Propstats, propstat{Prop: found, Status: status(http.StatusOK)})
+		}
+		if len(missing.Props) > 0 {
+			resp.Propstats = append(resp.Propstats, propstat{Prop: missing, Status: status(http.StatusNotFound)})
+		}
+		ms.Responses = append(ms.Responses, resp)
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, xml.Header)
+	return xml.NewEncoder(w).Encode(ms)
+}
+
+func status(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
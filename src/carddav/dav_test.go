@@ -0,0 +1,63 @@
+package carddav
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePropfind(t *testing.T) {
+	req, err := ParsePropfind(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.True(t, req.AllProp)
+
+	req, err = ParsePropfind(strings.NewReader(`<propfind xmlns="DAV:"><allprop/></propfind>`))
+	require.NoError(t, err)
+	assert.True(t, req.AllProp)
+
+	req, err = ParsePropfind(strings.NewReader(`<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:carddav">
+		<d:prop><d:getetag/><c:addressbook-home-set/></d:prop></d:propfind>`))
+	require.NoError(t, err)
+	assert.False(t, req.AllProp)
+	assert.Equal(t, []xml.Name{GetETag, AddressbookHomeSet}, req.Names)
+
+	_, err = ParsePropfind(strings.NewReader(`<propfind`))
+	assert.Error(t, err)
+}
+
+func TestParseReport(t *testing.T) {
+	report, err := ParseReport(strings.NewReader(`<c:addressbook-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:carddav">
+		<d:prop><c:address-data/></d:prop><d:href>/a.vcf</d:href><d:href>/b.vcf</d:href></c:addressbook-multiget>`))
+	require.NoError(t, err)
+	assert.True(t, report.Multiget)
+	assert.Equal(t, []string{"/a.vcf", "/b.vcf"}, report.Hrefs)
+	assert.Equal(t, []xml.Name{AddressData}, report.Props.Names)
+
+	report, err = ParseReport(strings.NewReader(`<c:addressbook-query xmlns:c="urn:ietf:params:xml:ns:carddav"/>`))
+	require.NoError(t, err)
+	assert.False(t, report.Multiget)
+	assert.True(t, report.Props.AllProp)
+
+	_, err = ParseReport(strings.NewReader(`<d:sync-collection xmlns:d="DAV:"/>`))
+	assert.ErrorIs(t, err, ErrUnsupportedReport)
+}
+
+func TestWriteMultistatus_AllPropSkipsAddressData(t *testing.T) {
+	rec := httptest.NewRecorder()
+	require.NoError(t, WriteMultistatus(rec, []Resource{{
+		Href: "/a.vcf",
+		Props: map[xml.Name]string{
+			GetETag:     Text(`"abc"`),
+			AddressData: Text("BEGIN:VCARD"),
+		},
+	}}, PropRequest{AllProp: true}))
+
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<getetag xmlns="DAV:">&#34;abc&#34;</getetag>`)
+	assert.NotContains(t, rec.Body.String(), "BEGIN:VCARD")
+}
@@ -5,16 +5,26 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/securecookie"
 	"github.com/spf13/viper"
+
+	"github.com/sebastiw/sidan-backend/src/enums"
 )
 
 type Configuration struct {
 	Server       ServerConfiguration
 	Database     DatabaseConfiguration
+	Cache        CacheConfiguration
+	RateLimit    map[string]RateLimitConfiguration
+	Analytics    AnalyticsConfiguration
+	Admin        AdminConfiguration
 	Mail         MailConfiguration
+	Password     PasswordConfiguration
+	Hetsa        HetsaConfiguration
+	Reminder     ReminderConfiguration
 	JWT          JWTConfiguration
 	FDroid       FDroidConfiguration
 	OAuth2       map[string]OAuth2Configuration
@@ -34,6 +44,8 @@ type FDroidConfiguration struct {
 type ServerConfiguration struct {
 	Port int
 	StaticPath string
+	// TrustedProxies are IPs or CIDRs whose X-Forwarded-For is believed
+	TrustedProxies []string
 }
 
 type DatabaseConfiguration struct {
@@ -45,16 +57,81 @@ type DatabaseConfiguration struct {
 	Password string
 }
 
+// CacheConfiguration controls the in-memory read cache in front of the
+// database
+type CacheConfiguration struct {
+	Enabled    bool
+	Size       int
+	TTLSeconds int
+}
+
+// RateLimitConfiguration limits a route to Requests per WindowSeconds for
+// each member or client IP. Requests 0 disables the limit.
+type RateLimitConfiguration struct {
+	Requests      int
+	WindowSeconds int
+}
+
+// AnalyticsConfiguration controls which request data is stored in
+// cl_visitors. IPMode is full, anonymized (only the IPv4 /24 or the IPv6 /48
+// is kept, the rest zeroed) or none. The host name is only stored with full
+// IPs.
+type AnalyticsConfiguration struct {
+	Enabled       bool
+	IPMode        string
+	UserAgent     bool
+	Members       bool
+	RetentionDays int
+	ExcludePaths  []string
+}
+
+// AdminConfiguration lists the member numbers that get the admin scope
+type AdminConfiguration struct {
+	Members []int64
+}
+
 type MailConfiguration struct {
 	Host     string
 	Port     int
 	User     string
 	Password string
+	// From is the sender of mails the server sends on its own
+	From string
+}
+
+// PasswordConfiguration controls classic password logins. Policy is none,
+// low, medium or high (see enums.PasswordPolicy). After MaxAttempts failed
+// logins in a row the account is locked for LockoutSeconds. Reset mails link
+// to ResetURL with the token added, and the token is valid for ResetMinutes.
+type PasswordConfiguration struct {
+	Policy         string
+	MaxAttempts    int
+	LockoutSeconds int
+	ResetURL       string
+	ResetMinutes   int
+}
+
+// HetsaConfiguration controls invitations to arr. Invite mails link to URL
+// with the invitation code added.
+type HetsaConfiguration struct {
+	URL string
+}
+
+// ReminderConfiguration controls the mails reminding members who answered
+// yes or maybe to an arr. A reminder is sent OffsetsMinutes before the start,
+// checked every IntervalSeconds. Mails link to URL with the arr id added.
+type ReminderConfiguration struct {
+	Enabled         bool
+	OffsetsMinutes  []int
+	IntervalSeconds int
+	URL             string
 }
 
 type JWTConfiguration struct {
 	Secret      string
 	ExpiryHours int
+	// ArrTokenMinutes is how long the password of an arr lets a guest see it
+	ArrTokenMinutes int
 }
 
 type OAuth2Configuration struct {
@@ -101,10 +178,31 @@ func ReadConfig(configuration *Configuration) {
 	viper.SetDefault("database.type", "mysql")
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", "3306")
+	viper.SetDefault("cache.enabled", false)
+	viper.SetDefault("cache.size", 1000)
+	viper.SetDefault("cache.ttlseconds", 60)
+	viper.SetDefault("analytics.enabled", false)
+	viper.SetDefault("analytics.ipmode", "anonymized")
+	viper.SetDefault("analytics.useragent", true)
+	viper.SetDefault("analytics.members", true)
+	viper.SetDefault("analytics.retentiondays", 90)
+	viper.SetDefault("analytics.excludepaths", []string{"/file/", "/repo/", "/presence/events"})
 	viper.SetDefault("mail.host", "localhost")
 	viper.SetDefault("mail.port", "25")
+	viper.SetDefault("mail.from", "noreply@chalmerslosers.com")
+	viper.SetDefault("password.policy", "medium")
+	viper.SetDefault("password.maxattempts", 5)
+	viper.SetDefault("password.lockoutseconds", 900)
+	viper.SetDefault("password.reseturl", "https://www.chalmerslosers.com/reset-password")
+	viper.SetDefault("password.resetminutes", 60)
+	viper.SetDefault("hetsa.url", "https://www.chalmerslosers.com/hetsa")
+	viper.SetDefault("reminder.enabled", false)
+	viper.SetDefault("reminder.offsetsminutes", []int{1440, 120})
+	viper.SetDefault("reminder.intervalseconds", 60)
+	viper.SetDefault("reminder.url", "https://www.chalmerslosers.com/arr")
 	viper.SetDefault("server.staticpath", "./static")
 	viper.SetDefault("jwt.expiryhours", 8)
+	viper.SetDefault("jwt.arrtokenminutes", 120)
 	viper.SetDefault("fdroid.reponame", "F-Droid Repository")
 	viper.SetDefault("fdroid.repodescription", "App repository")
 	viper.SetDefault("fdroid.repopath", "./static/fdroid/repo")
@@ -128,6 +226,34 @@ func GetDatabase() *DatabaseConfiguration {
 	return &cfg.Database
 }
 
+func GetCache() *CacheConfiguration {
+	return &cfg.Cache
+}
+
+// GetRateLimit returns the configured limit of the named route, if any
+func GetRateLimit(name string) (RateLimitConfiguration, bool) {
+	c, ok := cfg.RateLimit[strings.ToLower(name)]
+	return c, ok
+}
+
+func GetAnalytics() *AnalyticsConfiguration {
+	return &cfg.Analytics
+}
+
+func GetAdmin() *AdminConfiguration {
+	return &cfg.Admin
+}
+
+// IsAdmin tells whether the member is listed in admin.members
+func IsAdmin(memberNumber int64) bool {
+	for _, n := range cfg.Admin.Members {
+		if n == memberNumber {
+			return true
+		}
+	}
+	return false
+}
+
 func GetServer() *ServerConfiguration {
 	return &cfg.Server
 }
@@ -136,6 +262,29 @@ func GetMail() *MailConfiguration {
 	return &cfg.Mail
 }
 
+func GetPassword() *PasswordConfiguration {
+	return &cfg.Password
+}
+
+// GetPasswordPolicy returns the configured policy, or medium if it is not
+// a known policy
+func GetPasswordPolicy() enums.PasswordPolicy {
+	p, err := enums.ParsePasswordPolicy(cfg.Password.Policy)
+	if err != nil {
+		slog.Warn("Using medium password policy", slog.String("error", err.Error()))
+		return enums.PasswordPolicyMedium
+	}
+	return p
+}
+
+func GetHetsa() *HetsaConfiguration {
+	return &cfg.Hetsa
+}
+
+func GetReminder() *ReminderConfiguration {
+	return &cfg.Reminder
+}
+
 func GetFDroid() *FDroidConfiguration {
 	return &cfg.FDroid
 }
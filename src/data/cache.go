@@ -0,0 +1,318 @@
+package data
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// Cache tags. Every cached read is tagged with what it depends on so that
+// writes only drop the results they can affect.
+const (
+	tagEntryLists   = "entries"
+	tagEntryDynamic = "entries:dynamic" // lists whose membership or order depends on likes
+	tagArticleLists = "articles"
+)
+
+// maxCachedRows is the largest list that is cached. Larger pages are read
+// from the backend every time, so the cache holds at most maxItems times
+// this many rows.
+const maxCachedRows = 100
+
+// CacheStats are the counters of a CachedDatabase
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Size          int    `json:"size"`
+}
+
+type cacheItem struct {
+	key     string
+	value   interface{}
+	tags    []string
+	expires time.Time
+}
+
+// CachedDatabase decorates a Database with an in-memory LRU cache for entry
+// and article reads. All other methods are passed through to the backend.
+type CachedDatabase struct {
+	Database
+
+	mu       sync.Mutex
+	maxItems int
+	ttl      time.Duration
+	lru      *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{}
+	stats    CacheStats
+	now      func() time.Time
+}
+
+// NewCachedDatabase wraps backend with a cache holding at most maxItems
+// results of at most maxCachedRows rows, each for at most ttl (0 means until
+// evicted or invalidated). The ttl bounds staleness from writes that do not
+// go through this process.
+func NewCachedDatabase(backend Database, maxItems int, ttl time.Duration) *CachedDatabase {
+	if maxItems <= 0 {
+		maxItems = 1000
+	}
+	return &CachedDatabase{
+		Database: backend,
+		maxItems: maxItems,
+		ttl:      ttl,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+		now:      time.Now,
+	}
+}
+
+// Stats returns a snapshot of the cache counters
+func (c *CachedDatabase) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	return stats
+}
+
+func (c *CachedDatabase) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok {
+		item := el.Value.(*cacheItem)
+		if item.expires.IsZero() || c.now().Before(item.expires) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			return item.value, true
+		}
+		c.remove(el)
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *CachedDatabase) put(key string, value interface{}, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	item := &cacheItem{key: key, value: value, tags: tags}
+	if c.ttl > 0 {
+		item.expires = c.now().Add(c.ttl)
+	}
+	c.items[key] = c.lru.PushFront(item)
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for c.lru.Len() > c.maxItems {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// invalidate drops every cached result carrying any of the tags
+func (c *CachedDatabase) invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if el, ok := c.items[key]; ok {
+				c.remove(el)
+				c.stats.Invalidations++
+			}
+		}
+	}
+}
+
+// remove must be called with mu held
+func (c *CachedDatabase) remove(el *list.Element) {
+	item := c.lru.Remove(el).(*cacheItem)
+	delete(c.items, item.key)
+	for _, tag := range item.tags {
+		if keys, ok := c.tags[tag]; ok {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+func entryTag(id int64) string {
+	return fmt.Sprintf("entry:%d", id)
+}
+
+func articleTag(id int64) string {
+	return fmt.Sprintf("article:%d", id)
+}
+
+// Callers modify returned entries (e.g. FilterEntryMessage), so cached values
+// are copied on the way in and out.
+func cloneEntry(e models.Entry) models.Entry {
+	e.SideKicks = append([]models.SideKick(nil), e.SideKicks...)
+	e.LikeRecords = append([]models.Like(nil), e.LikeRecords...)
+	e.Permissions = append([]models.Permission(nil), e.Permissions...)
+	return e
+}
+
+func cloneEntries(entries []models.Entry) []models.Entry {
+	if entries == nil {
+		return nil
+	}
+	out := make([]models.Entry, len(entries))
+	for i, e := range entries {
+		out[i] = cloneEntry(e)
+	}
+	return out
+}
+
+func (c *CachedDatabase) CreateEntry(entry *models.Entry) (*models.Entry, error) {
+	created, err := c.Database.CreateEntry(entry)
+	if err == nil {
+		c.invalidate(tagEntryLists)
+	}
+	return created, err
+}
+
+func (c *CachedDatabase) ReadEntry(id int64) (*models.Entry, error) {
+	key := entryTag(id)
+	if v, ok := c.get(key); ok {
+		entry := cloneEntry(v.(models.Entry))
+		return &entry, nil
+	}
+
+	entry, err := c.Database.ReadEntry(id)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+	c.put(key, cloneEntry(*entry), key)
+	return entry, nil
+}
+
+func (c *CachedDatabase) ReadEntries(take int, skip int, filter string, sort string, fields []string) ([]models.Entry, error) {
+	key := fmt.Sprintf("entries:%d:%d:%q:%q:%q", take, skip, filter, sort, strings.Join(fields, ","))
+	if v, ok := c.get(key); ok {
+		return cloneEntries(v.([]models.Entry)), nil
+	}
+
+	entries, err := c.Database.ReadEntries(take, skip, filter, sort, fields)
+	if err != nil || len(entries) > maxCachedRows {
+		return entries, err
+	}
+
+	tags := []string{tagEntryLists}
+	if filter != "" || sort != "" {
+		tags = append(tags, tagEntryDynamic)
+	}
+	for _, e := range entries {
+		tags = append(tags, entryTag(e.Id))
+	}
+	c.put(key, cloneEntries(entries), tags...)
+	return entries, nil
+}
+
+func (c *CachedDatabase) UpdateEntry(entry *models.Entry) (*models.Entry, error) {
+	updated, err := c.Database.UpdateEntry(entry)
+	if err == nil {
+		// datetime or message may change, which affects order and filters
+		c.invalidate(entryTag(entry.Id), tagEntryLists)
+	}
+	return updated, err
+}
+
+func (c *CachedDatabase) DeleteEntry(entry *models.Entry) (*models.Entry, error) {
+	deleted, err := c.Database.DeleteEntry(entry)
+	if err == nil {
+		c.invalidate(entryTag(entry.Id), tagEntryLists)
+	}
+	return deleted, err
+}
+
+// LikeEntry only changes the like count, so besides the entry itself only
+// lists that are filtered or sorted (possibly on likes) are dropped
+func (c *CachedDatabase) LikeEntry(entryId int64, sig string, host string) error {
+	err := c.Database.LikeEntry(entryId, sig, host)
+	if err == nil {
+		c.invalidate(entryTag(entryId), tagEntryDynamic)
+	}
+	return err
+}
+
+func (c *CachedDatabase) CreateArticle(article *models.Article) (*models.Article, error) {
+	created, err := c.Database.CreateArticle(article)
+	if err == nil {
+		c.invalidate(tagArticleLists)
+	}
+	return created, err
+}
+
+func (c *CachedDatabase) ReadArticle(id int64) (*models.Article, error) {
+	key := articleTag(id)
+	if v, ok := c.get(key); ok {
+		article := v.(models.Article)
+		return &article, nil
+	}
+
+	article, err := c.Database.ReadArticle(id)
+	if err != nil || article == nil {
+		return article, err
+	}
+	c.put(key, *article, key)
+	return article, nil
+}
+
+func (c *CachedDatabase) ReadArticles(take int, skip int) ([]models.Article, error) {
+	key := fmt.Sprintf("articles:%d:%d", take, skip)
+	if v, ok := c.get(key); ok {
+		return append([]models.Article(nil), v.([]models.Article)...), nil
+	}
+
+	articles, err := c.Database.ReadArticles(take, skip)
+	if err != nil || len(articles) > maxCachedRows {
+		return articles, err
+	}
+
+	tags := []string{tagArticleLists}
+	for _, a := range articles {
+		tags = append(tags, articleTag(a.Id))
+	}
+	c.put(key, append([]models.Article(nil), articles...), tags...)
+	return articles, nil
+}
+
+// UpdateArticle keeps the id, and articles are listed by id, so only results
+// containing the article are dropped
+func (c *CachedDatabase) UpdateArticle(article *models.Article) (*models.Article, error) {
+	updated, err := c.Database.UpdateArticle(article)
+	if err == nil {
+		c.invalidate(articleTag(article.Id))
+	}
+	return updated, err
+}
+
+func (c *CachedDatabase) DeleteArticle(article *models.Article) (*models.Article, error) {
+	deleted, err := c.Database.DeleteArticle(article)
+	if err == nil {
+		c.invalidate(articleTag(article.Id), tagArticleLists)
+	}
+	return deleted, err
+}
@@ -0,0 +1,179 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// fakeDatabase counts backend reads. Methods that are not overridden panic
+// through the nil embedded interface.
+type fakeDatabase struct {
+	data.Database
+	entries      map[int64]models.Entry
+	articles     map[int64]models.Article
+	entryReads   int
+	articleReads int
+}
+
+func (f *fakeDatabase) ReadEntry(id int64) (*models.Entry, error) {
+	f.entryReads++
+	e := f.entries[id]
+	return &e, nil
+}
+
+func (f *fakeDatabase) ReadEntries(take int, skip int, filter string, sort string, fields []string) ([]models.Entry, error) {
+	f.entryReads++
+	var out []models.Entry
+	for _, e := range f.entries {
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (f *fakeDatabase) CreateEntry(entry *models.Entry) (*models.Entry, error) {
+	f.entries[entry.Id] = *entry
+	return entry, nil
+}
+
+func (f *fakeDatabase) LikeEntry(entryId int64, sig string, host string) error {
+	e := f.entries[entryId]
+	e.Likes++
+	f.entries[entryId] = e
+	return nil
+}
+
+func (f *fakeDatabase) ReadArticles(take int, skip int) ([]models.Article, error) {
+	f.articleReads++
+	var out []models.Article
+	if skip >= len(f.articles) {
+		return out, nil
+	}
+	for _, a := range f.articles {
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (f *fakeDatabase) UpdateArticle(article *models.Article) (*models.Article, error) {
+	f.articles[article.Id] = *article
+	return article, nil
+}
+
+func newFake() *fakeDatabase {
+	return &fakeDatabase{
+		entries: map[int64]models.Entry{
+			1: {Id: 1, Msg: "first", Permissions: []models.Permission{{Id: 1, UserId: 0}}},
+			2: {Id: 2, Msg: "second"},
+		},
+		articles: map[int64]models.Article{
+			1: {Id: 1},
+		},
+	}
+}
+
+func TestCachedDatabase_HitsAndMisses(t *testing.T) {
+	fake := newFake()
+	db := data.NewCachedDatabase(fake, 10, time.Minute)
+
+	_, err := db.ReadEntries(10, 0, "", "", nil)
+	assert.NoError(t, err)
+	_, err = db.ReadEntries(10, 0, "", "", nil)
+	assert.NoError(t, err)
+	_, err = db.ReadEntries(20, 0, "", "", nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, fake.entryReads)
+	stats := db.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 2, stats.Size)
+}
+
+func TestCachedDatabase_ReturnsCopies(t *testing.T) {
+	fake := newFake()
+	db := data.NewCachedDatabase(fake, 10, time.Minute)
+
+	entry, _ := db.ReadEntry(1)
+	entry.Msg = "hemlis"
+	entry.Permissions[0].UserId = 8
+
+	entry, _ = db.ReadEntry(1)
+	assert.Equal(t, "first", entry.Msg)
+	assert.Equal(t, int64(0), entry.Permissions[0].UserId)
+	assert.Equal(t, 1, fake.entryReads)
+}
+
+func TestCachedDatabase_Invalidation(t *testing.T) {
+	fake := newFake()
+	db := data.NewCachedDatabase(fake, 10, time.Minute)
+
+	db.ReadEntry(1)
+	db.ReadEntry(2)
+	db.ReadEntries(10, 0, "", "", nil)
+	db.ReadEntries(10, 0, "likes=gt=0", "", nil)
+	assert.Equal(t, 4, fake.entryReads)
+
+	// Liking entry 2 drops entry 2, the lists containing it and the filtered list
+	assert.NoError(t, db.LikeEntry(2, "#8", "host"))
+	db.ReadEntry(1)
+	assert.Equal(t, 4, fake.entryReads)
+	entry, _ := db.ReadEntry(2)
+	assert.Equal(t, int64(1), entry.Likes)
+	assert.Equal(t, 5, fake.entryReads)
+
+	// Creating an entry drops all lists but not single entries
+	db.ReadEntries(10, 0, "", "", nil)
+	assert.Equal(t, 6, fake.entryReads)
+	db.CreateEntry(&models.Entry{Id: 3})
+	entries, _ := db.ReadEntries(10, 0, "", "", nil)
+	assert.Len(t, entries, 3)
+	db.ReadEntry(1)
+	assert.Equal(t, 7, fake.entryReads)
+
+	// Updating an article only drops results that contain it
+	db.ReadArticles(10, 0)
+	db.ReadArticles(10, 100)
+	db.UpdateArticle(&models.Article{Id: 1})
+	db.ReadArticles(10, 0)
+	db.ReadArticles(10, 100)
+	assert.Equal(t, 3, fake.articleReads)
+}
+
+func TestCachedDatabase_BoundedAndExpiring(t *testing.T) {
+	fake := newFake()
+	db := data.NewCachedDatabase(fake, 2, 10*time.Millisecond)
+
+	db.ReadEntries(1, 0, "", "", nil)
+	db.ReadEntries(2, 0, "", "", nil)
+	db.ReadEntries(3, 0, "", "", nil)
+	stats := db.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, uint64(1), stats.Evictions)
+
+	time.Sleep(20 * time.Millisecond)
+	db.ReadEntries(3, 0, "", "", nil)
+	assert.Equal(t, 4, fake.entryReads)
+}
+
+func TestCachedDatabase_SkipsLargePages(t *testing.T) {
+	fake := newFake()
+	for id := int64(3); id <= 101; id++ {
+		fake.entries[id] = models.Entry{Id: id}
+	}
+	db := data.NewCachedDatabase(fake, 10, time.Minute)
+
+	db.ReadEntries(1000, 0, "", "", nil)
+	db.ReadEntries(1000, 0, "", "", nil)
+	assert.Equal(t, 2, fake.entryReads)
+	assert.Equal(t, 0, db.Stats().Size)
+
+	// A single entry is still cached
+	db.ReadEntry(1)
+	db.ReadEntry(1)
+	assert.Equal(t, 3, fake.entryReads)
+}
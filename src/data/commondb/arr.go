@@ -1,47 +1,274 @@
 package commondb
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	rsql "github.com/sebastiw/go-rsql-mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sebastiw/sidan-backend/src/calendar"
 	"github.com/sebastiw/sidan-backend/src/models"
 )
 
+// RSQL configuration for arr filtering
+var arrAllowedKeys = []string{
+	"cl2015_arrsidan.namn",
+	"cl2015_arrsidan.plats",
+	"cl2015_arrsidan.organisator",
+}
+
+func newArrParser() (*rsql.Parser, error) {
+	return rsql.NewParser(
+		rsql.MySQL(),
+		rsql.WithKeyTransformers(func(key string) string {
+			return "cl2015_arrsidan." + key
+		}),
+	)
+}
+
+// parseStartsAt interprets the legacy start date, nil if it cannot be
+func parseStartsAt(startDate *string) *time.Time {
+	if startDate == nil {
+		return nil
+	}
+	t, _, err := calendar.ParseStartDate(*startDate)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
 func (d *CommonDatabase) CreateArr(arr *models.Arr) (*models.Arr, error) {
-	result := d.DB.Create(arr)
-	if result.Error != nil {
-		return nil, result.Error
+	arr.StartsAt = parseStartsAt(arr.StartDate)
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(arr).Error; err != nil {
+			return err
+		}
+		return syncLegacyAttendance(tx, arr)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return arr, nil
 }
 
 func (d *CommonDatabase) ReadArr(id int64) (*models.Arr, error) {
 	var arr models.Arr
-	result := d.DB.First(&arr, id)
+	result := d.DB.Preload("Attendance").Preload("Guests").First(&arr, id)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &arr, nil
 }
 
-func (d *CommonDatabase) ReadArrs(take int, skip int) ([]models.Arr, error) {
+// ReadArrs returns a page of arr, newest first unless filter orders by
+// start. Upcoming arr start today or later, in the timezone of the arr.
+func (d *CommonDatabase) ReadArrs(take int, skip int, filter models.ArrFilter) ([]models.Arr, error) {
 	var arrs []models.Arr
-	result := d.DB.Order("id DESC").Limit(take).Offset(skip).Find(&arrs)
+	query := d.DB.Preload("Attendance").Preload("Guests")
+
+	if filter.Query != "" {
+		parser, err := newArrParser()
+		if err != nil {
+			return nil, fmt.Errorf("RSQL parser creation failed: %w", err)
+		}
+		condition, err := parser.Process(filter.Query, rsql.SetAllowedKeys(arrAllowedKeys))
+		if err != nil {
+			return nil, &models.InvalidFilterError{Err: fmt.Errorf("RSQL parse error: %w", err)}
+		}
+		query = query.Where(condition)
+	}
+
+	if filter.Upcoming || filter.Past {
+		now := filter.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		y, m, day := now.In(calendar.Location).Date()
+		today := time.Date(y, m, day, 0, 0, 0, 0, calendar.Location)
+		if filter.Upcoming {
+			query = query.Where("starts_at >= ?", today).Order("starts_at ASC")
+		} else {
+			query = query.Where("starts_at < ?", today).Order("starts_at DESC")
+		}
+	}
+	if filter.From != nil {
+		query = query.Where("starts_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("starts_at < ?", *filter.To)
+	}
+	if (filter.From != nil || filter.To != nil) && !filter.Upcoming && !filter.Past {
+		query = query.Order("starts_at ASC")
+	}
+
+	result := query.Order("id DESC").Limit(take).Offset(skip).Find(&arrs)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return arrs, nil
 }
 
+// BackfillArrStartsAt parses the start date of arr without a start time, or
+// of every arr with all, in batches. Returns the number of arr given a start
+// time and the arr whose start date could not be interpreted.
+func (d *CommonDatabase) BackfillArrStartsAt(all bool) (int, []models.Arr, error) {
+	const batchSize = 500
+	var updated int
+	var unparsed []models.Arr
+	var lastId int64
+	for {
+		var arrs []models.Arr
+		query := d.DB.Select("id", "start_date", "starts_at").Where("id > ?", lastId)
+		if !all {
+			query = query.Where("starts_at IS NULL")
+		}
+		if err := query.Order("id ASC").Limit(batchSize).Find(&arrs).Error; err != nil {
+			return updated, unparsed, err
+		}
+		if len(arrs) == 0 {
+			return updated, unparsed, nil
+		}
+		for _, arr := range arrs {
+			lastId = arr.Id
+			startsAt := parseStartsAt(arr.StartDate)
+			if startsAt == nil {
+				if arr.StartDate != nil && *arr.StartDate != "" {
+					unparsed = append(unparsed, arr)
+				}
+				if arr.StartsAt == nil {
+					continue
+				}
+			} else if arr.StartsAt != nil && arr.StartsAt.Equal(*startsAt) {
+				continue
+			}
+			if err := d.DB.Model(&models.Arr{Id: arr.Id}).Update("starts_at", startsAt).Error; err != nil {
+				return updated, unparsed, err
+			}
+			if startsAt != nil {
+				updated++
+			}
+		}
+	}
+}
+
+// UpdateArr updates the given fields. If any legacy attendance column is
+// given, the attendance is rebuilt from the columns, as old clients edit them
+// directly. The start time follows a given start date.
 func (d *CommonDatabase) UpdateArr(arr *models.Arr) (*models.Arr, error) {
-	result := d.DB.Model(arr).Updates(arr)
-	if result.Error != nil {
-		return nil, result.Error
+	legacy := arr.Deltagare != nil || arr.Kanske != nil || arr.Hetsade != nil
+	arr.StartsAt = nil
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(arr).Omit(clause.Associations).Updates(arr).Error; err != nil {
+			return err
+		}
+		if arr.StartDate != nil {
+			arr.StartsAt = parseStartsAt(arr.StartDate)
+			if err := tx.Model(arr).Update("starts_at", arr.StartsAt).Error; err != nil {
+				return err
+			}
+		}
+		if !legacy {
+			return nil
+		}
+		var stored models.Arr
+		if err := tx.First(&stored, arr.Id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("arr_id = ?", arr.Id).Delete(&models.ArrAttendance{}).Error; err != nil {
+			return err
+		}
+		if err := syncLegacyAttendance(tx, &stored); err != nil {
+			return err
+		}
+		arr.Attendance = stored.Attendance
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return arr, nil
 }
 
 func (d *CommonDatabase) DeleteArr(arr *models.Arr) (*models.Arr, error) {
-	result := d.DB.Delete(arr)
-	if result.Error != nil {
-		return nil, result.Error
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("arr_id = ?", arr.Id).Delete(&models.ArrAttendance{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(arr).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 	return arr, nil
 }
+
+// SetArrAttendance records a member's answer to an arr and updates the
+// legacy columns. Being hetsad never replaces an answer the member already
+// gave. Returns nil if the arr does not exist.
+func (d *CommonDatabase) SetArrAttendance(arrId int64, memberNumber int64, status models.AttendanceStatus) (*models.Arr, error) {
+	return d.changeAttendance(arrId, func(tx *gorm.DB) error {
+		row := models.ArrAttendance{ArrId: arrId, MemberNumber: memberNumber, Status: status, UpdatedAt: time.Now()}
+		onConflict := clause.OnConflict{
+			Columns:   []clause.Column{{Name: "arr_id"}, {Name: "member_number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "updated_at"}),
+		}
+		if status == models.AttendanceHetsad {
+			onConflict = clause.OnConflict{DoNothing: true}
+		}
+		return tx.Clauses(onConflict).Create(&row).Error
+	})
+}
+
+// DeleteArrAttendance removes a member's answer to an arr and updates the
+// legacy columns. Returns nil if the arr does not exist.
+func (d *CommonDatabase) DeleteArrAttendance(arrId int64, memberNumber int64) (*models.Arr, error) {
+	return d.changeAttendance(arrId, func(tx *gorm.DB) error {
+		return tx.Where("arr_id = ? AND member_number = ?", arrId, memberNumber).Delete(&models.ArrAttendance{}).Error
+	})
+}
+
+// changeAttendance runs change on the attendance of a locked arr, then
+// rewrites the legacy columns from the attendance
+func (d *CommonDatabase) changeAttendance(arrId int64, change func(tx *gorm.DB) error) (*models.Arr, error) {
+	var arr models.Arr
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&arr, arrId).Error; err != nil {
+			return err
+		}
+		if err := change(tx); err != nil {
+			return err
+		}
+		if err := tx.Where("arr_id = ?", arrId).Order("updated_at ASC, member_number ASC").Find(&arr.Attendance).Error; err != nil {
+			return err
+		}
+		arr.SetLegacyAttendance(arr.Attendance)
+		return tx.Model(&arr).Select("deltagare", "kanske", "hetsade").Updates(&arr).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &arr, nil
+}
+
+// syncLegacyAttendance stores the members listed in the legacy columns of a
+// newly written arr as attendance rows
+func syncLegacyAttendance(tx *gorm.DB, arr *models.Arr) error {
+	rows := arr.LegacyAttendance()
+	now := time.Now()
+	for i := range rows {
+		rows[i].ArrId = arr.Id
+		rows[i].UpdatedAt = now
+	}
+	arr.Attendance = rows
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
+}
@@ -0,0 +1,165 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/calendar"
+	"github.com/sebastiw/sidan-backend/src/data/commondb"
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func newArrDB(t *testing.T) *commondb.CommonDatabase {
+	return commondbtest.NewDB(t, &models.Arr{}, &models.ArrAttendance{}, &models.Hetsa{})
+}
+
+func str(s string) *string { return &s }
+
+func TestArrAttendance(t *testing.T) {
+	cdb := newArrDB(t)
+	arr, err := cdb.CreateArr(&models.Arr{Namn: str("Fest"), Deltagare: str("#10,GuiGui,#3"), Kanske: str("#8")})
+	require.NoError(t, err)
+	assert.Len(t, arr.Attendance, 3)
+
+	// Answers move members between the legacy lists, guests stay
+	arr, err = cdb.SetArrAttendance(arr.Id, 8, models.AttendanceYes)
+	require.NoError(t, err)
+	assert.Equal(t, "#10,GuiGui,#3,#8", *arr.Deltagare)
+	assert.Equal(t, "", *arr.Kanske)
+
+	arr, err = cdb.SetArrAttendance(arr.Id, 3, models.AttendanceMaybe)
+	require.NoError(t, err)
+	assert.Equal(t, "#10,GuiGui,#8", *arr.Deltagare)
+	assert.Equal(t, "#3", *arr.Kanske)
+
+	// Hetsa does not replace an answer
+	arr, err = cdb.SetArrAttendance(arr.Id, 10, models.AttendanceHetsad)
+	require.NoError(t, err)
+	assert.Equal(t, "", *arr.Hetsade)
+	arr, err = cdb.SetArrAttendance(arr.Id, 42, models.AttendanceHetsad)
+	require.NoError(t, err)
+	assert.Equal(t, "#42", *arr.Hetsade)
+
+	arr, err = cdb.DeleteArrAttendance(arr.Id, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "GuiGui,#8", *arr.Deltagare)
+
+	stored, err := cdb.ReadArr(arr.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "GuiGui,#8", *stored.Deltagare)
+	status := map[int64]models.AttendanceStatus{}
+	for _, a := range stored.Attendance {
+		status[a.MemberNumber] = a.Status
+	}
+	assert.Equal(t, map[int64]models.AttendanceStatus{8: "yes", 3: "maybe", 42: "hetsad"}, status)
+
+	missing, err := cdb.SetArrAttendance(9999, 8, models.AttendanceYes)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestUpdateArr_RebuildsAttendanceFromLegacyColumns(t *testing.T) {
+	cdb := newArrDB(t)
+	arr, err := cdb.CreateArr(&models.Arr{Namn: str("Fest"), Deltagare: str("#10")})
+	require.NoError(t, err)
+
+	// An old client edits the list directly
+	updated, err := cdb.UpdateArr(&models.Arr{Id: arr.Id, Deltagare: str("#10,#11"), Kanske: str("#11,#12")})
+	require.NoError(t, err)
+	require.Len(t, updated.Attendance, 3)
+
+	stored, err := cdb.ReadArr(arr.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "Fest", *stored.Namn)
+	status := map[int64]models.AttendanceStatus{}
+	for _, a := range stored.Attendance {
+		status[a.MemberNumber] = a.Status
+	}
+	assert.Equal(t, map[int64]models.AttendanceStatus{10: "yes", 11: "yes", 12: "maybe"}, status)
+
+	// Other fields leave the attendance alone
+	_, err = cdb.UpdateArr(&models.Arr{Id: arr.Id, Plats: str("Hubben")})
+	require.NoError(t, err)
+	stored, err = cdb.ReadArr(arr.Id)
+	require.NoError(t, err)
+	assert.Len(t, stored.Attendance, 3)
+}
+
+func TestReadArrs_Filter(t *testing.T) {
+	cdb := newArrDB(t)
+	for _, a := range []models.Arr{
+		{Namn: str("Kräftskiva"), StartDate: str("2026-08-20 18:00"), Plats: str("Hubben")},
+		{Namn: str("Pubrunda"), StartDate: str("2026-10-18"), Plats: str("Stan")},
+		{Namn: str("Julfest"), StartDate: str("2026-12-12 19:00"), Plats: str("Hubben")},
+		{Namn: str("Någon gång"), StartDate: str("snart")},
+	} {
+		_, err := cdb.CreateArr(&a)
+		require.NoError(t, err)
+	}
+	now := time.Date(2026, 10, 18, 21, 0, 0, 0, calendar.Location)
+	names := func(filter models.ArrFilter) []string {
+		t.Helper()
+		arrs, err := cdb.ReadArrs(10, 0, filter)
+		require.NoError(t, err)
+		var names []string
+		for _, a := range arrs {
+			names = append(names, *a.Namn)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Någon gång", "Julfest", "Pubrunda", "Kräftskiva"}, names(models.ArrFilter{}))
+	assert.Equal(t, []string{"Pubrunda", "Julfest"}, names(models.ArrFilter{Upcoming: true, Now: now}))
+	assert.Equal(t, []string{"Kräftskiva"}, names(models.ArrFilter{Past: true, Now: now}))
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, calendar.Location)
+	to := time.Date(2027, 1, 1, 0, 0, 0, 0, calendar.Location)
+	assert.Equal(t, []string{"Pubrunda", "Julfest"}, names(models.ArrFilter{From: &from, To: &to}))
+	assert.Equal(t, []string{"Julfest", "Kräftskiva"}, names(models.ArrFilter{Query: `plats=="Hubben"`}))
+	assert.Equal(t, []string{"Julfest"}, names(models.ArrFilter{Query: `plats=="Hubben"`, Upcoming: true, Now: now}))
+
+	_, err := cdb.ReadArrs(10, 0, models.ArrFilter{Query: `losen=="hemligt"`})
+	assert.Error(t, err)
+}
+
+func TestUpdateArr_ParsesStartDate(t *testing.T) {
+	cdb := newArrDB(t)
+	arr, err := cdb.CreateArr(&models.Arr{Namn: str("Fest"), StartDate: str("2026-10-18 20:00")})
+	require.NoError(t, err)
+	require.NotNil(t, arr.StartsAt)
+
+	updated, err := cdb.UpdateArr(&models.Arr{Id: arr.Id, StartDate: str("2026-10-19 kl 21:30")})
+	require.NoError(t, err)
+	assert.True(t, time.Date(2026, 10, 19, 21, 30, 0, 0, calendar.Location).Equal(*updated.StartsAt))
+
+	_, err = cdb.UpdateArr(&models.Arr{Id: arr.Id, StartDate: str("någon gång")})
+	require.NoError(t, err)
+	read, err := cdb.ReadArr(arr.Id)
+	require.NoError(t, err)
+	assert.Nil(t, read.StartsAt)
+}
+
+func TestBackfillArrStartsAt(t *testing.T) {
+	cdb := newArrDB(t)
+	for _, raw := range []string{"2001-07-22 ca 20", "22/7-01 20:00", "snart", ""} {
+		require.NoError(t, cdb.DB.Create(&models.Arr{StartDate: str(raw)}).Error)
+	}
+
+	updated, unparsed, err := cdb.BackfillArrStartsAt(false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+	require.Len(t, unparsed, 1)
+	assert.Equal(t, "snart", *unparsed[0].StartDate)
+
+	updated, _, err = cdb.BackfillArrStartsAt(false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, updated)
+
+	arr, err := cdb.ReadArr(2)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2001, 7, 22, 20, 0, 0, 0, calendar.Location).Equal(*arr.StartsAt))
+}
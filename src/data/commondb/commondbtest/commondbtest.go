@@ -0,0 +1,21 @@
+// Package commondbtest sets up databases for tests of code using commondb
+package commondbtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb"
+)
+
+// NewDB returns a database in SQLite memory with tables for models
+func NewDB(t testing.TB, models ...interface{}) *commondb.CommonDatabase {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(models...))
+	return &commondb.CommonDatabase{DB: db}
+}
@@ -1,6 +1,7 @@
 package commondb
 
 import (
+	"errors"
 	"log/slog"
 
 	"gorm.io/gorm"
@@ -42,3 +43,12 @@ func (d *CommonDatabase) CommitTransaction(tx *gorm.DB) error {
 func (d *CommonDatabase) RollbackTransaction(tx *gorm.DB) error {
 	return tx.Rollback().Error
 }
+
+// isDuplicateKey tells whether err is a unique constraint violation, in the
+// words of whichever driver db uses
+func isDuplicateKey(db *gorm.DB, err error) bool {
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = t.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
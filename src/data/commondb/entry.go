@@ -2,6 +2,7 @@ package commondb
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	rsql "github.com/sebastiw/go-rsql-mysql"
@@ -70,39 +71,127 @@ func (d *CommonDatabase) ReadEntry(id int64) (*models.Entry, error) {
 	}
 
 	// Compute virtual fields
-	entry.Likes = int64(len(entry.LikeRecords))
-	entry.Secret = len(entry.Permissions) > 0
-	entry.PersonalSecret = false
-	for _, perm := range entry.Permissions {
-		if perm.UserId != 0 {
-			entry.PersonalSecret = true
-			break
+	computeEntryVirtualFields(&entry)
+
+	return &entry, nil
+}
+
+// entryKeyTransformer maps RSQL keys to SQL expressions
+func entryKeyTransformer(key string) string {
+	// Map virtual fields to SQL expressions
+	if sqlExpr, ok := entryVirtualMap[key]; ok {
+		return sqlExpr
+	}
+	// Prefix regular fields with table name for JOIN clarity
+	return "cl2003_msgs." + key
+}
+
+func newEntryParser() (*rsql.Parser, error) {
+	return rsql.NewParser(
+		rsql.MySQL(),
+		rsql.WithKeyTransformers(entryKeyTransformer),
+	)
+}
+
+func isEntryAllowedKey(expr string) bool {
+	for _, k := range entryAllowedKeys {
+		if k == expr {
+			return true
 		}
 	}
+	return false
+}
 
-	return &entry, nil
+// parseEntrySort turns "-likes,datetime" into ORDER BY expressions. The
+// returned bool tells if the expressions need the likes/sidekicks joins.
+func parseEntrySort(sort string) ([]string, bool, error) {
+	var orders []string
+	needsJoin := false
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		direction := "ASC"
+		switch key[0] {
+		case '-':
+			direction = "DESC"
+			key = key[1:]
+		case '+':
+			key = key[1:]
+		}
+		expr := entryKeyTransformer(key)
+		if !isEntryAllowedKey(expr) {
+			return nil, false, fmt.Errorf("sort key '%s' not allowed", key)
+		}
+		if key == "kumpaner" {
+			// One entry has many sidekicks, so there is no single value to sort on
+			return nil, false, fmt.Errorf("sort key '%s' not allowed", key)
+		}
+		if _, ok := entryVirtualMap[key]; ok {
+			needsJoin = true
+		}
+		orders = append(orders, expr+" "+direction)
+	}
+	return orders, needsJoin, nil
 }
 
-func (d *CommonDatabase) ReadEntries(take int, skip int, rsqlFilter string) ([]models.Entry, error) {
+// parseEntryFields validates a sparse fieldset and returns the columns to
+// select. id and sig are always selected since they are needed to load
+// relationships and to filter secret entries.
+func parseEntryFields(fields []string) (columns []string, likes bool, sideKicks bool, err error) {
+	columns = []string{"cl2003_msgs.id", "cl2003_msgs.sig"}
+	for _, key := range fields {
+		key = strings.TrimSpace(key)
+		if key == "" || key == "id" || key == "sig" {
+			continue
+		}
+		expr := entryKeyTransformer(key)
+		if !isEntryAllowedKey(expr) {
+			return nil, false, false, fmt.Errorf("field '%s' not allowed", key)
+		}
+		switch key {
+		case "likes":
+			likes = true
+		case "kumpaner":
+			sideKicks = true
+		default:
+			columns = append(columns, expr)
+		}
+	}
+	return columns, likes, sideKicks, nil
+}
+
+// ReadEntries returns a page of entries. filter is an RSQL expression, sort a
+// comma separated list of keys (prefix with - for descending) and fields an
+// optional sparse fieldset, all validated against entryAllowedKeys.
+func (d *CommonDatabase) ReadEntries(take int, skip int, rsqlFilter string, sort string, fields []string) ([]models.Entry, error) {
 	var entries []models.Entry
 
 	// Start with base query
 	query := d.DB.Model(&models.Entry{})
 
+	orders, sortNeedsJoin, err := parseEntrySort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	selectColumns := []string{"cl2003_msgs.*"}
+	preloadLikes, preloadSideKicks := true, true
+	if len(fields) > 0 {
+		selectColumns, preloadLikes, preloadSideKicks, err = parseEntryFields(fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+	query = query.Select(strings.Join(selectColumns, ", "))
+
+	var whereClause, havingClause string
+
 	// If RSQL filtering requested, parse and apply
 	if rsqlFilter != "" {
 		// Create parser with key transformer
-		parser, err := rsql.NewParser(
-			rsql.MySQL(),
-			rsql.WithKeyTransformers(func(key string) string {
-				// Map virtual fields to SQL expressions
-				if sqlExpr, ok := entryVirtualMap[key]; ok {
-					return sqlExpr
-				}
-				// Prefix regular fields with table name for JOIN clarity
-				return "cl2003_msgs." + key
-			}),
-		)
+		parser, err := newEntryParser()
 		if err != nil {
 			return nil, fmt.Errorf("RSQL parser creation failed: %w", err)
 		}
@@ -113,12 +202,13 @@ func (d *CommonDatabase) ReadEntries(take int, skip int, rsqlFilter string) ([]m
 			return nil, fmt.Errorf("RSQL parse error: %w", err)
 		}
 
-		whereClause, havingClause := SplitWhereHaving(sqlCondition)
+		whereClause, havingClause = SplitWhereHaving(sqlCondition)
+	}
 
+	if rsqlFilter != "" || sortNeedsJoin {
 		// Apply joins
 		// Using aliases that match entryVirtualMap
 		query = query.
-			Select("cl2003_msgs.*").
 			Joins("LEFT JOIN `2003_likes` LikeRecords ON LikeRecords.id = cl2003_msgs.id").
 			Joins("LEFT JOIN `cl2003_msgs_kumpaner` SideKicks ON SideKicks.id = cl2003_msgs.id")
 
@@ -136,13 +226,23 @@ func (d *CommonDatabase) ReadEntries(take int, skip int, rsqlFilter string) ([]m
 		}
 	}
 
-	// Execute query with ordering and pagination
+	// Requested ordering first, newest id as tie-breaker
+	for _, order := range orders {
+		query = query.Order(order)
+	}
+	query = query.Order("cl2003_msgs.id DESC") // Explicit table prefix to avoid ambiguity
+
+	if preloadSideKicks {
+		query = query.Preload("SideKicks")
+	}
+	if preloadLikes {
+		query = query.Preload("LikeRecords")
+	}
+
+	// Execute query with pagination
 	result := query.
-		Order("cl2003_msgs.id DESC"). // Explicit table prefix to avoid ambiguity
 		Limit(take).
 		Offset(skip).
-		Preload("SideKicks").
-		Preload("LikeRecords").
 		Preload("Permissions").
 		Find(&entries)
 
@@ -152,20 +252,25 @@ func (d *CommonDatabase) ReadEntries(take int, skip int, rsqlFilter string) ([]m
 
 	// Post-process: compute virtual fields from loaded relationships
 	for i := range entries {
-		entries[i].Likes = int64(len(entries[i].LikeRecords))
-		entries[i].Secret = len(entries[i].Permissions) > 0
-		entries[i].PersonalSecret = false
-		for _, perm := range entries[i].Permissions {
-			if perm.UserId != 0 {
-				entries[i].PersonalSecret = true
-				break
-			}
-		}
+		computeEntryVirtualFields(&entries[i])
 	}
 
 	return entries, nil
 }
 
+// computeEntryVirtualFields sets the computed fields from loaded relationships
+func computeEntryVirtualFields(entry *models.Entry) {
+	entry.Likes = int64(len(entry.LikeRecords))
+	entry.Secret = len(entry.Permissions) > 0
+	entry.PersonalSecret = false
+	for _, perm := range entry.Permissions {
+		if perm.UserId != 0 {
+			entry.PersonalSecret = true
+			break
+		}
+	}
+}
+
 func (d *CommonDatabase) UpdateEntry(entry *models.Entry) (*models.Entry, error) {
 	result := d.DB.Model(entry).Updates(entry)
 
@@ -208,3 +313,97 @@ func (d *CommonDatabase) LikeEntry(entryId int64, sig string, host string) error
 	result = d.DB.Table("2003_likes").Create(like)
 	return result.Error
 }
+
+// ExportBatchSize is the number of entries whose relationships are loaded at
+// once while streaming an export
+var ExportBatchSize = 500
+
+// ExportEntries streams all entries with from <= datetime < to (zero times
+// are unbounded) in id order through a database cursor. Sidekicks, likes and
+// permissions are loaded per batch so memory use stays bounded.
+func (d *CommonDatabase) ExportEntries(from time.Time, to time.Time, fn func(*models.Entry) error) error {
+	query := d.DB.Model(&models.Entry{}).Order("cl2003_msgs.id ASC")
+	if !from.IsZero() {
+		query = query.Where("cl2003_msgs.datetime >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("cl2003_msgs.datetime < ?", to)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make([]models.Entry, 0, ExportBatchSize)
+	for rows.Next() {
+		var entry models.Entry
+		if err := d.DB.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		batch = append(batch, entry)
+		if len(batch) >= ExportBatchSize {
+			if err := d.flushExportBatch(batch, fn); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return d.flushExportBatch(batch, fn)
+}
+
+func (d *CommonDatabase) flushExportBatch(batch []models.Entry, fn func(*models.Entry) error) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(batch))
+	for i, e := range batch {
+		ids[i] = e.Id
+	}
+
+	var sideKicks []models.SideKick
+	if err := d.DB.Where("id IN ?", ids).Find(&sideKicks).Error; err != nil {
+		return err
+	}
+	var likes []models.Like
+	if err := d.DB.Where("id IN ?", ids).Find(&likes).Error; err != nil {
+		return err
+	}
+	var permissions []models.Permission
+	if err := d.DB.Where("id IN ?", ids).Find(&permissions).Error; err != nil {
+		return err
+	}
+
+	index := make(map[int64]*models.Entry, len(batch))
+	for i := range batch {
+		index[batch[i].Id] = &batch[i]
+	}
+	for _, s := range sideKicks {
+		if e, ok := index[s.Id]; ok {
+			e.SideKicks = append(e.SideKicks, s)
+		}
+	}
+	for _, l := range likes {
+		if e, ok := index[l.Id]; ok {
+			e.LikeRecords = append(e.LikeRecords, l)
+		}
+	}
+	for _, p := range permissions {
+		if e, ok := index[p.Id]; ok {
+			e.Permissions = append(e.Permissions, p)
+		}
+	}
+
+	for i := range batch {
+		computeEntryVirtualFields(&batch[i])
+		if err := fn(&batch[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -69,14 +69,14 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 
 	// 4. Test Case A: No Filter (Should return all 3)
 	t.Run("no filter returns all entries", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, "")
+		res, err := repo.ReadEntries(10, 0, "", "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res, 3, "Should return all 3 entries")
 	})
 
 	// 5. Test Case B: RSQL Filter "likes > 5"
 	t.Run("filter likes greater than 5", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, "likes=gt=5")
+		res, err := repo.ReadEntries(10, 0, "likes=gt=5", "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res, 1, "Should only return entries with >5 likes")
 		assert.Equal(t, "Alice", res[0].Sig)
@@ -85,7 +85,7 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 
 	// 6. Test Case C: RSQL Filter "likes < 2"
 	t.Run("filter likes less than 2", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, "likes=lt=2")
+		res, err := repo.ReadEntries(10, 0, "likes=lt=2", "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res, 1, "Should only return entries with <2 likes")
 		assert.Equal(t, "Bob", res[0].Sig)
@@ -94,7 +94,7 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 
 	// 7. Test Case D: RSQL Filter "likes == 3"
 	t.Run("filter likes equals 3", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, "likes==3")
+		res, err := repo.ReadEntries(10, 0, "likes==3", "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res, 1, "Should only return entries with exactly 3 likes")
 		assert.Equal(t, "Charlie", res[0].Sig)
@@ -102,7 +102,7 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 
 	// 8. Test Case E: RSQL Filter by signature
 	t.Run("filter by signature", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, `sig=="Alice"`)
+		res, err := repo.ReadEntries(10, 0, `sig=="Alice"`, "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res, 1, "Should only return Alice's entry")
 		assert.Equal(t, "Alice", res[0].Sig)
@@ -110,7 +110,7 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 
 	// 9. Test Case F: Complex AND filter (likes AND sig)
 	t.Run("complex AND filter", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, `likes=gt=2;sig=="Charlie"`)
+		res, err := repo.ReadEntries(10, 0, `likes=gt=2;sig=="Charlie"`, "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res, 1, "Should return Charlie with >2 likes")
 		assert.Equal(t, "Charlie", res[0].Sig)
@@ -118,14 +118,14 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 
 	// 10. Test Case G: Complex OR filter
 	t.Run("complex OR filter", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, `sig=="Alice",sig=="Bob"`)
+		res, err := repo.ReadEntries(10, 0, `sig=="Alice",sig=="Bob"`, "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res, 2, "Should return both Alice and Bob")
 	})
 
 	// 11. Test Case H: Message content filter
 	t.Run("filter by message content", func(t *testing.T) {
-		res, err := repo.ReadEntries(10, 0, `msg=="beer"`)
+		res, err := repo.ReadEntries(10, 0, `msg=="beer"`, "", nil)
 		assert.NoError(t, err)
 		// Note: This might return 0 results depending on exact match vs contains
 		// The RSQL library uses = which is exact match
@@ -138,26 +138,72 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 
 	// 12. Test Case I: Invalid field should return error
 	t.Run("invalid field returns error", func(t *testing.T) {
-		_, err := repo.ReadEntries(10, 0, `email=="test@example.com"`)
+		_, err := repo.ReadEntries(10, 0, `email=="test@example.com"`, "", nil)
 		assert.Error(t, err, "Should return error for disallowed field")
 		assert.Contains(t, err.Error(), "not allowed")
 	})
 
 	// 13. Test Case J: Invalid syntax should return error
 	t.Run("invalid RSQL syntax returns error", func(t *testing.T) {
-		_, err := repo.ReadEntries(10, 0, "invalid==")
+		_, err := repo.ReadEntries(10, 0, "invalid==", "", nil)
 		assert.Error(t, err, "Should return error for invalid syntax")
 	})
 
+	// 15. Test Case L: Sort by virtual field
+	t.Run("sort by likes descending", func(t *testing.T) {
+		res, err := repo.ReadEntries(10, 0, "", "-likes", nil)
+		assert.NoError(t, err)
+		assert.Len(t, res, 3)
+		assert.Equal(t, "Alice", res[0].Sig)
+		assert.Equal(t, "Charlie", res[1].Sig)
+		assert.Equal(t, "Bob", res[2].Sig)
+	})
+
+	// 16. Test Case M: Sort by several keys combined with filter
+	t.Run("sort by likes then datetime with filter", func(t *testing.T) {
+		res, err := repo.ReadEntries(10, 0, "likes=lt=5", "likes,-datetime", nil)
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "Bob", res[0].Sig)
+		assert.Equal(t, "Charlie", res[1].Sig)
+	})
+
+	// 17. Test Case N: Disallowed sort keys
+	t.Run("invalid sort key returns error", func(t *testing.T) {
+		_, err := repo.ReadEntries(10, 0, "", "email", nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not allowed")
+
+		_, err = repo.ReadEntries(10, 0, "", "-kumpaner", nil)
+		assert.Error(t, err)
+	})
+
+	// 18. Test Case O: Sparse fieldset
+	t.Run("fields only loads requested columns", func(t *testing.T) {
+		res, err := repo.ReadEntries(10, 0, "", "-likes", []string{"msg", "likes"})
+		assert.NoError(t, err)
+		assert.Len(t, res, 3)
+		assert.Equal(t, "Great post about beer", res[0].Msg)
+		assert.Equal(t, int64(10), res[0].Likes)
+		assert.True(t, res[0].DateTime.IsZero(), "datetime was not requested")
+	})
+
+	// 19. Test Case P: Disallowed field
+	t.Run("invalid field in fieldset returns error", func(t *testing.T) {
+		_, err := repo.ReadEntries(10, 0, "", "", []string{"email"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not allowed")
+	})
+
 	// 14. Test Case K: Pagination
 	t.Run("pagination works with filter", func(t *testing.T) {
 		// Get first 2 entries
-		res1, err := repo.ReadEntries(2, 0, "")
+		res1, err := repo.ReadEntries(2, 0, "", "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res1, 2)
 
 		// Get next entry (skip first 2)
-		res2, err := repo.ReadEntries(2, 2, "")
+		res2, err := repo.ReadEntries(2, 2, "", "", nil)
 		assert.NoError(t, err)
 		assert.Len(t, res2, 1)
 
@@ -165,3 +211,58 @@ func TestReadEntries_WithRSQL(t *testing.T) {
 		assert.NotEqual(t, res1[0].Id, res2[0].Id)
 	})
 }
+
+// TestExportEntries tests that the export cursor streams entries in id order
+// with relationships loaded across batch boundaries
+func TestExportEntries(t *testing.T) {
+	// Shared cache so the batch queries see the same database as the cursor
+	db, err := gorm.Open(sqlite.Open("file:export?mode=memory&cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	db.AutoMigrate(&models.Entry{})
+	db.Exec("CREATE TABLE IF NOT EXISTS `2003_likes` (date TEXT, time TEXT, id INTEGER, sig TEXT, host TEXT)")
+	db.Exec("CREATE TABLE IF NOT EXISTS `cl2003_msgs_kumpaner` (id INTEGER, number TEXT)")
+	db.Exec("CREATE TABLE IF NOT EXISTS `cl2003_permissions` (id INTEGER, user_id INTEGER)")
+
+	base := time.Date(2003, 1, 1, 12, 0, 0, 0, time.UTC)
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		e := models.Entry{Sig: "#1", Msg: "entry", DateTime: base.AddDate(0, 0, i)}
+		db.Create(&e)
+		ids = append(ids, e.Id)
+	}
+	db.Exec("INSERT INTO `2003_likes` (id, sig) VALUES (?, ?), (?, ?)", ids[4], "#2", ids[4], "#3")
+	db.Exec("INSERT INTO `cl2003_msgs_kumpaner` (id, number) VALUES (?, ?)", ids[3], "8")
+	db.Exec("INSERT INTO `cl2003_permissions` (id, user_id) VALUES (?, ?)", ids[2], 8)
+
+	repo := commondb.CommonDatabase{DB: db}
+	oldBatchSize := commondb.ExportBatchSize
+	commondb.ExportBatchSize = 2
+	defer func() { commondb.ExportBatchSize = oldBatchSize }()
+
+	t.Run("exports everything in id order", func(t *testing.T) {
+		var got []models.Entry
+		err := repo.ExportEntries(time.Time{}, time.Time{}, func(e *models.Entry) error {
+			got = append(got, *e)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, got, 5)
+		for i := range got {
+			assert.Equal(t, ids[i], got[i].Id)
+		}
+		assert.True(t, got[2].PersonalSecret)
+		assert.Len(t, got[3].SideKicks, 1)
+		assert.Equal(t, int64(2), got[4].Likes)
+	})
+
+	t.Run("exports date range", func(t *testing.T) {
+		var got []int64
+		err := repo.ExportEntries(base.AddDate(0, 0, 1), base.AddDate(0, 0, 3), func(e *models.Entry) error {
+			got = append(got, e.Id)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{ids[1], ids[2]}, got)
+	})
+}
@@ -0,0 +1,46 @@
+package commondb
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ReadFeedSecret returns the secret of a member's feed, or nil if there is none
+func (d *CommonDatabase) ReadFeedSecret(memberNumber int64, feed string) (*models.FeedSecret, error) {
+	var secret models.FeedSecret
+	result := d.DB.Where("member_number = ? AND feed = ?", memberNumber, feed).First(&secret)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &secret, nil
+}
+
+// CreateFeedSecret stores the secret unless the feed already has one, and
+// returns the stored secret. Concurrent first reads thus agree on one secret.
+func (d *CommonDatabase) CreateFeedSecret(secret *models.FeedSecret) (*models.FeedSecret, error) {
+	if err := d.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(secret).Error; err != nil {
+		return nil, err
+	}
+	return d.ReadFeedSecret(secret.MemberNumber, secret.Feed)
+}
+
+// UpdateFeedSecret stores the secret, replacing and thereby revoking any
+// former secret of the feed
+func (d *CommonDatabase) UpdateFeedSecret(secret *models.FeedSecret) error {
+	return d.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "member_number"}, {Name: "feed"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "created_at"}),
+	}).Create(secret).Error
+}
+
+// DeleteFeedSecret revokes the secret of a member's feed
+func (d *CommonDatabase) DeleteFeedSecret(memberNumber int64, feed string) error {
+	return d.DB.Where("member_number = ? AND feed = ?", memberNumber, feed).Delete(&models.FeedSecret{}).Error
+}
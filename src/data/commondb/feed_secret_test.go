@@ -0,0 +1,44 @@
+package commondb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestFeedSecrets(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.FeedSecret{})
+
+	secret, err := cdb.ReadFeedSecret(8, "arr.ics")
+	require.NoError(t, err)
+	assert.Nil(t, secret)
+
+	secret, err = cdb.CreateFeedSecret(&models.FeedSecret{MemberNumber: 8, Feed: "arr.ics", Secret: "first"})
+	require.NoError(t, err)
+	assert.Equal(t, "first", secret.Secret)
+
+	// A concurrent first read gets the stored secret
+	secret, err = cdb.CreateFeedSecret(&models.FeedSecret{MemberNumber: 8, Feed: "arr.ics", Secret: "second"})
+	require.NoError(t, err)
+	assert.Equal(t, "first", secret.Secret)
+
+	require.NoError(t, cdb.UpdateFeedSecret(&models.FeedSecret{MemberNumber: 8, Feed: "arr.ics", Secret: "renewed"}))
+	secret, err = cdb.ReadFeedSecret(8, "arr.ics")
+	require.NoError(t, err)
+	assert.Equal(t, "renewed", secret.Secret)
+
+	// Feeds have their own secrets
+	_, err = cdb.CreateFeedSecret(&models.FeedSecret{MemberNumber: 8, Feed: "members.vcf", Secret: "contacts"})
+	require.NoError(t, err)
+	require.NoError(t, cdb.DeleteFeedSecret(8, "arr.ics"))
+	secret, err = cdb.ReadFeedSecret(8, "arr.ics")
+	require.NoError(t, err)
+	assert.Nil(t, secret)
+	secret, err = cdb.ReadFeedSecret(8, "members.vcf")
+	require.NoError(t, err)
+	assert.Equal(t, "contacts", secret.Secret)
+}
@@ -0,0 +1,53 @@
+package commondb
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *CommonDatabase) CreateHetsa(hetsa *models.Hetsa) (*models.Hetsa, error) {
+	hetsa.LegacyArr = strconv.FormatInt(hetsa.ArrId, 10)
+	hetsa.Name1 = "#" + strconv.FormatInt(hetsa.HetsadBy, 10)
+	if hetsa.Status == "" {
+		hetsa.Status = models.HetsaPending
+	}
+	if err := d.DB.Create(hetsa).Error; err != nil {
+		return nil, err
+	}
+	return hetsa, nil
+}
+
+// ReadHetsaByCode returns the invitation with the code, or nil if there is
+// none
+func (d *CommonDatabase) ReadHetsaByCode(code string) (*models.Hetsa, error) {
+	var hetsa models.Hetsa
+	result := d.DB.Where("mystring = ? AND arr_id IS NOT NULL", code).First(&hetsa)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &hetsa, nil
+}
+
+// RespondHetsa records the invitee's answer. The answer can be changed.
+// Returns nil if there is no invitation with the code.
+func (d *CommonDatabase) RespondHetsa(code string, status models.HetsaStatus, at time.Time) (*models.Hetsa, error) {
+	hetsa, err := d.ReadHetsaByCode(code)
+	if err != nil || hetsa == nil {
+		return nil, err
+	}
+	hetsa.Status = status
+	hetsa.RespondedAt = &at
+	result := d.DB.Model(hetsa).Select("status", "responded_at").Updates(hetsa)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return hetsa, nil
+}
@@ -0,0 +1,46 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestHetsa(t *testing.T) {
+	cdb := newArrDB(t)
+	arr, err := cdb.CreateArr(&models.Arr{Namn: str("Fest")})
+	require.NoError(t, err)
+
+	hetsa, err := cdb.CreateHetsa(&models.Hetsa{ArrId: arr.Id, HetsadBy: 8, Name: "Gäst", Email: "gast@example.com", Code: "abc123def456"})
+	require.NoError(t, err)
+	assert.Equal(t, models.HetsaPending, hetsa.Status)
+	assert.Equal(t, "#8", hetsa.Name1)
+
+	found, err := cdb.ReadHetsaByCode("abc123def456")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, hetsa.Id, found.Id)
+
+	missing, err := cdb.ReadHetsaByCode("nope")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	at := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	answered, err := cdb.RespondHetsa("abc123def456", models.HetsaDeclined, at)
+	require.NoError(t, err)
+	assert.Equal(t, models.HetsaDeclined, answered.Status)
+
+	read, err := cdb.ReadArr(arr.Id)
+	require.NoError(t, err)
+	require.Len(t, read.Guests, 1)
+	assert.Equal(t, models.HetsaDeclined, read.Guests[0].Status)
+	require.NotNil(t, read.Guests[0].RespondedAt)
+
+	none, err := cdb.RespondHetsa("nope", models.HetsaAccepted, at)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
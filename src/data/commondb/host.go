@@ -0,0 +1,14 @@
+package commondb
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *CommonDatabase) ReadHostPatterns() ([]models.HostPattern, error) {
+	var patterns []models.HostPattern
+	result := d.DB.Order("id ASC").Find(&patterns)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return patterns, nil
+}
@@ -0,0 +1,54 @@
+package commondb
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *CommonDatabase) CreateIdentity(identity *models.Identity) (*models.Identity, error) {
+	if err := d.DB.Create(identity).Error; err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ReadIdentity returns the identity of the provider account, or nil if it is
+// not linked to any member
+func (d *CommonDatabase) ReadIdentity(provider string, providerUserID string) (*models.Identity, error) {
+	var identity models.Identity
+	result := d.DB.Where("provider = ? AND provider_user_id = ?", provider, providerUserID).First(&identity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &identity, nil
+}
+
+func (d *CommonDatabase) ReadIdentities(memberNumber int64) ([]models.Identity, error) {
+	var identities []models.Identity
+	result := d.DB.Where("member_number = ?", memberNumber).Order("provider ASC, id ASC").Find(&identities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return identities, nil
+}
+
+// UpdateIdentityLogin records a login, keeping the provider email current
+func (d *CommonDatabase) UpdateIdentityLogin(id int64, email string, at time.Time) error {
+	return d.DB.Model(&models.Identity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "last_login_at": at}).Error
+}
+
+func (d *CommonDatabase) DeleteIdentity(identity *models.Identity) (*models.Identity, error) {
+	if err := d.DB.Delete(identity).Error; err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,63 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestIdentities(t *testing.T) {
+	repo := commondbtest.NewDB(t, &models.Identity{})
+
+	github, err := repo.CreateIdentity(&models.Identity{MemberNumber: 8, Provider: "github", ProviderUserID: "1234", Email: "atta@users.noreply.github.com"})
+	require.NoError(t, err)
+	_, err = repo.CreateIdentity(&models.Identity{MemberNumber: 8, Provider: "google", ProviderUserID: "1234", Email: "atta@gmail.com"})
+	require.NoError(t, err)
+
+	t.Run("a provider account links to one member only", func(t *testing.T) {
+		_, err := repo.CreateIdentity(&models.Identity{MemberNumber: 9, Provider: "github", ProviderUserID: "1234"})
+		assert.Error(t, err)
+	})
+
+	t.Run("lookup by provider user id", func(t *testing.T) {
+		identity, err := repo.ReadIdentity("github", "1234")
+		require.NoError(t, err)
+		require.NotNil(t, identity)
+		assert.Equal(t, int64(8), identity.MemberNumber)
+
+		identity, err = repo.ReadIdentity("github", "5678")
+		assert.NoError(t, err)
+		assert.Nil(t, identity)
+	})
+
+	t.Run("login is recorded", func(t *testing.T) {
+		at := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+		require.NoError(t, repo.UpdateIdentityLogin(github.Id, "atta@example.com", at))
+
+		identity, err := repo.ReadIdentity("github", "1234")
+		require.NoError(t, err)
+		assert.Equal(t, "atta@example.com", identity.Email)
+		require.NotNil(t, identity.LastLoginAt)
+		assert.True(t, identity.LastLoginAt.Equal(at))
+	})
+
+	t.Run("list and unlink", func(t *testing.T) {
+		identities, err := repo.ReadIdentities(8)
+		require.NoError(t, err)
+		require.Len(t, identities, 2)
+		assert.Equal(t, "github", identities[0].Provider)
+
+		_, err = repo.DeleteIdentity(&identities[0])
+		require.NoError(t, err)
+
+		identities, err = repo.ReadIdentities(8)
+		require.NoError(t, err)
+		assert.Len(t, identities, 1)
+		assert.Equal(t, "google", identities[0].Provider)
+	})
+}
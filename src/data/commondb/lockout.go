@@ -0,0 +1,59 @@
+package commondb
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ReadLoginLockout returns the failed logins of the member, or nil if there
+// are none
+func (d *CommonDatabase) ReadLoginLockout(memberNumber int64) (*models.LoginLockout, error) {
+	var lockout models.LoginLockout
+	result := d.DB.Where("member_number = ?", memberNumber).First(&lockout)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &lockout, nil
+}
+
+// RecordFailedLogin counts a failed login at the given time. The
+// maxAttempts:th failure in a row locks the account for lockFor and starts
+// the count over. Concurrent failures of the same member are counted one at a
+// time by locking the row.
+func (d *CommonDatabase) RecordFailedLogin(memberNumber int64, at time.Time, maxAttempts int, lockFor time.Duration) (*models.LoginLockout, error) {
+	var lockout models.LoginLockout
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		// Make sure there is a row to lock, the first failure creates it
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LoginLockout{MemberNumber: memberNumber}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("member_number = ?", memberNumber).First(&lockout).Error; err != nil {
+			return err
+		}
+		lockout.FailedAttempts++
+		lockout.LastFailedAt = &at
+		if maxAttempts > 0 && lockout.FailedAttempts >= maxAttempts {
+			until := at.Add(lockFor)
+			lockout.LockedUntil = &until
+			lockout.FailedAttempts = 0
+		}
+		return tx.Save(&lockout).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// ClearFailedLogins forgets the failed logins of the member
+func (d *CommonDatabase) ClearFailedLogins(memberNumber int64) error {
+	return d.DB.Where("member_number = ?", memberNumber).Delete(&models.LoginLockout{}).Error
+}
@@ -0,0 +1,60 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestRecordFailedLogin(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.LoginLockout{})
+
+	lockout, err := cdb.ReadLoginLockout(8)
+	require.NoError(t, err)
+	assert.Nil(t, lockout)
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for i := 1; i < 3; i++ {
+		lockout, err = cdb.RecordFailedLogin(8, now, 3, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, i, lockout.FailedAttempts)
+		assert.False(t, lockout.IsLocked(now))
+	}
+
+	// The third failure locks and starts the count over
+	lockout, err = cdb.RecordFailedLogin(8, now, 3, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, lockout.IsLocked(now))
+	assert.False(t, lockout.IsLocked(now.Add(time.Minute)))
+	assert.Equal(t, 0, lockout.FailedAttempts)
+
+	stored, err := cdb.ReadLoginLockout(8)
+	require.NoError(t, err)
+	assert.True(t, stored.IsLocked(now))
+
+	require.NoError(t, cdb.ClearFailedLogins(8))
+	stored, err = cdb.ReadLoginLockout(8)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestSetMemberPassword(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.Member{})
+
+	classic := "hemligt"
+	_, err := cdb.CreateMember(&models.Member{Number: 8, Password_classic: &classic})
+	require.NoError(t, err)
+
+	require.NoError(t, cdb.SetMemberPassword(8, "$2a$10$hash"))
+	member, err := cdb.ReadMemberByNumber(8)
+	require.NoError(t, err)
+	assert.Equal(t, "$2a$10$hash", *member.Password)
+	assert.Nil(t, member.Password_classic)
+
+	assert.Error(t, cdb.SetMemberPassword(9, "$2a$10$hash"))
+}
@@ -8,13 +8,23 @@ import (
 	"github.com/sebastiw/sidan-backend/src/models"
 )
 
+// nextMemberNumber is one past the highest member number; numbers of
+// removed members are not reused
+func nextMemberNumber(db *gorm.DB) (int64, error) {
+	var maxNumber int64
+	if err := db.Model(&models.Member{}).Select("COALESCE(MAX(number), 2)").Scan(&maxNumber).Error; err != nil {
+		return 0, err
+	}
+	return maxNumber + 1, nil
+}
+
 func (d *CommonDatabase) applyMemberDefaults(member *models.Member) error {
 	if member.Number == 0 {
-		var maxNumber int64
-		if err := d.DB.Model(&models.Member{}).Select("COALESCE(MAX(number), 2)").Scan(&maxNumber).Error; err != nil {
+		n, err := nextMemberNumber(d.DB)
+		if err != nil {
 			return err
 		}
-		member.Number = maxNumber + 1
+		member.Number = n
 	}
 	if member.Isvalid == nil {
 		t := true
@@ -69,6 +79,10 @@ func (d *CommonDatabase) ReadMemberByEmail(email string) (*models.Member, error)
 	return &member, nil
 }
 
+// passwordColumns are the cl2007_members columns holding passwords or reset
+// strings
+var passwordColumns = []string{"password", "password_classic", "password_resetstring", "password_classic_resetstring"}
+
 func (d *CommonDatabase) ReadMembers(onlyValid bool) ([]models.Member, error) {
 	var members []models.Member
 
@@ -80,14 +94,30 @@ func (d *CommonDatabase) ReadMembers(onlyValid bool) ([]models.Member, error) {
 	return members, nil
 }
 
+// UpdateMember writes the set fields of member. Passwords and reset strings
+// are never written here, they change through SetMemberPassword and the
+// reset flow.
 func (d *CommonDatabase) UpdateMember(member *models.Member) (*models.Member, error) {
-	result := d.DB.Model(member).Updates(member)
+	result := d.DB.Model(member).Omit(passwordColumns...).Updates(member)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return member, nil
 }
 
+// PatchMember updates only the columns set in patch, including clearing
+// them, and returns the updated member
+func (d *CommonDatabase) PatchMember(id int64, patch models.MemberPatch) (*models.Member, error) {
+	columns := patch.Columns()
+	if len(columns) > 0 {
+		result := d.DB.Model(&models.Member{Id: id}).Updates(columns)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+	}
+	return d.ReadMember(id)
+}
+
 func (d *CommonDatabase) DeleteMember(member *models.Member) (*models.Member, error) {
 	result := d.DB.Delete(&member)
 
@@ -96,3 +126,60 @@ func (d *CommonDatabase) DeleteMember(member *models.Member) (*models.Member, er
 	}
 	return member, nil
 }
+
+// SetMemberPassword stores a bcrypt hash as the member's password and clears
+// the classic password
+func (d *CommonDatabase) SetMemberPassword(number int64, hash string) error {
+	result := d.DB.Model(&models.Member{}).Where("number = ?", number).Updates(map[string]interface{}{
+		"password":         hash,
+		"password_classic": nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetPasswordResetString stores a pending password reset on the member
+func (d *CommonDatabase) SetPasswordResetString(number int64, reset string) error {
+	result := d.DB.Model(&models.Member{}).Where("number = ?", number).Update("password_resetstring", reset)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ResetMemberPassword sets a new bcrypt password if reset is still the
+// member's pending reset, which uses it up. All sessions of the member are revoked and
+// failed logins forgotten. Returns false if the reset was already used.
+func (d *CommonDatabase) ResetMemberPassword(number int64, reset string, hash string) (bool, error) {
+	done := false
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Member{}).
+			Where("number = ? AND password_resetstring = ?", number, reset).
+			Updates(map[string]interface{}{
+				"password":                     hash,
+				"password_classic":             nil,
+				"password_resetstring":         nil,
+				"password_classic_resetstring": nil,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if err := tx.Where("member_number = ?", number).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("member_number = ?", number).Delete(&models.LoginLockout{}).Error; err != nil {
+			return err
+		}
+		done = true
+		return nil
+	})
+	return done, err
+}
@@ -0,0 +1,20 @@
+package commondb
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ReadMemberEvents returns up to take events with an id above after, oldest
+// first, optionally only those of one member
+func (d *CommonDatabase) ReadMemberEvents(after int64, take int, memberNumber int64) ([]models.MemberEvent, error) {
+	var events = make([]models.MemberEvent, 0)
+	query := d.DB.Where("id > ?", after)
+	if memberNumber > 0 {
+		query = query.Where("member_number = ?", memberNumber)
+	}
+	result := query.Order("id ASC").Limit(take).Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return events, nil
+}
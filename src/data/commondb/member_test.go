@@ -0,0 +1,100 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestPatchMember(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.Member{})
+
+	str := func(s string) *string { return &s }
+	valid := true
+	member, err := cdb.CreateMember(&models.Member{
+		Number:  8,
+		Name:    str("Åtta"),
+		Phone:   str("031-123456"),
+		History: str("Gammal historia"),
+		Im:      "icq",
+		Isvalid: &valid,
+	})
+	assert.NoError(t, err)
+
+	// Only the given fields change, and empty strings clear
+	updated, err := cdb.PatchMember(member.Id, models.MemberPatch{
+		Phone: str("+46 70 123 45 67"),
+		Im:    str(""),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "+46 70 123 45 67", *updated.Phone)
+	assert.Equal(t, "", updated.Im)
+	assert.Equal(t, "Gammal historia", *updated.History)
+	assert.Equal(t, "Åtta", *updated.Name)
+	assert.Equal(t, int64(8), updated.Number)
+
+	// An empty patch is a read
+	same, err := cdb.PatchMember(member.Id, models.MemberPatch{})
+	assert.NoError(t, err)
+	assert.Equal(t, updated, same)
+}
+
+func TestUpdateMemberKeepsPasswords(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.Member{})
+
+	classic, reset := "hemligt", "123:abc"
+	member, err := cdb.CreateMember(&models.Member{Number: 8, Password_classic: &classic, Password_resetstring: &reset})
+	require.NoError(t, err)
+
+	name, other := "Åtta", "annat"
+	_, err = cdb.UpdateMember(&models.Member{Id: member.Id, Name: &name, Password: &other, Password_classic: &other, Password_resetstring: &other, Password_classic_resetstring: &other})
+	require.NoError(t, err)
+
+	stored, err := cdb.ReadMemberByNumber(8)
+	require.NoError(t, err)
+	assert.Equal(t, "Åtta", *stored.Name)
+	assert.Nil(t, stored.Password)
+	assert.Equal(t, "hemligt", *stored.Password_classic)
+	assert.Equal(t, "123:abc", *stored.Password_resetstring)
+	assert.Nil(t, stored.Password_classic_resetstring)
+}
+
+func TestResetMemberPassword(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.Member{}, &models.Session{}, &models.LoginLockout{})
+
+	classic := "hemligt"
+	_, err := cdb.CreateMember(&models.Member{Number: 8, Password_classic: &classic})
+	require.NoError(t, err)
+	require.NoError(t, cdb.CreateSession(&models.Session{Token: "s1", MemberNumber: 8, ExpiresAt: time.Now().Add(time.Hour)}))
+	require.NoError(t, cdb.CreateSession(&models.Session{Token: "s2", MemberNumber: 9, ExpiresAt: time.Now().Add(time.Hour)}))
+	_, err = cdb.RecordFailedLogin(8, time.Now(), 5, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, cdb.SetPasswordResetString(8, "123:abc"))
+
+	done, err := cdb.ResetMemberPassword(8, "123:abc", "$2a$10$hash")
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	member, err := cdb.ReadMemberByNumber(8)
+	require.NoError(t, err)
+	assert.Equal(t, "$2a$10$hash", *member.Password)
+	assert.Nil(t, member.Password_classic)
+	assert.Nil(t, member.Password_resetstring)
+	_, err = cdb.GetSession("s1")
+	assert.Error(t, err)
+	_, err = cdb.GetSession("s2")
+	assert.NoError(t, err)
+	lockout, err := cdb.ReadLoginLockout(8)
+	require.NoError(t, err)
+	assert.Nil(t, lockout)
+
+	// The reset can only be used once
+	done, err = cdb.ResetMemberPassword(8, "123:abc", "$2a$10$other")
+	require.NoError(t, err)
+	assert.False(t, done)
+}
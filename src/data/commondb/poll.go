@@ -0,0 +1,229 @@
+package commondb
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func orderPollOptions(db *gorm.DB) *gorm.DB {
+	return db.Order("vote ASC")
+}
+
+// CreatePoll stores a poll and its extra options
+func (d *CommonDatabase) CreatePoll(poll *models.Poll) (*models.Poll, error) {
+	now := time.Now()
+	if poll.Date == nil {
+		y, m, day := now.Date()
+		date := time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+		poll.Date = &date
+	}
+	if poll.Time == nil {
+		t := now.Format("15:04:05")
+		poll.Time = &t
+	}
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(poll).Error; err != nil {
+			return err
+		}
+		return createPollOptions(tx, poll)
+	})
+	if err != nil {
+		return nil, err
+	}
+	poll.SetResults(nil)
+	return poll, nil
+}
+
+// ReadPoll returns the poll with its results, or nil if there is none
+func (d *CommonDatabase) ReadPoll(id int64) (*models.Poll, error) {
+	var poll models.Poll
+	result := d.DB.Preload("Extra", orderPollOptions).First(&poll, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	polls := []models.Poll{poll}
+	if err := d.setPollResults(polls); err != nil {
+		return nil, err
+	}
+	return &polls[0], nil
+}
+
+// ReadPolls returns polls with their results, newest first
+func (d *CommonDatabase) ReadPolls(take int, skip int) ([]models.Poll, error) {
+	var polls = make([]models.Poll, 0)
+	result := d.DB.Preload("Extra", orderPollOptions).Order("ID DESC").Limit(take).Offset(skip).Find(&polls)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if err := d.setPollResults(polls); err != nil {
+		return nil, err
+	}
+	return polls, nil
+}
+
+// UpdatePoll changes the theme and options of a poll. The options cannot
+// change once someone has voted, then ErrPollHasVotes is returned. Returns
+// nil if the poll does not exist.
+func (d *CommonDatabase) UpdatePoll(poll *models.Poll) (*models.Poll, error) {
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		var stored models.Poll
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Extra", orderPollOptions).First(&stored, poll.Id).Error; err != nil {
+			return err
+		}
+
+		if !samePollOptions(stored.Options(), poll.Options()) {
+			var votes int64
+			if err := tx.Model(&models.PollVote{}).Where("poll_ID = ?", poll.Id).Count(&votes).Error; err != nil {
+				return err
+			}
+			if votes > 0 {
+				return models.ErrPollHasVotes
+			}
+			if err := tx.Where("poll_id = ?", poll.Id).Delete(&models.PollOption{}).Error; err != nil {
+				return err
+			}
+			if err := createPollOptions(tx, poll); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Poll{Id: poll.Id}).Select("theme", "yae", "nay").Updates(poll).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.ReadPoll(poll.Id)
+}
+
+// DeletePoll removes a poll with its options and votes. Returns false if it
+// did not exist.
+func (d *CommonDatabase) DeletePoll(id int64) (bool, error) {
+	var deleted bool
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("poll_ID = ?", id).Delete(&models.PollVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("poll_id = ?", id).Delete(&models.PollOption{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Poll{Id: id})
+		deleted = result.RowsAffected > 0
+		return result.Error
+	})
+	return deleted, err
+}
+
+// VotePoll records the vote of a member. Returns nil if the poll does not
+// exist, ErrPollOption if it has no such option and ErrPollVoted if the
+// member has already voted.
+func (d *CommonDatabase) VotePoll(id int64, memberNumber int64, vote int, at time.Time) (*models.Poll, error) {
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		var poll models.Poll
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Extra").First(&poll, id).Error; err != nil {
+			return err
+		}
+		if !poll.HasOption(vote) {
+			return models.ErrPollOption
+		}
+
+		var voted int64
+		if err := tx.Model(&models.PollVote{}).Where("poll_ID = ? AND member_number = ?", id, memberNumber).Count(&voted).Error; err != nil {
+			return err
+		}
+		if voted > 0 {
+			return models.ErrPollVoted
+		}
+		return tx.Create(&models.PollVote{PollId: id, MemberNumber: &memberNumber, Vote: vote, CreatedAt: &at}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.ReadPoll(id)
+}
+
+// ReadPollVote returns the vote of the member, or nil if they have not voted
+func (d *CommonDatabase) ReadPollVote(id int64, memberNumber int64) (*models.PollVote, error) {
+	var vote models.PollVote
+	result := d.DB.Where("poll_ID = ? AND member_number = ?", id, memberNumber).First(&vote)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &vote, nil
+}
+
+func createPollOptions(tx *gorm.DB, poll *models.Poll) error {
+	for i := range poll.Extra {
+		poll.Extra[i].PollId = poll.Id
+		poll.Extra[i].Vote = models.PollVoteExtra + i
+	}
+	if len(poll.Extra) == 0 {
+		return nil
+	}
+	return tx.Create(&poll.Extra).Error
+}
+
+func samePollOptions(a []models.PollOption, b []models.PollOption) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Text != b[i].Text {
+			return false
+		}
+	}
+	return true
+}
+
+// setPollResults counts the votes of the polls per option
+func (d *CommonDatabase) setPollResults(polls []models.Poll) error {
+	if len(polls) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(polls))
+	for i, p := range polls {
+		ids[i] = p.Id
+	}
+
+	var rows []struct {
+		PollId int64 `gorm:"column:poll_ID"`
+		Vote   int
+		Count  int64
+	}
+	result := d.DB.Model(&models.PollVote{}).
+		Select("poll_ID, vote, COUNT(*) AS count").
+		Where("poll_ID IN ?", ids).
+		Group("poll_ID, vote").
+		Scan(&rows)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	counts := make(map[int64]map[int]int64, len(polls))
+	for _, row := range rows {
+		if counts[row.PollId] == nil {
+			counts[row.PollId] = map[int]int64{}
+		}
+		counts[row.PollId][row.Vote] = row.Count
+	}
+	for i := range polls {
+		polls[i].SetResults(counts[polls[i].Id])
+	}
+	return nil
+}
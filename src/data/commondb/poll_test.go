@@ -0,0 +1,119 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb"
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func newPollDB(t *testing.T) *commondb.CommonDatabase {
+	return commondbtest.NewDB(t, &models.Poll{}, &models.PollOption{}, &models.PollVote{})
+}
+
+func TestPoll(t *testing.T) {
+	cdb := newPollDB(t)
+	by := int64(8)
+	poll, err := cdb.CreatePoll(&models.Poll{Theme: "Vilken öl?", Yae: "Lager", Nay: "Ale", CreatedBy: &by,
+		Extra: []models.PollOption{{Text: "Stout"}, {Text: "Porter"}}})
+	require.NoError(t, err)
+	require.NotNil(t, poll.Date)
+	require.NotNil(t, poll.Time)
+	assert.Equal(t, []int{2, 3}, []int{poll.Extra[0].Vote, poll.Extra[1].Vote})
+
+	now := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC)
+	_, err = cdb.VotePoll(poll.Id, 8, models.PollVoteYae, now)
+	require.NoError(t, err)
+	_, err = cdb.VotePoll(poll.Id, 3, 3, now)
+	require.NoError(t, err)
+	voted, err := cdb.VotePoll(poll.Id, 4, 3, now)
+	require.NoError(t, err)
+
+	// Old votes by host count too
+	require.NoError(t, cdb.DB.Create(&models.PollVote{PollId: poll.Id, Host: "lusburk.example.com", Vote: models.PollVoteNay}).Error)
+	require.NoError(t, cdb.DB.Create(&models.PollVote{PollId: poll.Id, Host: "other.example.com", Vote: models.PollVoteNay}).Error)
+
+	assert.Equal(t, int64(3), voted.Votes)
+	read, err := cdb.ReadPoll(poll.Id)
+	require.NoError(t, err)
+	assert.Equal(t, poll.Date.Format(time.DateOnly), read.Date.Format(time.DateOnly))
+	assert.Equal(t, int64(5), read.Votes)
+	assert.Equal(t, []models.PollResult{
+		{Vote: 1, Text: "Lager", Votes: 1},
+		{Vote: 0, Text: "Ale", Votes: 2},
+		{Vote: 2, Text: "Stout", Votes: 0},
+		{Vote: 3, Text: "Porter", Votes: 2},
+	}, read.Results)
+
+	// One vote per member, for an option the poll has
+	_, err = cdb.VotePoll(poll.Id, 8, models.PollVoteNay, now)
+	assert.ErrorIs(t, err, models.ErrPollVoted)
+	_, err = cdb.VotePoll(poll.Id, 9, 4, now)
+	assert.ErrorIs(t, err, models.ErrPollOption)
+	missing, err := cdb.VotePoll(999, 9, 1, now)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	vote, err := cdb.ReadPollVote(poll.Id, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, vote.Vote)
+	vote, err = cdb.ReadPollVote(poll.Id, 9)
+	require.NoError(t, err)
+	assert.Nil(t, vote)
+
+	// The theme can change after voting, the options cannot
+	read.Theme = "Vilken öl ikväll?"
+	updated, err := cdb.UpdatePoll(read)
+	require.NoError(t, err)
+	assert.Equal(t, "Vilken öl ikväll?", updated.Theme)
+	updated.Extra = updated.Extra[:1]
+	_, err = cdb.UpdatePoll(updated)
+	assert.ErrorIs(t, err, models.ErrPollHasVotes)
+
+	polls, err := cdb.ReadPolls(10, 0)
+	require.NoError(t, err)
+	require.Len(t, polls, 1)
+	assert.Len(t, polls[0].Results, 4)
+
+	deleted, err := cdb.DeletePoll(poll.Id)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	read, err = cdb.ReadPoll(poll.Id)
+	require.NoError(t, err)
+	assert.Nil(t, read)
+	var votes int64
+	require.NoError(t, cdb.DB.Model(&models.PollVote{}).Count(&votes).Error)
+	assert.Zero(t, votes)
+}
+
+func TestReadPoll_WithoutDate(t *testing.T) {
+	cdb := newPollDB(t)
+	require.NoError(t, cdb.DB.Create(&models.Poll{Id: 683, Theme: "Ringen?", Yae: "vänster", Nay: "höger"}).Error)
+
+	poll, err := cdb.ReadPoll(683)
+	require.NoError(t, err)
+	assert.Nil(t, poll.Date)
+	assert.Nil(t, poll.Time)
+}
+
+func TestUpdatePoll_Options(t *testing.T) {
+	cdb := newPollDB(t)
+	poll, err := cdb.CreatePoll(&models.Poll{Theme: "Supa?", Yae: "Ja", Nay: "Nej"})
+	require.NoError(t, err)
+
+	// Without votes the options can change
+	poll.Nay = "Kanske"
+	poll.Extra = []models.PollOption{{Text: "Nej"}}
+	updated, err := cdb.UpdatePoll(poll)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Ja", "Kanske", "Nej"}, []string{updated.Results[0].Text, updated.Results[1].Text, updated.Results[2].Text})
+
+	missing, err := cdb.UpdatePoll(&models.Poll{Id: 999, Theme: "x", Yae: "a", Nay: "b"})
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -1,6 +1,12 @@
 package commondb
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
 	"github.com/sebastiw/sidan-backend/src/models"
 )
 
@@ -71,3 +77,109 @@ func (d *CommonDatabase) DeleteProspect(prospect *models.Prospect) (*models.Pros
 	}
 	return prospect, nil
 }
+
+// promoteAttempts is how many times PromoteProspect looks for the next free
+// member number
+const promoteAttempts = 3
+
+// PromoteProspect makes a prospect a full member in one transaction. The
+// member gets number, or the next free member number if number is 0, and a
+// copy of the prospect's name, email, phone and history. The prospect is kept
+// with status StatusPromoted, and the member_created and prospect_promoted
+// events are stored with by as creator. Sessions and identities left on a
+// reused number are removed so that they cannot log in as the new member.
+// Returns ErrMemberNumberTaken also if a chosen number is taken
+// concurrently, and nil if the prospect does not exist. A next free number
+// taken concurrently is retried with the one after.
+func (d *CommonDatabase) PromoteProspect(id int64, number int64, by int64) (*models.Member, *models.Prospect, error) {
+	for attempt := 1; ; attempt++ {
+		member, prospect, err := d.promoteProspect(id, number, by)
+		if number != 0 || !errors.Is(err, models.ErrMemberNumberTaken) {
+			return member, prospect, err
+		}
+		if attempt == promoteAttempts {
+			return nil, nil, fmt.Errorf("no free member number after %d attempts", attempt)
+		}
+	}
+}
+
+func (d *CommonDatabase) promoteProspect(id int64, number int64, by int64) (*models.Member, *models.Prospect, error) {
+	var member *models.Member
+	var prospect models.Prospect
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&prospect, id).Error; err != nil {
+			return err
+		}
+		if prospect.Status != models.StatusProspect {
+			return models.ErrNotProspect
+		}
+
+		if number == 0 {
+			n, err := nextMemberNumber(tx)
+			if err != nil {
+				return err
+			}
+			number = n
+		} else {
+			var taken int64
+			if err := tx.Model(&models.Member{}).Where("number = ?", number).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				return models.ErrMemberNumberTaken
+			}
+		}
+
+		valid := true
+		member = &models.Member{
+			Number:  number,
+			Name:    nonEmpty(prospect.Name),
+			Email:   nonEmpty(prospect.Email),
+			Phone:   nonEmpty(prospect.Phone),
+			History: nonEmpty(prospect.History),
+			Isvalid: &valid,
+		}
+		if err := tx.Create(member).Error; err != nil {
+			if isDuplicateKey(tx, err) {
+				return models.ErrMemberNumberTaken
+			}
+			return err
+		}
+
+		if err := tx.Where("member_number = ?", number).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("member_number = ?", number).Delete(&models.Identity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("member_number = ?", number).Delete(&models.FeedSecret{}).Error; err != nil {
+			return err
+		}
+
+		prospect.Status = models.StatusPromoted
+		if err := tx.Model(&prospect).Update("status", prospect.Status).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		events := []models.MemberEvent{
+			{Event: models.EventMemberCreated, MemberNumber: number, ProspectId: &prospect.Id, CreatedBy: &by, CreatedAt: now},
+			{Event: models.EventProspectPromoted, MemberNumber: number, ProspectId: &prospect.Id, CreatedBy: &by, CreatedAt: now},
+		}
+		return tx.Create(&events).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	return member, &prospect, nil
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,157 @@
+package commondb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb"
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func newPromotionDB(t *testing.T) *commondb.CommonDatabase {
+	return commondbtest.NewDB(t, &models.Member{}, &models.Prospect{}, &models.Session{}, &models.Identity{}, &models.FeedSecret{}, &models.MemberEvent{})
+}
+
+func TestPromoteProspect(t *testing.T) {
+	cdb := newPromotionDB(t)
+	_, err := cdb.CreateMember(&models.Member{Number: 41})
+	require.NoError(t, err)
+	prospect, err := cdb.CreateProspect(&models.Prospect{
+		Status:  models.StatusProspect,
+		Name:    "Nykomling",
+		Email:   "ny@example.com",
+		Phone:   "0701234567",
+		History: "Kom via suput",
+	})
+	require.NoError(t, err)
+
+	member, promoted, err := cdb.PromoteProspect(prospect.Id, 0, 8)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), member.Number)
+	assert.Equal(t, "Nykomling", *member.Name)
+	assert.Equal(t, "ny@example.com", *member.Email)
+	assert.Equal(t, "0701234567", *member.Phone)
+	assert.Equal(t, "Kom via suput", *member.History)
+	assert.True(t, *member.Isvalid)
+	assert.Equal(t, models.StatusPromoted, promoted.Status)
+
+	stored, err := cdb.ReadProspect(prospect.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPromoted, stored.Status)
+
+	events, err := cdb.ReadMemberEvents(0, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventMemberCreated, events[0].Event)
+	assert.Equal(t, models.EventProspectPromoted, events[1].Event)
+	for _, e := range events {
+		assert.Equal(t, int64(42), e.MemberNumber)
+		assert.Equal(t, prospect.Id, *e.ProspectId)
+		assert.Equal(t, int64(8), *e.CreatedBy)
+	}
+	events, err = cdb.ReadMemberEvents(events[0].Id, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	events, err = cdb.ReadMemberEvents(0, 10, 41)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// A promoted prospect cannot be promoted again
+	_, _, err = cdb.PromoteProspect(prospect.Id, 0, 8)
+	assert.ErrorIs(t, err, models.ErrNotProspect)
+
+	member, promoted, err = cdb.PromoteProspect(9999, 0, 8)
+	assert.NoError(t, err)
+	assert.Nil(t, member)
+	assert.Nil(t, promoted)
+}
+
+func TestPromoteProspect_ChosenNumber(t *testing.T) {
+	cdb := newPromotionDB(t)
+	_, err := cdb.CreateMember(&models.Member{Number: 7})
+	require.NoError(t, err)
+	prospect, err := cdb.CreateProspect(&models.Prospect{Status: models.StatusProspect, Name: "Vald"})
+	require.NoError(t, err)
+
+	// A taken number rolls back and leaves the prospect as it was
+	_, _, err = cdb.PromoteProspect(prospect.Id, 7, 8)
+	assert.ErrorIs(t, err, models.ErrMemberNumberTaken)
+	stored, err := cdb.ReadProspect(prospect.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusProspect, stored.Status)
+
+	// Leftovers of a former member with the number are removed
+	require.NoError(t, cdb.CreateSession(&models.Session{Token: "old", MemberNumber: 3}))
+	_, err = cdb.CreateIdentity(&models.Identity{MemberNumber: 3, Provider: "github", ProviderUserID: "1"})
+	require.NoError(t, err)
+	require.NoError(t, cdb.UpdateFeedSecret(&models.FeedSecret{MemberNumber: 3, Feed: "arr.ics", Secret: "old"}))
+
+	member, _, err := cdb.PromoteProspect(prospect.Id, 3, 8)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), member.Number)
+	assert.Nil(t, member.Email)
+	_, err = cdb.GetSession("old")
+	assert.Error(t, err)
+	identities, err := cdb.ReadIdentities(3)
+	require.NoError(t, err)
+	assert.Empty(t, identities)
+	secret, err := cdb.ReadFeedSecret(3, "arr.ics")
+	require.NoError(t, err)
+	assert.Nil(t, secret)
+}
+
+func TestPromoteProspect_NumberTakenConcurrently(t *testing.T) {
+	cdb := newPromotionDB(t)
+	require.NoError(t, cdb.DB.Exec("CREATE UNIQUE INDEX uq_member_number ON cl2007_members (number)").Error)
+	prospect, err := cdb.CreateProspect(&models.Prospect{Status: models.StatusProspect, Name: "Sen"})
+	require.NoError(t, err)
+
+	// Another promotion takes the number between the check and the insert
+	require.NoError(t, cdb.DB.Callback().Create().Before("gorm:create").Register("race", func(tx *gorm.DB) {
+		if tx.Statement.Table == "cl2007_members" {
+			tx.Session(&gorm.Session{NewDB: true}).Exec("INSERT INTO cl2007_members (number) VALUES (5)")
+		}
+	}))
+
+	_, _, err = cdb.PromoteProspect(prospect.Id, 5, 8)
+	assert.ErrorIs(t, err, models.ErrMemberNumberTaken)
+	stored, err := cdb.ReadProspect(prospect.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusProspect, stored.Status)
+}
+
+func TestPromoteProspect_NextNumberTakenConcurrently(t *testing.T) {
+	cdb := newPromotionDB(t)
+	require.NoError(t, cdb.DB.Exec("CREATE UNIQUE INDEX uq_member_number ON cl2007_members (number)").Error)
+	prospect, err := cdb.CreateProspect(&models.Prospect{Status: models.StatusProspect, Name: "Sen"})
+	require.NoError(t, err)
+
+	// Other promotions take the next number between the lookup and the
+	// insert, the first races times
+	races := 1
+	require.NoError(t, cdb.DB.Callback().Create().Before("gorm:create").Register("race", func(tx *gorm.DB) {
+		if m, ok := tx.Statement.Dest.(*models.Member); ok && races > 0 {
+			races--
+			tx.Session(&gorm.Session{NewDB: true}).Exec("INSERT INTO cl2007_members (number) VALUES (?)", m.Number)
+		}
+	}))
+
+	member, _, err := cdb.PromoteProspect(prospect.Id, 0, 8)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), member.Number)
+
+	// Giving up is not the client's fault
+	prospect, err = cdb.CreateProspect(&models.Prospect{Status: models.StatusProspect, Name: "Senare"})
+	require.NoError(t, err)
+	races = 10
+	_, _, err = cdb.PromoteProspect(prospect.Id, 0, 8)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, models.ErrMemberNumberTaken)
+	stored, err := cdb.ReadProspect(prospect.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusProspect, stored.Status)
+}
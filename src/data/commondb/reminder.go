@@ -0,0 +1,53 @@
+package commondb
+
+import (
+	"gorm.io/gorm/clause"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ReadArrReminders returns the reminders sent of the arr
+func (d *CommonDatabase) ReadArrReminders(arrId int64) ([]models.ArrReminder, error) {
+	var reminders []models.ArrReminder
+	result := d.DB.Where("arr_id = ?", arrId).Find(&reminders)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return reminders, nil
+}
+
+// ClaimArrReminder records the reminder as sent. Returns false if it already
+// was, so that only one caller sends it.
+func (d *CommonDatabase) ClaimArrReminder(reminder *models.ArrReminder) (bool, error) {
+	result := d.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteArrReminder forgets a claimed reminder, e.g. when it could not be
+// sent, so that it is tried again
+func (d *CommonDatabase) DeleteArrReminder(reminder *models.ArrReminder) error {
+	return d.DB.Where("arr_id = ? AND member_number = ? AND offset_minutes = ?",
+		reminder.ArrId, reminder.MemberNumber, reminder.Offset).Delete(&models.ArrReminder{}).Error
+}
+
+// ReadReminderOptOut tells whether the member has opted out of reminders
+func (d *CommonDatabase) ReadReminderOptOut(memberNumber int64) (bool, error) {
+	var count int64
+	result := d.DB.Model(&models.ReminderOptOut{}).Where("member_number = ?", memberNumber).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// SetReminderOptOut opts the member out of reminders, or back in
+func (d *CommonDatabase) SetReminderOptOut(memberNumber int64, optOut bool) error {
+	if !optOut {
+		return d.DB.Where("member_number = ?", memberNumber).Delete(&models.ReminderOptOut{}).Error
+	}
+	optout := models.ReminderOptOut{MemberNumber: memberNumber}
+	return d.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&optout).Error
+}
@@ -0,0 +1,62 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestArrReminders(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.ArrReminder{}, &models.ReminderOptOut{})
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	reminder := models.ArrReminder{ArrId: 1, MemberNumber: 8, Offset: 120, SentAt: now}
+	claimed, err := cdb.ClaimArrReminder(&reminder)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// Only the first claim sends
+	again := reminder
+	claimed, err = cdb.ClaimArrReminder(&again)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	other := models.ArrReminder{ArrId: 1, MemberNumber: 8, Offset: 1440, SentAt: now}
+	claimed, err = cdb.ClaimArrReminder(&other)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	reminders, err := cdb.ReadArrReminders(1)
+	require.NoError(t, err)
+	assert.Len(t, reminders, 2)
+
+	require.NoError(t, cdb.DeleteArrReminder(&reminder))
+	reminders, err = cdb.ReadArrReminders(1)
+	require.NoError(t, err)
+	require.Len(t, reminders, 1)
+	assert.Equal(t, 1440, reminders[0].Offset)
+}
+
+func TestReminderOptOut(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.ReminderOptOut{})
+
+	optOut, err := cdb.ReadReminderOptOut(8)
+	require.NoError(t, err)
+	assert.False(t, optOut)
+
+	require.NoError(t, cdb.SetReminderOptOut(8, true))
+	require.NoError(t, cdb.SetReminderOptOut(8, true))
+	optOut, err = cdb.ReadReminderOptOut(8)
+	require.NoError(t, err)
+	assert.True(t, optOut)
+
+	require.NoError(t, cdb.SetReminderOptOut(8, false))
+	optOut, err = cdb.ReadReminderOptOut(8)
+	require.NoError(t, err)
+	assert.False(t, optOut)
+}
@@ -0,0 +1,198 @@
+package commondb
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	rsql "github.com/sebastiw/go-rsql-mysql"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ValidateEntryFilter checks an RSQL filter and sort with the same parser
+// configuration and allowed keys as ReadEntries. Mistakes of the client are
+// returned as *models.InvalidFilterError.
+func ValidateEntryFilter(filter string, sort string) error {
+	parser, err := newEntryParser()
+	if err != nil {
+		return fmt.Errorf("RSQL parser creation failed: %w", err)
+	}
+	if filter == "" && sort == "" {
+		return &models.InvalidFilterError{Err: errors.New("filter query or sort required")}
+	}
+	if filter != "" {
+		if _, err := parser.Process(filter, rsql.SetAllowedKeys(entryAllowedKeys)); err != nil {
+			return &models.InvalidFilterError{Err: fmt.Errorf("RSQL parse error: %w", err)}
+		}
+	}
+	if _, _, err := parseEntrySort(sort); err != nil {
+		return &models.InvalidFilterError{Err: err}
+	}
+	return nil
+}
+
+// validateSavedFilter checks what CreateSavedFilter and UpdateSavedFilter
+// store
+func validateSavedFilter(filter *models.SavedFilter) error {
+	if filter.Name == "" {
+		return &models.InvalidFilterError{Err: errors.New("filter name required")}
+	}
+	return ValidateEntryFilter(filter.Query, filter.Sort)
+}
+
+func computeSavedFilterFields(filter *models.SavedFilter) {
+	filter.SharedWith = make([]int64, len(filter.Shares))
+	for i, s := range filter.Shares {
+		filter.SharedWith[i] = s.MemberNumber
+	}
+}
+
+func sharesFor(filterId int64, numbers []int64) []models.SavedFilterShare {
+	seen := make(map[int64]bool, len(numbers))
+	shares := make([]models.SavedFilterShare, 0, len(numbers))
+	for _, n := range numbers {
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		shares = append(shares, models.SavedFilterShare{FilterId: filterId, MemberNumber: n})
+	}
+	return shares
+}
+
+func (d *CommonDatabase) CreateSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	if err := validateSavedFilter(filter); err != nil {
+		return nil, err
+	}
+
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Shares").Create(filter).Error; err != nil {
+			if isDuplicateKey(tx, err) {
+				return models.ErrFilterNameTaken
+			}
+			return err
+		}
+		filter.Shares = sharesFor(filter.Id, filter.SharedWith)
+		if len(filter.Shares) > 0 {
+			return tx.Create(&filter.Shares).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	computeSavedFilterFields(filter)
+	return filter, nil
+}
+
+func (d *CommonDatabase) ReadSavedFilter(id int64) (*models.SavedFilter, error) {
+	var filter models.SavedFilter
+	result := d.DB.Preload("Shares").First(&filter, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	computeSavedFilterFields(&filter)
+	return &filter, nil
+}
+
+// ReadSavedFilterByName returns the member's own filter with the name, or
+// else the oldest filter with the name shared with the member
+func (d *CommonDatabase) ReadSavedFilterByName(memberNumber int64, name string) (*models.SavedFilter, error) {
+	var filter models.SavedFilter
+	result := d.DB.Preload("Shares").
+		Where("member_number = ? AND name = ?", memberNumber, name).
+		First(&filter)
+	if result.Error == nil {
+		computeSavedFilterFields(&filter)
+		return &filter, nil
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+
+	result = d.DB.Preload("Shares").
+		Where("name = ?", name).
+		Where("id IN (?)", d.DB.Model(&models.SavedFilterShare{}).
+			Select("filter_id").
+			Where("member_number IN ?", []int64{0, memberNumber})).
+		Order("id ASC").
+		First(&filter)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	computeSavedFilterFields(&filter)
+	return &filter, nil
+}
+
+// ReadSavedFilters returns the member's own filters and those shared with them
+func (d *CommonDatabase) ReadSavedFilters(memberNumber int64) ([]models.SavedFilter, error) {
+	var filters []models.SavedFilter
+	result := d.DB.Preload("Shares").
+		Where("member_number = ?", memberNumber).
+		Or("id IN (?)", d.DB.Model(&models.SavedFilterShare{}).
+			Select("filter_id").
+			Where("member_number IN ?", []int64{0, memberNumber})).
+		Order("name ASC, id ASC").
+		Find(&filters)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	for i := range filters {
+		computeSavedFilterFields(&filters[i])
+	}
+	return filters, nil
+}
+
+// UpdateSavedFilter replaces name, query, sort and shares of a filter
+func (d *CommonDatabase) UpdateSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	if err := validateSavedFilter(filter); err != nil {
+		return nil, err
+	}
+
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(filter).
+			Select("name", "query", "sort").
+			Updates(filter).Error
+		if err != nil {
+			if isDuplicateKey(tx, err) {
+				return models.ErrFilterNameTaken
+			}
+			return err
+		}
+		if err := tx.Where("filter_id = ?", filter.Id).Delete(&models.SavedFilterShare{}).Error; err != nil {
+			return err
+		}
+		filter.Shares = sharesFor(filter.Id, filter.SharedWith)
+		if len(filter.Shares) > 0 {
+			return tx.Create(&filter.Shares).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	computeSavedFilterFields(filter)
+	return filter, nil
+}
+
+func (d *CommonDatabase) DeleteSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("filter_id = ?", filter.Id).Delete(&models.SavedFilterShare{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(filter).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
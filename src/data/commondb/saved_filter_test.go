@@ -0,0 +1,111 @@
+package commondb_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestSavedFilters(t *testing.T) {
+	repo := commondbtest.NewDB(t, &models.SavedFilter{}, &models.SavedFilterShare{})
+
+	t.Run("invalid RSQL is rejected", func(t *testing.T) {
+		_, err := repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 8, Name: "bad", Query: `email=="x"`})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not allowed")
+		var invalid *models.InvalidFilterError
+		assert.True(t, errors.As(err, &invalid))
+
+		_, err = repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 8, Name: "bad", Query: "likes=gt=1", Sort: "-email"})
+		assert.True(t, errors.As(err, &invalid))
+
+		_, err = repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 8, Query: "likes=gt=1"})
+		assert.True(t, errors.As(err, &invalid))
+	})
+
+	own, err := repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 8, Name: "popular", Query: "likes=gt=5", Sort: "-likes"})
+	assert.NoError(t, err)
+	shared, err := repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 3, Name: "popular", Query: "likes=gt=10", SharedWith: []int64{8, 8, 27}})
+	assert.NoError(t, err)
+	mine, err := repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 3, Name: "mine", Query: `sig=="#3"`})
+	assert.NoError(t, err)
+	public, err := repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 10, Name: "beer", Query: `msg=="öl"`, SharedWith: []int64{0}})
+	assert.NoError(t, err)
+
+	t.Run("shares are deduplicated", func(t *testing.T) {
+		f, err := repo.ReadSavedFilter(shared.Id)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []int64{8, 27}, f.SharedWith)
+		assert.True(t, f.VisibleTo(27))
+		assert.False(t, f.VisibleTo(10))
+	})
+
+	t.Run("own filter wins over shared with the same name", func(t *testing.T) {
+		f, err := repo.ReadSavedFilterByName(8, "popular")
+		assert.NoError(t, err)
+		assert.Equal(t, own.Id, f.Id)
+
+		f, err = repo.ReadSavedFilterByName(27, "popular")
+		assert.NoError(t, err)
+		assert.Equal(t, shared.Id, f.Id)
+	})
+
+	t.Run("filters shared with everyone are found", func(t *testing.T) {
+		f, err := repo.ReadSavedFilterByName(99, "beer")
+		assert.NoError(t, err)
+		assert.Equal(t, public.Id, f.Id)
+	})
+
+	t.Run("unshared filters are not found", func(t *testing.T) {
+		f, err := repo.ReadSavedFilterByName(8, "mine")
+		assert.NoError(t, err)
+		assert.Nil(t, f)
+	})
+
+	t.Run("list includes own and shared", func(t *testing.T) {
+		filters, err := repo.ReadSavedFilters(8)
+		assert.NoError(t, err)
+		assert.Len(t, filters, 3)
+	})
+
+	t.Run("names are unique per member", func(t *testing.T) {
+		_, err := repo.CreateSavedFilter(&models.SavedFilter{MemberNumber: 8, Name: "popular", Query: "likes=gt=1"})
+		assert.ErrorIs(t, err, models.ErrFilterNameTaken)
+
+		renamed := *mine
+		renamed.Name = "popular"
+		_, err = repo.UpdateSavedFilter(&renamed)
+		assert.ErrorIs(t, err, models.ErrFilterNameTaken)
+		f, err := repo.ReadSavedFilter(mine.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "mine", f.Name)
+	})
+
+	t.Run("update replaces shares", func(t *testing.T) {
+		shared.SharedWith = []int64{27}
+		_, err := repo.UpdateSavedFilter(shared)
+		assert.NoError(t, err)
+
+		f, err := repo.ReadSavedFilterByName(8, "popular")
+		assert.NoError(t, err)
+		assert.Equal(t, own.Id, f.Id)
+		filters, err := repo.ReadSavedFilters(8)
+		assert.NoError(t, err)
+		assert.Len(t, filters, 2)
+	})
+
+	t.Run("delete removes filter and shares", func(t *testing.T) {
+		_, err := repo.DeleteSavedFilter(public)
+		assert.NoError(t, err)
+		f, err := repo.ReadSavedFilter(public.Id)
+		assert.NoError(t, err)
+		assert.Nil(t, f)
+		var count int64
+		repo.DB.Model(&models.SavedFilterShare{}).Where("filter_id = ?", public.Id).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
@@ -0,0 +1,149 @@
+package commondb
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *CommonDatabase) CreateSuplogg(suplogg *models.Suplogg) (*models.Suplogg, error) {
+	if err := d.DB.Omit(clause.Associations).Create(suplogg).Error; err != nil {
+		return nil, err
+	}
+	suplogg.Deltagare = []models.SuploggDeltagare{}
+	return suplogg, nil
+}
+
+// ReadSuplogg returns the suplogg of an arr with its participants, most
+// enheter first, or nil if the arr has none
+func (d *CommonDatabase) ReadSuplogg(id int64) (*models.Suplogg, error) {
+	var suplogg models.Suplogg
+	result := d.DB.Preload("Deltagare", orderDeltagare).First(&suplogg, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &suplogg, nil
+}
+
+func (d *CommonDatabase) ReadSuploggs(take int, skip int) ([]models.Suplogg, error) {
+	var suploggs = make([]models.Suplogg, 0)
+	result := d.DB.Preload("Deltagare", orderDeltagare).Order("ID DESC").Limit(take).Offset(skip).Find(&suploggs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return suploggs, nil
+}
+
+// UpdateSuplogg renames a suplogg. Returns nil if it does not exist.
+func (d *CommonDatabase) UpdateSuplogg(suplogg *models.Suplogg) (*models.Suplogg, error) {
+	result := d.DB.Model(&models.Suplogg{Id: suplogg.Id}).Update("Name", suplogg.Name)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return d.ReadSuplogg(suplogg.Id)
+}
+
+// DeleteSuplogg removes a suplogg and its participants. Returns false if it
+// did not exist.
+func (d *CommonDatabase) DeleteSuplogg(id int64) (bool, error) {
+	var deleted bool
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("Arr = ?", id).Delete(&models.SuploggDeltagare{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Suplogg{Id: id})
+		deleted = result.RowsAffected > 0
+		return result.Error
+	})
+	return deleted, err
+}
+
+// AddSuploggEnheter adds count enheter to a participant, who joins the
+// suplogg on the first call. A negative count corrects a miscount; enheter
+// never go below zero. Returns nil if the suplogg does not exist and
+// ErrSuploggClosed once it is closed.
+func (d *CommonDatabase) AddSuploggEnheter(id int64, name string, count int64) (*models.SuploggDeltagare, error) {
+	var deltagare *models.SuploggDeltagare
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		var suplogg models.Suplogg
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&suplogg, id).Error; err != nil {
+			return err
+		}
+		if suplogg.ClosedAt != nil {
+			return models.ErrSuploggClosed
+		}
+
+		row := models.SuploggDeltagare{Arr: id, Name: name}
+		if err := tx.Where(&row).Limit(1).Find(&row).Error; err != nil {
+			return err
+		}
+		row.Enheter = max(row.Enheter+count, 0)
+		row.UpdatedAt = time.Now()
+		if err := tx.Save(&row).Error; err != nil {
+			return err
+		}
+		deltagare = &row
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return deltagare, nil
+}
+
+// CloseSuplogg stops the suplogg from counting. Returns nil if it does not
+// exist and ErrSuploggClosed if it already is closed.
+func (d *CommonDatabase) CloseSuplogg(id int64, at time.Time) (*models.Suplogg, error) {
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		var suplogg models.Suplogg
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&suplogg, id).Error; err != nil {
+			return err
+		}
+		if suplogg.ClosedAt != nil {
+			return models.ErrSuploggClosed
+		}
+		return tx.Model(&suplogg).Update("closed_at", at).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return d.ReadSuplogg(id)
+}
+
+// SetSuploggEntry links the kommentar written with the final tally
+func (d *CommonDatabase) SetSuploggEntry(id int64, entryId int64) error {
+	return d.DB.Model(&models.Suplogg{Id: id}).Update("entry_id", entryId).Error
+}
+
+// ReadSuploggLeaderboard sums the enheter of every participant over all
+// suploggs, most enheter first
+func (d *CommonDatabase) ReadSuploggLeaderboard(take int) ([]models.SuploggResult, error) {
+	var results = make([]models.SuploggResult, 0)
+	result := d.DB.Model(&models.SuploggDeltagare{}).
+		Select("Name AS name, SUM(Enheter) AS enheter, COUNT(*) AS arr").
+		Group("Name").
+		Order("enheter DESC, name ASC").
+		Limit(take).
+		Scan(&results)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return models.RankSuplogg(results), nil
+}
+
+func orderDeltagare(db *gorm.DB) *gorm.DB {
+	return db.Order("Enheter DESC, Name ASC")
+}
@@ -0,0 +1,93 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb"
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func newSuploggDB(t *testing.T) *commondb.CommonDatabase {
+	return commondbtest.NewDB(t, &models.Suplogg{}, &models.SuploggDeltagare{})
+}
+
+func TestSuplogg(t *testing.T) {
+	cdb := newSuploggDB(t)
+	_, err := cdb.CreateSuplogg(&models.Suplogg{Id: 7, Name: "Kräftskiva", StartedBy: 8})
+	require.NoError(t, err)
+
+	for _, add := range []struct {
+		name  string
+		count int64
+	}{{"#8", 1}, {"#8", 1}, {"#3", 4}, {"GuiGui", 2}, {"#8", 1}} {
+		_, err := cdb.AddSuploggEnheter(7, add.name, add.count)
+		require.NoError(t, err)
+	}
+	corrected, err := cdb.AddSuploggEnheter(7, "GuiGui", -5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), corrected.Enheter)
+
+	suplogg, err := cdb.ReadSuplogg(7)
+	require.NoError(t, err)
+	require.Len(t, suplogg.Deltagare, 3)
+	assert.Equal(t, "#3", suplogg.Deltagare[0].Name)
+	assert.Equal(t, int64(3), suplogg.Deltagare[1].Enheter)
+	assert.Equal(t, int64(7), suplogg.Total())
+
+	missing, err := cdb.AddSuploggEnheter(99, "#8", 1)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	closed, err := cdb.CloseSuplogg(7, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, closed.ClosedAt)
+	_, err = cdb.AddSuploggEnheter(7, "#8", 1)
+	assert.ErrorIs(t, err, models.ErrSuploggClosed)
+	_, err = cdb.CloseSuplogg(7, time.Now())
+	assert.ErrorIs(t, err, models.ErrSuploggClosed)
+
+	require.NoError(t, cdb.SetSuploggEntry(7, 42))
+	suplogg, err = cdb.ReadSuplogg(7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), *suplogg.EntryId)
+}
+
+func TestReadSuploggLeaderboard(t *testing.T) {
+	cdb := newSuploggDB(t)
+	for _, id := range []int64{1, 2} {
+		_, err := cdb.CreateSuplogg(&models.Suplogg{Id: id, Name: "Arr"})
+		require.NoError(t, err)
+	}
+	for _, add := range []struct {
+		id    int64
+		name  string
+		count int64
+	}{{1, "#8", 3}, {2, "#8", 2}, {1, "#3", 5}, {2, "#9", 1}} {
+		_, err := cdb.AddSuploggEnheter(add.id, add.name, add.count)
+		require.NoError(t, err)
+	}
+
+	results, err := cdb.ReadSuploggLeaderboard(10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.SuploggResult{
+		{Rank: 1, Name: "#3", Enheter: 5, Arr: 1},
+		{Rank: 1, Name: "#8", Enheter: 5, Arr: 2},
+		{Rank: 3, Name: "#9", Enheter: 1, Arr: 1},
+	}, results)
+
+	deleted, err := cdb.DeleteSuplogg(1)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = cdb.DeleteSuplogg(1)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	results, err = cdb.ReadSuploggLeaderboard(10)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+}
@@ -0,0 +1,75 @@
+package commondb
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// VisitBatchSize is the number of rows per INSERT when writing visits
+var VisitBatchSize = 100
+
+func (d *CommonDatabase) CreateVisits(visits []models.Visit) error {
+	if len(visits) == 0 {
+		return nil
+	}
+	return d.DB.CreateInBatches(visits, VisitBatchSize).Error
+}
+
+// DeleteVisitsBefore removes visits older than t and returns how many
+func (d *CommonDatabase) DeleteVisitsBefore(t time.Time) (int64, error) {
+	result := d.DB.Where("ts < ?", t).Delete(&models.Visit{})
+	return result.RowsAffected, result.Error
+}
+
+// ReadDailyVisitors counts unique visitors per day for from <= ts < to. A
+// visitor is the member signature, or the IP when anonymous.
+func (d *CommonDatabase) ReadDailyVisitors(from time.Time, to time.Time) ([]models.DailyVisitors, error) {
+	var days []models.DailyVisitors
+	result := d.DB.Model(&models.Visit{}).
+		Select("CAST(date AS CHAR) AS date, COUNT(DISTINCT COALESCE(sig, ip)) AS `unique`, COUNT(*) AS requests").
+		Where("ts >= ? AND ts < ?", from, to).
+		Group("date").
+		Order("date ASC").
+		Scan(&days)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return days, nil
+}
+
+func (d *CommonDatabase) ReadTopUserAgents(from time.Time, to time.Time, take int) ([]models.UserAgentCount, error) {
+	var agents []models.UserAgentCount
+	result := d.DB.Model(&models.Visit{}).
+		Select("ua AS user_agent, COUNT(*) AS requests").
+		Where("ts >= ? AND ts < ? AND ua IS NOT NULL AND ua <> ''", from, to).
+		Group("ua").
+		Order("requests DESC, user_agent ASC").
+		Limit(take).
+		Scan(&agents)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return agents, nil
+}
+
+// ReadEndpointUsage counts requests per member and endpoint. If sig is given
+// only that member is counted.
+func (d *CommonDatabase) ReadEndpointUsage(from time.Time, to time.Time, sig string, take int) ([]models.EndpointUsage, error) {
+	var usage []models.EndpointUsage
+	query := d.DB.Model(&models.Visit{}).
+		Select("sig, comment AS endpoint, COUNT(*) AS requests").
+		Where("ts >= ? AND ts < ? AND sig IS NOT NULL", from, to)
+	if sig != "" {
+		query = query.Where("sig = ?", sig)
+	}
+	result := query.
+		Group("sig, comment").
+		Order("requests DESC, sig ASC, endpoint ASC").
+		Limit(take).
+		Scan(&usage)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return usage, nil
+}
@@ -0,0 +1,79 @@
+package commondb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestVisits(t *testing.T) {
+	cdb := commondbtest.NewDB(t, &models.Visit{})
+
+	str := func(s string) *string { return &s }
+	day1 := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	visit := func(ts time.Time, ip string, sig *string, ua string, endpoint string) models.Visit {
+		return models.Visit{
+			Date:    ts.Format("2006-01-02"),
+			Time:    ts.Format("15:04:05"),
+			Ts:      ts,
+			Ip:      str(ip),
+			Sig:     sig,
+			Ua:      str(ua),
+			Comment: endpoint,
+		}
+	}
+
+	err := cdb.CreateVisits([]models.Visit{
+		visit(day1, "192.0.2.0", nil, "curl", "GET /db/entries"),
+		visit(day1, "192.0.2.0", nil, "curl", "GET /db/entries"),
+		visit(day1, "192.0.2.0", str("#8"), "app", "GET /db/entries"),
+		visit(day2, "198.51.100.0", str("#8"), "app", "POST /db/entries"),
+		visit(day2, "198.51.100.0", str("#8"), "app", "GET /db/entries"),
+		visit(day2, "198.51.100.0", str("#3"), "app", "GET /db/entries"),
+		visit(day1.AddDate(0, -6, 0), "203.0.113.0", nil, "old", "GET /db/arr"),
+	})
+	assert.NoError(t, err)
+
+	from := day1.Truncate(24 * time.Hour)
+	to := from.AddDate(0, 0, 2)
+
+	t.Run("DailyVisitors", func(t *testing.T) {
+		days, err := cdb.ReadDailyVisitors(from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.DailyVisitors{
+			{Date: "2026-10-17", Unique: 2, Requests: 3},
+			{Date: "2026-10-18", Unique: 2, Requests: 3},
+		}, days)
+	})
+
+	t.Run("TopUserAgents", func(t *testing.T) {
+		agents, err := cdb.ReadTopUserAgents(from, to, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserAgentCount{{UserAgent: "app", Requests: 4}}, agents)
+	})
+
+	t.Run("EndpointUsage", func(t *testing.T) {
+		usage, err := cdb.ReadEndpointUsage(from, to, "", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.EndpointUsage{
+			{Sig: "#8", Endpoint: "GET /db/entries", Requests: 2},
+			{Sig: "#3", Endpoint: "GET /db/entries", Requests: 1},
+			{Sig: "#8", Endpoint: "POST /db/entries", Requests: 1},
+		}, usage)
+
+		usage, err = cdb.ReadEndpointUsage(from, to, "#3", 10)
+		assert.NoError(t, err)
+		assert.Len(t, usage, 1)
+	})
+
+	t.Run("DeleteVisitsBefore", func(t *testing.T) {
+		n, err := cdb.DeleteVisitsBefore(from)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+}
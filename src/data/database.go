@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"errors"
+	"time"
 
 	// "gorm.io/gorm"
 
@@ -26,10 +27,20 @@ type Database interface {
 
 	CreateEntry(entry *models.Entry) (*models.Entry, error)
 	ReadEntry(id int64) (*models.Entry, error)
-	ReadEntries(take int, skip int, filter string) ([]models.Entry, error)
+	ReadEntries(take int, skip int, filter string, sort string, fields []string) ([]models.Entry, error)
 	UpdateEntry(entry *models.Entry) (*models.Entry, error)
 	DeleteEntry(entry *models.Entry) (*models.Entry, error)
 	LikeEntry(entryId int64, sig string, host string) error
+	ExportEntries(from time.Time, to time.Time, fn func(*models.Entry) error) error
+
+	ReadHostPatterns() ([]models.HostPattern, error)
+
+	CreateSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error)
+	ReadSavedFilter(id int64) (*models.SavedFilter, error)
+	ReadSavedFilterByName(memberNumber int64, name string) (*models.SavedFilter, error)
+	ReadSavedFilters(memberNumber int64) ([]models.SavedFilter, error)
+	UpdateSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error)
+	DeleteSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error)
 
 	CreateMember(member *models.Member) (*models.Member, error)
 	ReadMember(id int64) (*models.Member, error)
@@ -37,19 +48,56 @@ type Database interface {
 	ReadMemberByEmail(email string) (*models.Member, error)
 	ReadMembers(onlyValid bool) ([]models.Member, error)
 	UpdateMember(member *models.Member) (*models.Member, error)
+	PatchMember(id int64, patch models.MemberPatch) (*models.Member, error)
 	DeleteMember(member *models.Member) (*models.Member, error)
 
 	CreateArr(arr *models.Arr) (*models.Arr, error)
 	ReadArr(id int64) (*models.Arr, error)
-	ReadArrs(take int, skip int) ([]models.Arr, error)
+	ReadArrs(take int, skip int, filter models.ArrFilter) ([]models.Arr, error)
+	BackfillArrStartsAt(all bool) (int, []models.Arr, error)
 	UpdateArr(arr *models.Arr) (*models.Arr, error)
 	DeleteArr(arr *models.Arr) (*models.Arr, error)
+	SetArrAttendance(arrId int64, memberNumber int64, status models.AttendanceStatus) (*models.Arr, error)
+	DeleteArrAttendance(arrId int64, memberNumber int64) (*models.Arr, error)
+
+	CreateHetsa(hetsa *models.Hetsa) (*models.Hetsa, error)
+	ReadHetsaByCode(code string) (*models.Hetsa, error)
+	RespondHetsa(code string, status models.HetsaStatus, at time.Time) (*models.Hetsa, error)
+
+	// Reminders of arr sent to attending members
+	ReadArrReminders(arrId int64) ([]models.ArrReminder, error)
+	ClaimArrReminder(reminder *models.ArrReminder) (bool, error)
+	DeleteArrReminder(reminder *models.ArrReminder) error
+	ReadReminderOptOut(memberNumber int64) (bool, error)
+	SetReminderOptOut(memberNumber int64, optOut bool) error
+
+	// Suplogg: drinking logs of arr
+	CreateSuplogg(suplogg *models.Suplogg) (*models.Suplogg, error)
+	ReadSuplogg(id int64) (*models.Suplogg, error)
+	ReadSuploggs(take int, skip int) ([]models.Suplogg, error)
+	UpdateSuplogg(suplogg *models.Suplogg) (*models.Suplogg, error)
+	DeleteSuplogg(id int64) (bool, error)
+	AddSuploggEnheter(id int64, name string, count int64) (*models.SuploggDeltagare, error)
+	CloseSuplogg(id int64, at time.Time) (*models.Suplogg, error)
+	SetSuploggEntry(id int64, entryId int64) error
+	ReadSuploggLeaderboard(take int) ([]models.SuploggResult, error)
+
+	// Polls: yae or nay, or more options, one vote per member
+	CreatePoll(poll *models.Poll) (*models.Poll, error)
+	ReadPoll(id int64) (*models.Poll, error)
+	ReadPolls(take int, skip int) ([]models.Poll, error)
+	UpdatePoll(poll *models.Poll) (*models.Poll, error)
+	DeletePoll(id int64) (bool, error)
+	VotePoll(id int64, memberNumber int64, vote int, at time.Time) (*models.Poll, error)
+	ReadPollVote(id int64, memberNumber int64) (*models.PollVote, error)
 
 	CreateProspect(prospect *models.Prospect) (*models.Prospect, error)
 	ReadProspect(id int64) (*models.Prospect, error)
 	ReadProspects(status string) ([]models.Prospect, error)
 	UpdateProspect(prospect *models.Prospect) (*models.Prospect, error)
 	DeleteProspect(prospect *models.Prospect) (*models.Prospect, error)
+	PromoteProspect(id int64, number int64, by int64) (*models.Member, *models.Prospect, error)
+	ReadMemberEvents(after int64, take int, memberNumber int64) ([]models.MemberEvent, error)
 
 	CreateArticle(article *models.Article) (*models.Article, error)
 	ReadArticle(id int64) (*models.Article, error)
@@ -57,6 +105,13 @@ type Database interface {
 	UpdateArticle(article *models.Article) (*models.Article, error)
 	DeleteArticle(article *models.Article) (*models.Article, error)
 
+	// Request analytics
+	CreateVisits(visits []models.Visit) error
+	DeleteVisitsBefore(t time.Time) (int64, error)
+	ReadDailyVisitors(from time.Time, to time.Time) ([]models.DailyVisitors, error)
+	ReadTopUserAgents(from time.Time, to time.Time, take int) ([]models.UserAgentCount, error)
+	ReadEndpointUsage(from time.Time, to time.Time, sig string, take int) ([]models.EndpointUsage, error)
+
 	// Auth operations
 	CreateAuthState(state *models.AuthState) error
 	GetAuthState(id string) (*models.AuthState, error)
@@ -68,6 +123,27 @@ type Database interface {
 	GetSession(token string) (*models.Session, error)
 	DeleteSession(token string) error
 	CleanupExpiredSessions() error
+
+	// Login identities linked to members
+	CreateIdentity(identity *models.Identity) (*models.Identity, error)
+	ReadIdentity(provider string, providerUserID string) (*models.Identity, error)
+	ReadIdentities(memberNumber int64) ([]models.Identity, error)
+	UpdateIdentityLogin(id int64, email string, at time.Time) error
+	DeleteIdentity(identity *models.Identity) (*models.Identity, error)
+
+	// Secrets of personal feeds for clients without a Bearer header
+	ReadFeedSecret(memberNumber int64, feed string) (*models.FeedSecret, error)
+	CreateFeedSecret(secret *models.FeedSecret) (*models.FeedSecret, error)
+	UpdateFeedSecret(secret *models.FeedSecret) error
+	DeleteFeedSecret(memberNumber int64, feed string) error
+
+	// Classic password logins
+	SetMemberPassword(number int64, hash string) error
+	SetPasswordResetString(number int64, reset string) error
+	ResetMemberPassword(number int64, reset string, hash string) (bool, error)
+	ReadLoginLockout(memberNumber int64) (*models.LoginLockout, error)
+	RecordFailedLogin(memberNumber int64, at time.Time, maxAttempts int, lockFor time.Duration) (*models.LoginLockout, error)
+	ClearFailedLogins(memberNumber int64) error
 }
 
 func NewDatabase() (Database, error) {
@@ -87,6 +163,11 @@ func NewDatabase() (Database, error) {
 		return nil, err
 	}
 
+	if c := config.GetCache(); c.Enabled {
+		slog.Info("enabling database cache", "size", c.Size, "ttl_seconds", c.TTLSeconds)
+		database = NewCachedDatabase(database, c.Size, time.Duration(c.TTLSeconds)*time.Second)
+	}
+
 	// err = database.Migrate()
 	// if err != nil {
 	//         return nil, err
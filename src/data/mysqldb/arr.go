@@ -12,8 +12,12 @@ func (d *MySQLDatabase) ReadArr(id int64) (*models.Arr, error) {
 	return d.CommonDB.ReadArr(id)
 }
 
-func (d *MySQLDatabase) ReadArrs(take int, skip int) ([]models.Arr, error) {
-	return d.CommonDB.ReadArrs(take, skip)
+func (d *MySQLDatabase) ReadArrs(take int, skip int, filter models.ArrFilter) ([]models.Arr, error) {
+	return d.CommonDB.ReadArrs(take, skip, filter)
+}
+
+func (d *MySQLDatabase) BackfillArrStartsAt(all bool) (int, []models.Arr, error) {
+	return d.CommonDB.BackfillArrStartsAt(all)
 }
 
 func (d *MySQLDatabase) UpdateArr(arr *models.Arr) (*models.Arr, error) {
@@ -23,3 +27,11 @@ func (d *MySQLDatabase) UpdateArr(arr *models.Arr) (*models.Arr, error) {
 func (d *MySQLDatabase) DeleteArr(arr *models.Arr) (*models.Arr, error) {
 	return d.CommonDB.DeleteArr(arr)
 }
+
+func (d *MySQLDatabase) SetArrAttendance(arrId int64, memberNumber int64, status models.AttendanceStatus) (*models.Arr, error) {
+	return d.CommonDB.SetArrAttendance(arrId, memberNumber, status)
+}
+
+func (d *MySQLDatabase) DeleteArrAttendance(arrId int64, memberNumber int64) (*models.Arr, error) {
+	return d.CommonDB.DeleteArrAttendance(arrId, memberNumber)
+}
@@ -1,6 +1,8 @@
 package mysqldb
 
 import (
+       "time"
+
        "github.com/sebastiw/sidan-backend/src/models"
 )
 
@@ -12,8 +14,8 @@ func (d *MySQLDatabase) ReadEntry(id int64) (*models.Entry, error) {
 	return d.CommonDB.ReadEntry(id)
 }
 
-func (d *MySQLDatabase) ReadEntries(take int, skip int, filter string) ([]models.Entry, error) {
-	return d.CommonDB.ReadEntries(take, skip, filter)
+func (d *MySQLDatabase) ReadEntries(take int, skip int, filter string, sort string, fields []string) ([]models.Entry, error) {
+	return d.CommonDB.ReadEntries(take, skip, filter, sort, fields)
 }
 
 func (d *MySQLDatabase) UpdateEntry(entry *models.Entry) (*models.Entry, error) {
@@ -27,3 +29,7 @@ func (d *MySQLDatabase) DeleteEntry(entry *models.Entry) (*models.Entry, error)
 func (d *MySQLDatabase) LikeEntry(entryId int64, sig string, host string) error {
 	return d.CommonDB.LikeEntry(entryId, sig, host)
 }
+
+func (d *MySQLDatabase) ExportEntries(from time.Time, to time.Time, fn func(*models.Entry) error) error {
+	return d.CommonDB.ExportEntries(from, to, fn)
+}
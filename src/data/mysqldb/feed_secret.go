@@ -0,0 +1,21 @@
+package mysqldb
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) ReadFeedSecret(memberNumber int64, feed string) (*models.FeedSecret, error) {
+	return d.CommonDB.ReadFeedSecret(memberNumber, feed)
+}
+
+func (d *MySQLDatabase) CreateFeedSecret(secret *models.FeedSecret) (*models.FeedSecret, error) {
+	return d.CommonDB.CreateFeedSecret(secret)
+}
+
+func (d *MySQLDatabase) UpdateFeedSecret(secret *models.FeedSecret) error {
+	return d.CommonDB.UpdateFeedSecret(secret)
+}
+
+func (d *MySQLDatabase) DeleteFeedSecret(memberNumber int64, feed string) error {
+	return d.CommonDB.DeleteFeedSecret(memberNumber, feed)
+}
@@ -0,0 +1,19 @@
+package mysqldb
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) CreateHetsa(hetsa *models.Hetsa) (*models.Hetsa, error) {
+	return d.CommonDB.CreateHetsa(hetsa)
+}
+
+func (d *MySQLDatabase) ReadHetsaByCode(code string) (*models.Hetsa, error) {
+	return d.CommonDB.ReadHetsaByCode(code)
+}
+
+func (d *MySQLDatabase) RespondHetsa(code string, status models.HetsaStatus, at time.Time) (*models.Hetsa, error) {
+	return d.CommonDB.RespondHetsa(code, status, at)
+}
@@ -0,0 +1,9 @@
+package mysqldb
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) ReadHostPatterns() ([]models.HostPattern, error) {
+	return d.CommonDB.ReadHostPatterns()
+}
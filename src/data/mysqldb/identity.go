@@ -0,0 +1,27 @@
+package mysqldb
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) CreateIdentity(identity *models.Identity) (*models.Identity, error) {
+	return d.CommonDB.CreateIdentity(identity)
+}
+
+func (d *MySQLDatabase) ReadIdentity(provider string, providerUserID string) (*models.Identity, error) {
+	return d.CommonDB.ReadIdentity(provider, providerUserID)
+}
+
+func (d *MySQLDatabase) ReadIdentities(memberNumber int64) ([]models.Identity, error) {
+	return d.CommonDB.ReadIdentities(memberNumber)
+}
+
+func (d *MySQLDatabase) UpdateIdentityLogin(id int64, email string, at time.Time) error {
+	return d.CommonDB.UpdateIdentityLogin(id, email, at)
+}
+
+func (d *MySQLDatabase) DeleteIdentity(identity *models.Identity) (*models.Identity, error) {
+	return d.CommonDB.DeleteIdentity(identity)
+}
@@ -0,0 +1,19 @@
+package mysqldb
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) ReadLoginLockout(memberNumber int64) (*models.LoginLockout, error) {
+	return d.CommonDB.ReadLoginLockout(memberNumber)
+}
+
+func (d *MySQLDatabase) RecordFailedLogin(memberNumber int64, at time.Time, maxAttempts int, lockFor time.Duration) (*models.LoginLockout, error) {
+	return d.CommonDB.RecordFailedLogin(memberNumber, at, maxAttempts, lockFor)
+}
+
+func (d *MySQLDatabase) ClearFailedLogins(memberNumber int64) error {
+	return d.CommonDB.ClearFailedLogins(memberNumber)
+}
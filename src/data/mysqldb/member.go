@@ -28,6 +28,22 @@ func (d *MySQLDatabase) UpdateMember(member *models.Member) (*models.Member, err
 	return d.CommonDB.UpdateMember(member)
 }
 
+func (d *MySQLDatabase) PatchMember(id int64, patch models.MemberPatch) (*models.Member, error) {
+	return d.CommonDB.PatchMember(id, patch)
+}
+
 func (d *MySQLDatabase) DeleteMember(member *models.Member) (*models.Member, error) {
 	return d.CommonDB.DeleteMember(member)
 }
+
+func (d *MySQLDatabase) SetMemberPassword(number int64, hash string) error {
+	return d.CommonDB.SetMemberPassword(number, hash)
+}
+
+func (d *MySQLDatabase) SetPasswordResetString(number int64, reset string) error {
+	return d.CommonDB.SetPasswordResetString(number, reset)
+}
+
+func (d *MySQLDatabase) ResetMemberPassword(number int64, reset string, hash string) (bool, error) {
+	return d.CommonDB.ResetMemberPassword(number, reset, hash)
+}
@@ -0,0 +1,35 @@
+package mysqldb
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) CreatePoll(poll *models.Poll) (*models.Poll, error) {
+	return d.CommonDB.CreatePoll(poll)
+}
+
+func (d *MySQLDatabase) ReadPoll(id int64) (*models.Poll, error) {
+	return d.CommonDB.ReadPoll(id)
+}
+
+func (d *MySQLDatabase) ReadPolls(take int, skip int) ([]models.Poll, error) {
+	return d.CommonDB.ReadPolls(take, skip)
+}
+
+func (d *MySQLDatabase) UpdatePoll(poll *models.Poll) (*models.Poll, error) {
+	return d.CommonDB.UpdatePoll(poll)
+}
+
+func (d *MySQLDatabase) DeletePoll(id int64) (bool, error) {
+	return d.CommonDB.DeletePoll(id)
+}
+
+func (d *MySQLDatabase) VotePoll(id int64, memberNumber int64, vote int, at time.Time) (*models.Poll, error) {
+	return d.CommonDB.VotePoll(id, memberNumber, vote, at)
+}
+
+func (d *MySQLDatabase) ReadPollVote(id int64, memberNumber int64) (*models.PollVote, error) {
+	return d.CommonDB.ReadPollVote(id, memberNumber)
+}
@@ -23,3 +23,11 @@ func (d *MySQLDatabase) UpdateProspect(prospect *models.Prospect) (*models.Prosp
 func (d *MySQLDatabase) DeleteProspect(prospect *models.Prospect) (*models.Prospect, error) {
 	return d.CommonDB.DeleteProspect(prospect)
 }
+
+func (d *MySQLDatabase) PromoteProspect(id int64, number int64, by int64) (*models.Member, *models.Prospect, error) {
+	return d.CommonDB.PromoteProspect(id, number, by)
+}
+
+func (d *MySQLDatabase) ReadMemberEvents(after int64, take int, memberNumber int64) ([]models.MemberEvent, error) {
+	return d.CommonDB.ReadMemberEvents(after, take, memberNumber)
+}
@@ -0,0 +1,25 @@
+package mysqldb
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) ReadArrReminders(arrId int64) ([]models.ArrReminder, error) {
+	return d.CommonDB.ReadArrReminders(arrId)
+}
+
+func (d *MySQLDatabase) ClaimArrReminder(reminder *models.ArrReminder) (bool, error) {
+	return d.CommonDB.ClaimArrReminder(reminder)
+}
+
+func (d *MySQLDatabase) DeleteArrReminder(reminder *models.ArrReminder) error {
+	return d.CommonDB.DeleteArrReminder(reminder)
+}
+
+func (d *MySQLDatabase) ReadReminderOptOut(memberNumber int64) (bool, error) {
+	return d.CommonDB.ReadReminderOptOut(memberNumber)
+}
+
+func (d *MySQLDatabase) SetReminderOptOut(memberNumber int64, optOut bool) error {
+	return d.CommonDB.SetReminderOptOut(memberNumber, optOut)
+}
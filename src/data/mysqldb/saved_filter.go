@@ -0,0 +1,29 @@
+package mysqldb
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) CreateSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	return d.CommonDB.CreateSavedFilter(filter)
+}
+
+func (d *MySQLDatabase) ReadSavedFilter(id int64) (*models.SavedFilter, error) {
+	return d.CommonDB.ReadSavedFilter(id)
+}
+
+func (d *MySQLDatabase) ReadSavedFilterByName(memberNumber int64, name string) (*models.SavedFilter, error) {
+	return d.CommonDB.ReadSavedFilterByName(memberNumber, name)
+}
+
+func (d *MySQLDatabase) ReadSavedFilters(memberNumber int64) ([]models.SavedFilter, error) {
+	return d.CommonDB.ReadSavedFilters(memberNumber)
+}
+
+func (d *MySQLDatabase) UpdateSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	return d.CommonDB.UpdateSavedFilter(filter)
+}
+
+func (d *MySQLDatabase) DeleteSavedFilter(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	return d.CommonDB.DeleteSavedFilter(filter)
+}
@@ -0,0 +1,43 @@
+package mysqldb
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) CreateSuplogg(suplogg *models.Suplogg) (*models.Suplogg, error) {
+	return d.CommonDB.CreateSuplogg(suplogg)
+}
+
+func (d *MySQLDatabase) ReadSuplogg(id int64) (*models.Suplogg, error) {
+	return d.CommonDB.ReadSuplogg(id)
+}
+
+func (d *MySQLDatabase) ReadSuploggs(take int, skip int) ([]models.Suplogg, error) {
+	return d.CommonDB.ReadSuploggs(take, skip)
+}
+
+func (d *MySQLDatabase) UpdateSuplogg(suplogg *models.Suplogg) (*models.Suplogg, error) {
+	return d.CommonDB.UpdateSuplogg(suplogg)
+}
+
+func (d *MySQLDatabase) DeleteSuplogg(id int64) (bool, error) {
+	return d.CommonDB.DeleteSuplogg(id)
+}
+
+func (d *MySQLDatabase) AddSuploggEnheter(id int64, name string, count int64) (*models.SuploggDeltagare, error) {
+	return d.CommonDB.AddSuploggEnheter(id, name, count)
+}
+
+func (d *MySQLDatabase) CloseSuplogg(id int64, at time.Time) (*models.Suplogg, error) {
+	return d.CommonDB.CloseSuplogg(id, at)
+}
+
+func (d *MySQLDatabase) SetSuploggEntry(id int64, entryId int64) error {
+	return d.CommonDB.SetSuploggEntry(id, entryId)
+}
+
+func (d *MySQLDatabase) ReadSuploggLeaderboard(take int) ([]models.SuploggResult, error) {
+	return d.CommonDB.ReadSuploggLeaderboard(take)
+}
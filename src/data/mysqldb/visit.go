@@ -0,0 +1,27 @@
+package mysqldb
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func (d *MySQLDatabase) CreateVisits(visits []models.Visit) error {
+	return d.CommonDB.CreateVisits(visits)
+}
+
+func (d *MySQLDatabase) DeleteVisitsBefore(t time.Time) (int64, error) {
+	return d.CommonDB.DeleteVisitsBefore(t)
+}
+
+func (d *MySQLDatabase) ReadDailyVisitors(from time.Time, to time.Time) ([]models.DailyVisitors, error) {
+	return d.CommonDB.ReadDailyVisitors(from, to)
+}
+
+func (d *MySQLDatabase) ReadTopUserAgents(from time.Time, to time.Time, take int) ([]models.UserAgentCount, error) {
+	return d.CommonDB.ReadTopUserAgents(from, to, take)
+}
+
+func (d *MySQLDatabase) ReadEndpointUsage(from time.Time, to time.Time, sig string, take int) ([]models.EndpointUsage, error) {
+	return d.CommonDB.ReadEndpointUsage(from, to, sig, take)
+}
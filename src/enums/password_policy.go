@@ -0,0 +1,52 @@
+package enums
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// ParsePasswordPolicy reads a policy by name, as written in the config
+func ParsePasswordPolicy(s string) (PasswordPolicy, error) {
+	for p := PasswordPolicyNone; p <= PasswordPolicyHigh; p++ {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return PasswordPolicyNone, fmt.Errorf("unknown password policy %q", s)
+}
+
+// Validate checks password against the policy. The returned error wraps
+// ErrWeakPassword and tells what is missing.
+func (p PasswordPolicy) Validate(password string) error {
+	var upper, lower, digit, symbol bool
+	length := 0
+	for _, r := range password {
+		length++
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	minLength := []int{1, 6, 8, 10}[p]
+	if length < minLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, minLength)
+	}
+	if p >= PasswordPolicyMedium && !(upper && lower && digit) {
+		return fmt.Errorf("%w: an uppercase letter, a lowercase letter and a number required", ErrWeakPassword)
+	}
+	if p >= PasswordPolicyHigh && !symbol {
+		return fmt.Errorf("%w: a special character required", ErrWeakPassword)
+	}
+	return nil
+}
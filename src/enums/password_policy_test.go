@@ -0,0 +1,42 @@
+package enums
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy   PasswordPolicy
+		password string
+		ok       bool
+	}{
+		{PasswordPolicyNone, "", false},
+		{PasswordPolicyNone, "a", true},
+		{PasswordPolicyLow, "abcde", false},
+		{PasswordPolicyLow, "abcdef", true},
+		{PasswordPolicyMedium, "abcdefgh", false},
+		{PasswordPolicyMedium, "Abcdefg1", true},
+		{PasswordPolicyMedium, "Åäöåäö12", true},
+		{PasswordPolicyHigh, "Abcdefgh12", false},
+		{PasswordPolicyHigh, "Abcdefgh1!", true},
+	}
+	for _, tt := range tests {
+		err := tt.policy.Validate(tt.password)
+		if tt.ok && err != nil {
+			t.Errorf("%s %q: unexpected error %v", tt.policy, tt.password, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrWeakPassword) {
+			t.Errorf("%s %q: expected ErrWeakPassword, got %v", tt.policy, tt.password, err)
+		}
+	}
+}
+
+func TestParsePasswordPolicy(t *testing.T) {
+	if p, err := ParsePasswordPolicy("Medium"); err != nil || p != PasswordPolicyMedium {
+		t.Errorf("got %v, %v", p, err)
+	}
+	if _, err := ParsePasswordPolicy("extreme"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,67 @@
+package hosts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type cachedNames struct {
+	names   []string
+	err     error
+	expires time.Time
+}
+
+// CachedResolver remembers reverse lookups per address for ttl, also those
+// that failed or timed out, so that slow DNS costs at most one lookup per
+// address. At most size addresses are kept.
+type CachedResolver struct {
+	resolver Resolver
+	ttl      time.Duration
+	size     int
+
+	mu      sync.Mutex
+	entries map[string]cachedNames
+	now     func() time.Time
+}
+
+func NewCachedResolver(resolver Resolver, ttl time.Duration, size int) *CachedResolver {
+	return &CachedResolver{resolver: resolver, ttl: ttl, size: size, entries: map[string]cachedNames{}, now: time.Now}
+}
+
+func (c *CachedResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	c.mu.Lock()
+	e, ok := c.entries[addr]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return e.names, e.err
+	}
+
+	names, err := c.resolver.LookupAddr(ctx, addr)
+	// A request that went away says nothing about the address
+	if errors.Is(err, context.Canceled) {
+		return names, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		c.evict()
+	}
+	c.entries[addr] = cachedNames{names: names, err: err, expires: c.now().Add(c.ttl)}
+	return names, err
+}
+
+// evict drops expired addresses, or all of them if none has expired
+func (c *CachedResolver) evict() {
+	now := c.now()
+	for addr, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, addr)
+		}
+	}
+	if len(c.entries) >= c.size {
+		c.entries = map[string]cachedNames{}
+	}
+}
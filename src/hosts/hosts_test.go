@@ -0,0 +1,102 @@
+package hosts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if names, ok := f[addr]; ok {
+		return names, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestLookup(t *testing.T) {
+	resolver := fakeResolver{"129.16.1.1": {"gw.chalmers.se."}}
+
+	assert.Equal(t, "gw.chalmers.se", Lookup(context.Background(), resolver, "129.16.1.1"))
+	assert.Equal(t, "10.0.0.1", Lookup(context.Background(), resolver, "10.0.0.1"))
+	assert.Equal(t, "10.0.0.1", Lookup(context.Background(), nil, "10.0.0.1"))
+}
+
+type countingResolver struct {
+	fakeResolver
+	lookups int
+}
+
+func (c *countingResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	c.lookups++
+	return c.fakeResolver.LookupAddr(ctx, addr)
+}
+
+func TestCachedResolver(t *testing.T) {
+	counting := &countingResolver{fakeResolver: fakeResolver{"129.16.1.1": {"gw.chalmers.se."}}}
+	c := NewCachedResolver(counting, time.Hour, 2)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "gw.chalmers.se", Lookup(context.Background(), c, "129.16.1.1"))
+		// Failures are remembered too
+		assert.Equal(t, "10.0.0.1", Lookup(context.Background(), c, "10.0.0.1"))
+	}
+	assert.Equal(t, 2, counting.lookups)
+
+	// Full, nothing expired: start over
+	Lookup(context.Background(), c, "10.0.0.2")
+	Lookup(context.Background(), c, "129.16.1.1")
+	assert.Equal(t, 4, counting.lookups)
+
+	now = now.Add(2 * time.Hour)
+	Lookup(context.Background(), c, "129.16.1.1")
+	assert.Equal(t, 5, counting.lookups)
+
+	// Canceled lookups are not remembered
+	canceled := &canceledResolver{}
+	c = NewCachedResolver(canceled, time.Hour, 2)
+	Lookup(context.Background(), c, "10.0.0.3")
+	Lookup(context.Background(), c, "10.0.0.3")
+	assert.Equal(t, 2, canceled.lookups)
+}
+
+type canceledResolver struct {
+	lookups int
+}
+
+func (c *canceledResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	c.lookups++
+	return nil, context.Canceled
+}
+
+func TestMatcher(t *testing.T) {
+	loads := 0
+	m := NewMatcher(func() ([]models.HostPattern, error) {
+		loads++
+		return []models.HostPattern{
+			{Id: 1, Name1: "Bosse", Name2: "Ek", Pattern: `\.chalmers\.se$`},
+			{Id: 2, Name1: "Invalid", Pattern: `(`},
+			{Id: 3, Name1: "Empty"},
+			{Id: 4, Name1: "Hemma", Pattern: `^192\.168\.`},
+		}, nil
+	}, time.Hour)
+
+	assert.Equal(t, "Bosse Ek", m.Match("GW.Chalmers.SE", "129.16.1.1"))
+	assert.Equal(t, "Hemma", m.Match("", "192.168.0.10"))
+	assert.Equal(t, "", m.Match("example.com", "10.0.0.1"))
+
+	host := "gw.chalmers.se"
+	entry := &models.Entry{Host: &host}
+	m.Annotate(entry)
+	assert.Equal(t, "Bosse Ek", entry.HostName)
+
+	assert.Equal(t, 1, loads)
+}
@@ -0,0 +1,91 @@
+package hosts
+
+import (
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type compiledPattern struct {
+	re   *regexp.Regexp
+	name string
+}
+
+// Matcher maps hosts to nicknames using the cl2003_hosts patterns. The
+// patterns are loaded lazily and reloaded after ttl.
+type Matcher struct {
+	load func() ([]models.HostPattern, error)
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	patterns []compiledPattern
+	loaded   time.Time
+}
+
+func NewMatcher(load func() ([]models.HostPattern, error), ttl time.Duration) *Matcher {
+	return &Matcher{load: load, ttl: ttl}
+}
+
+// Match returns the nickname of the first pattern matching host or ip, or ""
+func (m *Matcher) Match(host string, ip string) string {
+	for _, p := range m.current() {
+		if (host != "" && p.re.MatchString(host)) || (ip != "" && p.re.MatchString(ip)) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// Annotate sets HostName on the entries
+func (m *Matcher) Annotate(entries ...*models.Entry) {
+	for _, e := range entries {
+		var host, ip string
+		if e.Host != nil {
+			host = *e.Host
+		}
+		if e.Ip != nil {
+			ip = *e.Ip
+		}
+		e.HostName = m.Match(host, ip)
+	}
+}
+
+func (m *Matcher) current() []compiledPattern {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.loaded.IsZero() && time.Since(m.loaded) < m.ttl {
+		return m.patterns
+	}
+	// On failure keep the previous patterns and retry after ttl
+	m.loaded = time.Now()
+
+	rows, err := m.load()
+	if err != nil {
+		slog.Warn("unable to load host patterns", "error", err.Error())
+		return m.patterns
+	}
+	m.patterns = compile(rows)
+	return m.patterns
+}
+
+// compile turns the legacy patterns (case insensitive, unanchored regular
+// expressions) into matchers, skipping empty and invalid ones
+func compile(rows []models.HostPattern) []compiledPattern {
+	patterns := make([]compiledPattern, 0, len(rows))
+	for _, row := range rows {
+		if row.Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + row.Pattern)
+		if err != nil {
+			slog.Debug("skipping invalid host pattern", "pattern", row.Fmt(), "error", err.Error())
+			continue
+		}
+		patterns = append(patterns, compiledPattern{re: re, name: row.Name()})
+	}
+	return patterns
+}
@@ -0,0 +1,33 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// Resolver does reverse DNS lookups. *net.Resolver implements it.
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// LookupTimeout bounds a reverse lookup so that slow DNS does not hold up
+// the request
+var LookupTimeout = 2 * time.Second
+
+// Lookup returns the host name of ip, or ip itself if it has none or the
+// resolver is nil
+func Lookup(ctx context.Context, resolver Resolver, ip string) string {
+	if resolver == nil || net.ParseIP(ip) == nil {
+		return ip
+	}
+	ctx, cancel := context.WithTimeout(ctx, LookupTimeout)
+	defer cancel()
+
+	names, err := resolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ip
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
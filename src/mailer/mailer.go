@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages. Tests replace the SMTP sender with a recorder.
+type Sender interface {
+	Send(m Message) error
+}
+
+// SMTPSender sends through the server in config.MailConfiguration
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPSender(cfg *config.MailConfiguration) *SMTPSender {
+	return &SMTPSender{Host: cfg.Host, Port: cfg.Port, Username: cfg.User, Password: cfg.Password, From: cfg.From}
+}
+
+// Send delivers m, from the configured address unless m.From is set
+func (s *SMTPSender) Send(m Message) error {
+	if m.From == "" {
+		m.From = s.From
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(fmt.Sprintf("%s:%d", s.Host, s.Port), auth, m.From, m.To, m.Bytes(time.Now()))
+}
+
+// Bytes renders m as an RFC 5322 message with a UTF-8 body
+func (m Message) Bytes(date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(m.From))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(strings.Join(m.To, ", ")))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(m.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n")
+	b.WriteString(body)
+	if !strings.HasSuffix(body, "\r\n") {
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
+
+// headerValue keeps a value on one line, so it cannot add headers
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,24 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBytes(t *testing.T) {
+	m := Message{
+		From:    "sidan@example.com",
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Återställ lösenord",
+		Body:    "Rad ett\nRad två",
+	}
+	out := string(m.Bytes(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)))
+
+	assert.Contains(t, out, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, out, "Subject: =?utf-8?q?=C3=85terst=C3=A4ll_l=C3=B6senord?=\r\n")
+	assert.Contains(t, out, "Date: Sun, 18 Oct 2026 12:00:00 +0000\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nRad ett\r\nRad två\r\n"))
+}
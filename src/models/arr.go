@@ -1,6 +1,11 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Arr represents an event/arrangemang in the cl2015_arrsidan table
 type Arr struct {
@@ -14,6 +19,50 @@ type Arr struct {
 	Hetsade     *string `gorm:"column:hetsade" json:"hetsade"`
 	Losen       *string `gorm:"column:losen" json:"losen"`
 	Fularr      *string `gorm:"column:fularr" json:"fularr"`
+
+	// StartsAt is StartDate parsed, nil when it could not be interpreted
+	StartsAt *time.Time `gorm:"column:starts_at;index" json:"starts_at"`
+
+	// Attendance of members. Deltagare, Kanske and Hetsade are kept in sync
+	// for old clients, and also list guests who are not members.
+	Attendance []ArrAttendance `gorm:"foreignKey:ArrId" json:"attendance"`
+	// Guests are people outside the club hetsad to the arr, and their answers
+	Guests []Hetsa `gorm:"foreignKey:ArrId" json:"guests"`
+}
+
+// ArrFilter narrows ReadArrs. Query is an RSQL expression on namn, plats and
+// organisator. Upcoming and Past are relative to Now and order the arr by
+// start, soonest first and latest first respectively. From and To bound
+// StartsAt, From inclusive and To exclusive. Arr whose start date could not
+// be parsed only match when no time filter is given.
+type ArrFilter struct {
+	Query    string
+	Upcoming bool
+	Past     bool
+	From     *time.Time
+	To       *time.Time
+	Now      time.Time
+}
+
+// AttendanceStatus is a member's answer to an arr
+type AttendanceStatus string
+
+const (
+	AttendanceYes    AttendanceStatus = "yes"
+	AttendanceMaybe  AttendanceStatus = "maybe"
+	AttendanceHetsad AttendanceStatus = "hetsad"
+)
+
+// ArrAttendance is one member's answer to an arr in cl_arr_attendance
+type ArrAttendance struct {
+	ArrId        int64            `gorm:"column:arr_id;primaryKey;autoIncrement:false" json:"-"`
+	MemberNumber int64            `gorm:"column:member_number;primaryKey;autoIncrement:false" json:"member_number"`
+	Status       AttendanceStatus `gorm:"column:status;size:8;not null" json:"status"`
+	UpdatedAt    time.Time        `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (ArrAttendance) TableName() string {
+	return "cl_arr_attendance"
 }
 
 // TableName specifies the table name for GORM
@@ -33,3 +82,106 @@ func (a Arr) Fmt() string {
 	}
 	return fmt.Sprintf("Arr{Id: %d, Namn: %s, Plats: %s}", a.Id, namn, plats)
 }
+
+// DeltagareList returns the signed up participants, e.g. ["#10", "#3", "GuiGui"]
+func (a Arr) DeltagareList() []string {
+	return splitParticipants(a.Deltagare)
+}
+
+// KanskeList returns the participants who answered maybe
+func (a Arr) KanskeList() []string {
+	return splitParticipants(a.Kanske)
+}
+
+// HetsadeList returns the members who have been egged on to come
+func (a Arr) HetsadeList() []string {
+	return splitParticipants(a.Hetsade)
+}
+
+func splitParticipants(s *string) []string {
+	if s == nil {
+		return nil
+	}
+	var out []string
+	for _, p := range strings.Split(*s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// memberSig returns the number of a member signature like "#8"
+func memberSig(sig string) (int64, bool) {
+	if !strings.HasPrefix(sig, "#") {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(sig[1:], 10, 64)
+	return n, err == nil && n > 0
+}
+
+// AttendanceStatuses in order of precedence
+var AttendanceStatuses = []AttendanceStatus{AttendanceYes, AttendanceMaybe, AttendanceHetsad}
+
+// legacyColumn is the legacy column listing members with the status
+func (a *Arr) legacyColumn(status AttendanceStatus) **string {
+	switch status {
+	case AttendanceYes:
+		return &a.Deltagare
+	case AttendanceMaybe:
+		return &a.Kanske
+	}
+	return &a.Hetsade
+}
+
+// LegacyAttendance reads the member attendance from the legacy columns. A
+// member listed in more than one column gets the first of yes, maybe and
+// hetsad.
+func (a Arr) LegacyAttendance() []ArrAttendance {
+	seen := map[int64]bool{}
+	var out []ArrAttendance
+	for _, st := range AttendanceStatuses {
+		for _, sig := range splitParticipants(*a.legacyColumn(st)) {
+			if n, ok := memberSig(sig); ok && !seen[n] {
+				seen[n] = true
+				out = append(out, ArrAttendance{ArrId: a.Id, MemberNumber: n, Status: st})
+			}
+		}
+	}
+	return out
+}
+
+// SetLegacyAttendance rewrites the legacy columns from the attendance rows.
+// Guests and members already listed keep their place, new members are added
+// last.
+func (a *Arr) SetLegacyAttendance(rows []ArrAttendance) {
+	status := make(map[int64]AttendanceStatus, len(rows))
+	for _, r := range rows {
+		status[r.MemberNumber] = r.Status
+	}
+
+	for _, st := range AttendanceStatuses {
+		column := a.legacyColumn(st)
+		listed := map[int64]bool{}
+		var sigs []string
+		for _, sig := range splitParticipants(*column) {
+			n, ok := memberSig(sig)
+			if !ok {
+				sigs = append(sigs, sig)
+				continue
+			}
+			if status[n] == st && !listed[n] {
+				listed[n] = true
+				sigs = append(sigs, sig)
+			}
+		}
+		for _, r := range rows {
+			if r.Status == st && !listed[r.MemberNumber] {
+				listed[r.MemberNumber] = true
+				sigs = append(sigs, fmt.Sprintf("#%d", r.MemberNumber))
+			}
+		}
+		joined := strings.Join(sigs, ",")
+		*column = &joined
+	}
+}
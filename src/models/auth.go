@@ -13,6 +13,10 @@ type AuthState struct {
 	RedirectURI  string    `gorm:"column:redirect_uri;type:text" json:"redirect_uri,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+
+	// LinkMemberNumber is set when the flow links an identity to the member
+	// instead of logging in
+	LinkMemberNumber *int64 `gorm:"column:link_member_number" json:"link_member_number,omitempty"`
 }
 
 func (AuthState) TableName() string {
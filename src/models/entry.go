@@ -38,6 +38,7 @@ type Entry struct {
 	Likes          int64       `gorm:"-" json:"likes"` // Count from 2003_likes table
 	Secret         bool        `gorm:"-" json:"secret"` // TRUE if ANY permission exists
 	PersonalSecret bool        `gorm:"-" json:"personal_secret"` // TRUE if permission with user_id != 0 exists
+	HostName       string      `gorm:"-" json:"host_name"` // Nickname from cl2003_hosts matching Host or Ip
 	
 	// Relationships
 	SideKicks      []SideKick   `gorm:"foreignKey:Id" json:"sidekicks"`
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// FeedSecret is the secret with which a member's personal feed (e.g. arr.ics)
+// is read by clients that cannot send a Bearer header. It is random and
+// stored, so that replacing or deleting it revokes the old one.
+type FeedSecret struct {
+	MemberNumber int64     `gorm:"column:member_number;primaryKey;autoIncrement:false" json:"member_number"`
+	Feed         string    `gorm:"column:feed;primaryKey;size:32" json:"feed"`
+	Secret       string    `gorm:"column:secret;size:64;not null" json:"-"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (FeedSecret) TableName() string {
+	return "member_feed_secrets"
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HetsaStatus is the answer of someone hetsad to an arr
+type HetsaStatus string
+
+const (
+	HetsaPending  HetsaStatus = "pending"
+	HetsaAccepted HetsaStatus = "accepted"
+	HetsaDeclined HetsaStatus = "declined"
+)
+
+// Hetsa is an invitation of someone outside the club to an arr, in the
+// hetsa table. The invitee answers by Code without an account. The email is
+// never rendered, as arr are public.
+//
+//swagger:response Hetsa
+type Hetsa struct {
+	Id          int64       `gorm:"column:uid;primaryKey;autoIncrement" json:"id"`
+	ArrId       int64       `gorm:"column:arr_id;index" json:"arr_id"`
+	HetsadBy    int64       `gorm:"column:member_number" json:"hetsad_by"`
+	Name        string      `gorm:"column:name2" json:"name"`
+	Email       string      `gorm:"column:email" json:"-"`
+	Comment     string      `gorm:"column:comment" json:"comment"`
+	Code        string      `gorm:"column:mystring;size:12;index" json:"-"`
+	Status      HetsaStatus `gorm:"column:status;size:8;not null;default:pending" json:"status"`
+	CreatedAt   time.Time   `gorm:"column:created_at" json:"created_at"`
+	RespondedAt *time.Time  `gorm:"column:responded_at" json:"responded_at"`
+
+	// Legacy columns: the arr id as text and the signature of the member
+	LegacyArr string `gorm:"column:arr" json:"-"`
+	Name1     string `gorm:"column:name1" json:"-"`
+}
+
+func (Hetsa) TableName() string {
+	return "hetsa"
+}
+
+func (h Hetsa) Fmt() string {
+	s := make([]string, 0)
+	s = addI(s, "Id", h.Id)
+	s = addI(s, "ArrId", h.ArrId)
+	s = addI(s, "HetsadBy", h.HetsadBy)
+	s = addS(s, "Name", h.Name)
+	s = addS(s, "Status", string(h.Status))
+	return fmt.Sprintf("Hetsa{%s}", strings.Join(s, ", "))
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostPattern maps visitor hosts to a nickname in the cl2003_hosts table.
+// Pattern is a case insensitive regular expression matched against the
+// resolved host name or IP, as on the legacy site.
+//swagger:response HostPattern
+type HostPattern struct {
+	Id      int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Innow   int64  `gorm:"column:innow" json:"innow"`
+	Name1   string `gorm:"column:name1" json:"name1"`
+	Name2   string `gorm:"column:name2" json:"name2"`
+	Pattern string `gorm:"column:pattern" json:"pattern"`
+}
+
+// TableName specifies the table name for GORM
+func (HostPattern) TableName() string {
+	return "cl2003_hosts"
+}
+
+// Name returns the nickname to show, e.g. "Kalle Anka"
+func (h HostPattern) Name() string {
+	return strings.TrimSpace(h.Name1 + " " + h.Name2)
+}
+
+// Fmt formats HostPattern for logging
+func (h HostPattern) Fmt() string {
+	return fmt.Sprintf("HostPattern{Id: %d, Name: %s, Pattern: %s}", h.Id, h.Name(), h.Pattern)
+}
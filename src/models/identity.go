@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Identity is a provider account a member can log in with
+//
+//swagger:response Identity
+type Identity struct {
+	Id             int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	MemberNumber   int64      `gorm:"column:member_number;not null;index" json:"member_number"`
+	Provider       string     `gorm:"column:provider;size:32;not null;uniqueIndex:uq_provider_user" json:"provider"`
+	ProviderUserID string     `gorm:"column:provider_user_id;size:255;not null;uniqueIndex:uq_provider_user" json:"provider_user_id"`
+	Email          string     `gorm:"column:email;size:255" json:"email"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	LastLoginAt    *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
+}
+
+func (Identity) TableName() string {
+	return "member_identities"
+}
+
+func (i Identity) Fmt() string {
+	s := make([]string, 0)
+	s = addI(s, "Id", i.Id)
+	s = addI(s, "MemberNumber", i.MemberNumber)
+	s = addS(s, "Provider", i.Provider)
+	s = addS(s, "ProviderUserID", i.ProviderUserID)
+	s = addS(s, "Email", i.Email)
+	return fmt.Sprintf("Identity{%s}", strings.Join(s, ", "))
+}
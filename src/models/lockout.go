@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// LoginLockout counts failed classic logins of a member. The account is
+// locked while LockedUntil is in the future.
+type LoginLockout struct {
+	MemberNumber   int64      `gorm:"column:member_number;primaryKey;autoIncrement:false" json:"member_number"`
+	FailedAttempts int        `gorm:"column:failed_attempts;not null" json:"failed_attempts"`
+	LastFailedAt   *time.Time `gorm:"column:last_failed_at" json:"last_failed_at"`
+	LockedUntil    *time.Time `gorm:"column:locked_until" json:"locked_until"`
+}
+
+func (LoginLockout) TableName() string {
+	return "login_lockouts"
+}
+
+// IsLocked tells whether the account is locked at the given time
+func (l *LoginLockout) IsLocked(now time.Time) bool {
+	return l != nil && l.LockedUntil != nil && now.Before(*l.LockedUntil)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Member events. A promotion stores both, the new member first.
+const (
+	EventMemberCreated    = "member_created"
+	EventProspectPromoted = "prospect_promoted"
+)
+
+// MemberEvent is a change to the membership in cl_member_events, kept for
+// consumers that act on it. Ids only grow, so they can be read as a feed.
+type MemberEvent struct {
+	Id           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Event        string    `gorm:"column:event;size:32;not null" json:"event"`
+	MemberNumber int64     `gorm:"column:member_number;not null;index" json:"member_number"`
+	ProspectId   *int64    `gorm:"column:prospect_id" json:"prospect_id"`
+	CreatedBy    *int64    `gorm:"column:created_by" json:"created_by"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (MemberEvent) TableName() string {
+	return "cl_member_events"
+}
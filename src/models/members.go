@@ -47,8 +47,34 @@ func (m Member) Fmt() string {
 	return fmt.Sprintf("Member{%s, Isvalid: %t}", strings.Join(s, ", "), isvalid)
 }
 
-type MemberLite struct {
-	Id     int64   `json:"id"`
-	Number *int64 `json:"number"`
-	Title  *string `json:"title"`
+// MemberPatch holds the fields members may change on themselves. A nil
+// field is left unchanged, an empty string clears it.
+//swagger:response MemberPatch
+type MemberPatch struct {
+	Phone   *string `json:"phone"`
+	Adress  *string `json:"address"`
+	Im      *string `json:"im"`
+	Picture *string `json:"picture"`
+	History *string `json:"history"`
+}
+
+// Columns returns the changed columns for a partial update
+func (p MemberPatch) Columns() map[string]interface{} {
+	columns := make(map[string]interface{})
+	if p.Phone != nil {
+		columns["phone"] = *p.Phone
+	}
+	if p.Adress != nil {
+		columns["adress"] = *p.Adress
+	}
+	if p.Im != nil {
+		columns["im"] = *p.Im
+	}
+	if p.Picture != nil {
+		columns["picture"] = *p.Picture
+	}
+	if p.History != nil {
+		columns["history"] = *p.History
+	}
+	return columns
 }
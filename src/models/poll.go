@@ -0,0 +1,142 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPollVoted    = errors.New("member has already voted")
+	ErrPollOption   = errors.New("poll has no such option")
+	ErrPollHasVotes = errors.New("poll options cannot change after voting has started")
+)
+
+// Legacy vote values of cl2004_poll_votes. Further options are numbered from
+// PollVoteExtra.
+const (
+	PollVoteNay   = 0
+	PollVoteYae   = 1
+	PollVoteExtra = 2
+)
+
+// Poll is a question in cl2004_poll. Every poll has the options yae and nay;
+// polls with more options keep the rest in Extra. Date and Time are nil for
+// old polls that had none.
+//
+//swagger:response Poll
+type Poll struct {
+	Id        int64        `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	Theme     string       `gorm:"column:theme" json:"theme"`
+	Yae       string       `gorm:"column:yae" json:"yae"`
+	Nay       string       `gorm:"column:nay" json:"nay"`
+	Date      *time.Time   `gorm:"column:date;type:date" json:"-"`
+	Time      *string      `gorm:"column:time" json:"time"`
+	CreatedBy *int64       `gorm:"column:created_by" json:"created_by"`
+	Extra     []PollOption `gorm:"foreignKey:PollId" json:"-"`
+
+	// Computed from cl2004_poll_votes
+	Results []PollResult `gorm:"-" json:"results"`
+	Votes   int64        `gorm:"-" json:"votes"`
+	MyVote  *int         `gorm:"-" json:"my_vote,omitempty"`
+
+	// Legacy columns: where the poll was created from, not rendered
+	Ip   string `gorm:"column:ip" json:"-"`
+	Host string `gorm:"column:host" json:"-"`
+}
+
+func (Poll) TableName() string {
+	return "cl2004_poll"
+}
+
+func (p Poll) Fmt() string {
+	s := make([]string, 0)
+	s = addI(s, "Id", p.Id)
+	s = addS(s, "Theme", p.Theme)
+	s = addS(s, "Yae", p.Yae)
+	s = addS(s, "Nay", p.Nay)
+	if len(p.Extra) > 0 {
+		s = append(s, fmt.Sprintf("Extra: %d", len(p.Extra)))
+	}
+	return fmt.Sprintf("Poll{%s}", strings.Join(s, ", "))
+}
+
+// MarshalJSON renders Date as 2006-01-02, the driver reads it as a time
+func (p Poll) MarshalJSON() ([]byte, error) {
+	type poll Poll
+	var date *string
+	if p.Date != nil {
+		d := p.Date.Format(time.DateOnly)
+		date = &d
+	}
+	return json.Marshal(struct {
+		poll
+		Date *string `json:"date"`
+	}{poll(p), date})
+}
+
+// Options returns all options of the poll in order, yae and nay first
+func (p Poll) Options() []PollOption {
+	options := []PollOption{
+		{PollId: p.Id, Vote: PollVoteYae, Text: p.Yae},
+		{PollId: p.Id, Vote: PollVoteNay, Text: p.Nay},
+	}
+	return append(options, p.Extra...)
+}
+
+// HasOption tells whether vote is one of the options of the poll
+func (p Poll) HasOption(vote int) bool {
+	for _, o := range p.Options() {
+		if o.Vote == vote {
+			return true
+		}
+	}
+	return false
+}
+
+// SetResults fills Results and Votes from the number of votes per option.
+// Votes for options the poll does not have are left out.
+func (p *Poll) SetResults(counts map[int]int64) {
+	p.Results = make([]PollResult, 0, len(p.Extra)+2)
+	p.Votes = 0
+	for _, o := range p.Options() {
+		p.Results = append(p.Results, PollResult{Vote: o.Vote, Text: o.Text, Votes: counts[o.Vote]})
+		p.Votes += counts[o.Vote]
+	}
+}
+
+// PollOption is an option beyond yae and nay in cl_poll_options. Vote is
+// the value stored in cl2004_poll_votes.
+type PollOption struct {
+	PollId int64  `gorm:"column:poll_id;primaryKey;autoIncrement:false" json:"-"`
+	Vote   int    `gorm:"column:vote;primaryKey;autoIncrement:false" json:"vote"`
+	Text   string `gorm:"column:text;size:50;not null" json:"text"`
+}
+
+func (PollOption) TableName() string {
+	return "cl_poll_options"
+}
+
+// PollResult is the number of votes for one option
+type PollResult struct {
+	Vote  int    `json:"vote"`
+	Text  string `json:"text"`
+	Votes int64  `json:"votes"`
+}
+
+// PollVote is a vote in cl2004_poll_votes. Old votes were limited per host
+// and have no member; new votes are limited to one per member.
+type PollVote struct {
+	Id           int64      `gorm:"column:id;primaryKey;autoIncrement" json:"-"`
+	PollId       int64      `gorm:"column:poll_ID;uniqueIndex:uq_poll_member" json:"poll_id"`
+	MemberNumber *int64     `gorm:"column:member_number;uniqueIndex:uq_poll_member" json:"member_number"`
+	Vote         int        `gorm:"column:vote" json:"vote"`
+	Host         string     `gorm:"column:host" json:"-"`
+	CreatedAt    *time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (PollVote) TableName() string {
+	return "cl2004_poll_votes"
+}
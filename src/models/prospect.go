@@ -1,10 +1,23 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// Prospect statuses. A promoted prospect is kept with the member marker.
+const (
+	StatusSuspect  = "S"
+	StatusProspect = "P"
+	StatusPromoted = "#"
+)
+
+var (
+	ErrNotProspect       = errors.New("only prospects can be promoted")
+	ErrMemberNumberTaken = errors.New("member number already taken")
+)
+
 //swagger:response Prospect
 type Prospect struct {
 	Id      int64  `json:"id"`
@@ -20,13 +33,6 @@ func (Prospect) TableName() string {
   return "cl2007_prospects"
 }
 
-type ProspectLite struct {
-	Id      int64  `json:"id"`
-	Status  string `json:"status"`
-	Number  int64  `json:"number"`
-	History string `json:"history"`
-}
-
 func (p Prospect) Fmt() string {
 	s := make([]string, 0)
 	s = addI(s, "Id", p.Id)
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ArrReminder is a reminder sent to a member before an arr, kept so that it
+// is sent only once, also across restarts. Offset is how many minutes before
+// the start the reminder is for.
+type ArrReminder struct {
+	ArrId        int64     `gorm:"column:arr_id;primaryKey;autoIncrement:false" json:"arr_id"`
+	MemberNumber int64     `gorm:"column:member_number;primaryKey;autoIncrement:false" json:"member_number"`
+	Offset       int       `gorm:"column:offset_minutes;primaryKey;autoIncrement:false" json:"offset_minutes"`
+	SentAt       time.Time `gorm:"column:sent_at" json:"sent_at"`
+}
+
+func (ArrReminder) TableName() string {
+	return "cl_arr_reminders"
+}
+
+// ReminderOptOut is a member who does not want reminders of arr
+type ReminderOptOut struct {
+	MemberNumber int64     `gorm:"column:member_number;primaryKey;autoIncrement:false" json:"member_number"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (ReminderOptOut) TableName() string {
+	return "cl_reminder_optouts"
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrFilterNameTaken is returned when the member already has a filter with
+// the name
+var ErrFilterNameTaken = errors.New("filter name already taken")
+
+// InvalidFilterError is an RSQL query, sort or filter name given by the
+// client that cannot be used
+type InvalidFilterError struct {
+	Err error
+}
+
+func (e *InvalidFilterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidFilterError) Unwrap() error {
+	return e.Err
+}
+
+//swagger:response SavedFilter
+type SavedFilter struct {
+	Id           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	MemberNumber int64     `gorm:"column:member_number;not null;uniqueIndex:uq_member_name" json:"member_number"`
+	Name         string    `gorm:"column:name;size:64;not null;uniqueIndex:uq_member_name" json:"name"`
+	Query        string    `gorm:"column:query;type:text;not null" json:"query"`
+	Sort         string    `gorm:"column:sort;size:255" json:"sort"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"column:updated_at" json:"updated_at"`
+
+	// Computed from Shares, 0 means shared with all members
+	SharedWith []int64 `gorm:"-" json:"shared_with"`
+
+	// Relationships
+	Shares []SavedFilterShare `gorm:"foreignKey:FilterId" json:"-"`
+}
+
+func (SavedFilter) TableName() string {
+	return "saved_filters"
+}
+
+// SavedFilterShare grants a member read access to a saved filter
+// If member_number = 0: shared with every member
+type SavedFilterShare struct {
+	FilterId     int64 `gorm:"column:filter_id;primaryKey" json:"filter_id"`
+	MemberNumber int64 `gorm:"column:member_number;primaryKey" json:"member_number"`
+}
+
+func (SavedFilterShare) TableName() string {
+	return "saved_filter_shares"
+}
+
+// VisibleTo tells if the member owns the filter or it is shared with them
+func (f SavedFilter) VisibleTo(memberNumber int64) bool {
+	if f.MemberNumber == memberNumber {
+		return true
+	}
+	for _, s := range f.Shares {
+		if s.MemberNumber == 0 || s.MemberNumber == memberNumber {
+			return true
+		}
+	}
+	return false
+}
+
+func (f SavedFilter) Fmt() string {
+	s := make([]string, 0)
+	s = addI(s, "Id", f.Id)
+	s = addI(s, "MemberNumber", f.MemberNumber)
+	s = addS(s, "Name", f.Name)
+	s = addS(s, "Query", f.Query)
+	s = addS(s, "Sort", f.Sort)
+	return fmt.Sprintf("SavedFilter{%s}", strings.Join(s, ", "))
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrSuploggClosed = errors.New("suplogg is closed")
+
+// Suplogg is the drinking log of an arr in suplogg_arr. The id is the id of
+// the arr in cl2015_arrsidan, so an arr has at most one suplogg.
+//
+//swagger:response Suplogg
+type Suplogg struct {
+	Id        int64      `gorm:"column:ID;primaryKey;autoIncrement:false" json:"id"`
+	Name      string     `gorm:"column:Name" json:"name"`
+	StartedBy int64      `gorm:"column:started_by" json:"started_by"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"column:Timestamp" json:"updated_at"`
+	ClosedAt  *time.Time `gorm:"column:closed_at" json:"closed_at"`
+	// EntryId is the kommentar written with the final tally, if any
+	EntryId *int64 `gorm:"column:entry_id" json:"entry_id"`
+
+	Deltagare []SuploggDeltagare `gorm:"foreignKey:Arr" json:"deltagare"`
+}
+
+func (Suplogg) TableName() string {
+	return "suplogg_arr"
+}
+
+func (s Suplogg) Fmt() string {
+	str := make([]string, 0)
+	str = addI(str, "Id", s.Id)
+	str = addS(str, "Name", s.Name)
+	str = addI(str, "StartedBy", s.StartedBy)
+	return fmt.Sprintf("Suplogg{%s}", strings.Join(str, ", "))
+}
+
+// Total is the sum of enheter of all participants
+func (s Suplogg) Total() int64 {
+	var total int64
+	for _, d := range s.Deltagare {
+		total += d.Enheter
+	}
+	return total
+}
+
+// SuploggDeltagare is one participant of a suplogg in suplogg_deltagare.
+// Name is the signature of a member, e.g. "#8", or the name of a guest.
+type SuploggDeltagare struct {
+	Arr       int64     `gorm:"column:Arr;primaryKey;autoIncrement:false" json:"-"`
+	Name      string    `gorm:"column:Name;primaryKey;size:50" json:"name"`
+	Enheter   int64     `gorm:"column:Enheter;not null;default:0" json:"enheter"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (SuploggDeltagare) TableName() string {
+	return "suplogg_deltagare"
+}
+
+// SuploggResult is a place on a leaderboard. Arr is the number of suploggs
+// counted, and is only set on the all-time leaderboard.
+type SuploggResult struct {
+	Rank    int    `json:"rank"`
+	Name    string `json:"name"`
+	Enheter int64  `json:"enheter"`
+	Arr     int64  `json:"arr,omitempty"`
+}
+
+// RankSuplogg ranks results already ordered by enheter, highest first.
+// Participants with the same number of enheter share a place.
+func RankSuplogg(results []SuploggResult) []SuploggResult {
+	for i := range results {
+		if i > 0 && results[i].Enheter == results[i-1].Enheter {
+			results[i].Rank = results[i-1].Rank
+		} else {
+			results[i].Rank = i + 1
+		}
+	}
+	return results
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Visit is one logged request in the cl_visitors table. Comment holds the
+// normalised endpoint, e.g. "GET /db/entries/{id}". Host is the reverse DNS
+// name of Ip, only looked up when full IPs are stored.
+//swagger:response Visit
+type Visit struct {
+	Date    string    `gorm:"column:date;type:date" json:"date"`
+	Time    string    `gorm:"column:time;type:time" json:"time"`
+	Ip      *string   `gorm:"column:ip" json:"ip"`
+	Host    *string   `gorm:"column:host" json:"host"`
+	Comment string    `gorm:"column:comment" json:"comment"`
+	Sig     *string   `gorm:"column:sig" json:"sig"`
+	Ts      time.Time `gorm:"column:ts" json:"ts"`
+	Ua      *string   `gorm:"column:ua" json:"ua"`
+}
+
+func (Visit) TableName() string {
+	return "cl_visitors"
+}
+
+func (v Visit) Fmt() string {
+	s := make([]string, 0)
+	s = addS(s, "Comment", v.Comment)
+	s = addSp(s, "Sig", v.Sig)
+	s = addSp(s, "Ip", v.Ip)
+	return fmt.Sprintf("Visit{%s, Ts: %s}", strings.Join(s, ", "), v.Ts.Format(time.RFC3339))
+}
+
+// DailyVisitors counts the unique visitors (member, or IP when anonymous)
+// and requests of a day
+//swagger:response DailyVisitors
+type DailyVisitors struct {
+	Date     string `json:"date"`
+	Unique   int64  `json:"unique"`
+	Requests int64  `json:"requests"`
+}
+
+//swagger:response UserAgentCount
+type UserAgentCount struct {
+	UserAgent string `json:"user_agent"`
+	Requests  int64  `json:"requests"`
+}
+
+//swagger:response EndpointUsage
+type EndpointUsage struct {
+	Sig      string `json:"sig"`
+	Endpoint string `json:"endpoint"`
+	Requests int64  `json:"requests"`
+}
@@ -0,0 +1,144 @@
+package presence
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventType tells whether a member came online or went offline
+type EventType string
+
+const (
+	Online  EventType = "online"
+	Offline EventType = "offline"
+)
+
+// Event is published when a member's presence changes
+type Event struct {
+	Type         EventType `json:"type"`
+	MemberNumber int64     `json:"number"`
+	At           time.Time `json:"at"`
+}
+
+// Presence is one online member
+type Presence struct {
+	MemberNumber int64     `json:"number"`
+	OnlineSince  time.Time `json:"online_since"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// SubscriberBuffer is the number of events buffered per subscriber. Events
+// for subscribers that do not keep up are dropped.
+var SubscriberBuffer = 32
+
+// Tracker keeps presence in memory. Members are online from their first
+// authenticated request until timeout has passed without another.
+type Tracker struct {
+	timeout time.Duration
+	now     func() time.Time
+
+	mu          sync.Mutex
+	online      map[int64]*Presence
+	subscribers map[chan Event]struct{}
+}
+
+func NewTracker(timeout time.Duration) *Tracker {
+	return &Tracker{
+		timeout:     timeout,
+		now:         time.Now,
+		online:      make(map[int64]*Presence),
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Touch marks the member as seen now
+func (t *Tracker) Touch(memberNumber int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if p, ok := t.online[memberNumber]; ok {
+		p.LastSeen = now
+		return
+	}
+	t.online[memberNumber] = &Presence{MemberNumber: memberNumber, OnlineSince: now, LastSeen: now}
+	t.publish(Event{Type: Online, MemberNumber: memberNumber, At: now})
+}
+
+// Online returns the members currently online ordered by member number
+func (t *Tracker) Online() []Presence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire()
+	list := make([]Presence, 0, len(t.online))
+	for _, p := range t.online {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].MemberNumber < list[j].MemberNumber
+	})
+	return list
+}
+
+// Subscribe returns a channel of presence events and a function that
+// cancels the subscription
+func (t *Tracker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, SubscriberBuffer)
+
+	t.mu.Lock()
+	t.subscribers[ch] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.subscribers, ch)
+			t.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Expire removes members that timed out, publishing offline events
+func (t *Tracker) Expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+}
+
+// StartExpiryJob expires members periodically so that offline events are
+// published without waiting for a reader
+func (t *Tracker) StartExpiryJob(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			t.Expire()
+		}
+	}()
+	slog.Info("presence expiry job started", slog.Duration("interval", interval))
+}
+
+// expire must be called with mu held
+func (t *Tracker) expire() {
+	now := t.now()
+	for number, p := range t.online {
+		if now.Sub(p.LastSeen) >= t.timeout {
+			delete(t.online, number)
+			t.publish(Event{Type: Offline, MemberNumber: number, At: p.LastSeen.Add(t.timeout)})
+		}
+	}
+}
+
+// publish must be called with mu held
+func (t *Tracker) publish(e Event) {
+	for ch := range t.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC)
+	tracker := NewTracker(5 * time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	events, cancel := tracker.Subscribe()
+	defer cancel()
+
+	tracker.Touch(8)
+	now = now.Add(time.Minute)
+	tracker.Touch(3)
+	tracker.Touch(8)
+
+	online := tracker.Online()
+	assert.Len(t, online, 2)
+	assert.Equal(t, int64(3), online[0].MemberNumber)
+	assert.Equal(t, int64(8), online[1].MemberNumber)
+	assert.Equal(t, now, online[1].LastSeen)
+	assert.Equal(t, now.Add(-time.Minute), online[1].OnlineSince)
+
+	// Only coming online is an event, not every request
+	assert.Equal(t, Event{Type: Online, MemberNumber: 8, At: now.Add(-time.Minute)}, <-events)
+	assert.Equal(t, Event{Type: Online, MemberNumber: 3, At: now}, <-events)
+	assert.Len(t, events, 0)
+
+	now = now.Add(4*time.Minute + 30*time.Second)
+	tracker.Touch(3)
+	now = now.Add(30 * time.Second)
+	tracker.Expire()
+
+	online = tracker.Online()
+	assert.Len(t, online, 1)
+	assert.Equal(t, int64(3), online[0].MemberNumber)
+	e := <-events
+	assert.Equal(t, Offline, e.Type)
+	assert.Equal(t, int64(8), e.MemberNumber)
+}
+
+func TestTracker_SlowSubscriber(t *testing.T) {
+	tracker := NewTracker(time.Minute)
+	events, cancel := tracker.Subscribe()
+
+	for i := 0; i < SubscriberBuffer+10; i++ {
+		tracker.Touch(int64(i))
+	}
+	assert.Len(t, events, SubscriberBuffer)
+
+	cancel()
+	cancel()
+	tracker.Touch(1000)
+}
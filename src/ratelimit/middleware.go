@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+// Limiter attaches per route limits backed by a Store
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Key identifies the client: the member number when authenticated, otherwise
+// the client IP. Must run after RequireAuth/OptionalAuth for members to be
+// recognised.
+func Key(r *http.Request) string {
+	if claims := auth.GetClaims(r); claims != nil {
+		return fmt.Sprintf("member:%d", claims.MemberNumber)
+	}
+	return "ip:" + ru.ClientIP(r)
+}
+
+// Limit returns a middleware enforcing limit on the route called name.
+// Requests over the limit get 429 with Retry-After. If the store fails the
+// request is let through.
+func (l *Limiter) Limit(name string, limit Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit.Requests <= 0 || limit.Window <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := Key(r)
+			res, err := l.store.Take(r.Context(), name+"|"+key, limit)
+			if err != nil {
+				slog.Warn(ru.GetRequestId(r), "rate limit store failed", err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			if !res.Allowed {
+				seconds := int(math.Ceil(res.RetryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				slog.Info(ru.GetRequestId(r), "rate limited", name, "key", key)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				http.Error(w, `{"error":"rate limit exceeded"}`, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_FixedWindow(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Window: time.Minute}
+
+	res, _ := store.Take(context.Background(), "a", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	res, _ = store.Take(context.Background(), "a", limit)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(20 * time.Second)
+	res, _ = store.Take(context.Background(), "a", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 40*time.Second, res.RetryAfter)
+
+	// Other keys have their own window
+	res, _ = store.Take(context.Background(), "b", limit)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(40 * time.Second)
+	res, _ = store.Take(context.Background(), "a", limit)
+	assert.True(t, res.Allowed)
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore())
+	handler := limiter.Limit("test", Limit{Requests: 1, Window: time.Hour})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/db/entries", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNoContent, request("10.0.0.1:1234").Code)
+
+	rec := request("10.0.0.1:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3600", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusNoContent, request("10.0.0.2:1234").Code)
+}
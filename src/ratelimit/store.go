@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests requests per Window. A limit with Requests <= 0 is
+// unlimited.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Result is the outcome of counting one request against a limit
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store keeps the counters. Implementations must be safe for concurrent use;
+// a shared implementation (e.g. Redis INCR+EXPIRE) lets several instances
+// enforce the same limits.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type window struct {
+	end   time.Time
+	count int
+}
+
+// MemoryStore is a fixed window Store local to this process
+type MemoryStore struct {
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.end) {
+		w = &window{end: now.Add(limit.Window)}
+		s.windows[key] = w
+	}
+
+	if w.count >= limit.Requests {
+		return Result{Allowed: false, RetryAfter: w.end.Sub(now)}, nil
+	}
+	w.count++
+	return Result{Allowed: true, Remaining: limit.Requests - w.count}, nil
+}
+
+// sweep drops ended windows at most once a minute so that the map does not
+// grow with every client ever seen. Must be called with mu held.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, w := range s.windows {
+		if !now.Before(w.end) {
+			delete(s.windows, key)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/calendar"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/mailer"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// maxArrs is the most arr reminded of in one run
+const maxArrs = 500
+
+// defaultInterval is used when Start is given no valid interval
+const defaultInterval = time.Minute
+
+// Store is what the scheduler needs of the database
+type Store interface {
+	ReadArrs(take int, skip int, filter models.ArrFilter) ([]models.Arr, error)
+	ReadMemberByNumber(number int64) (*models.Member, error)
+	ReadReminderOptOut(memberNumber int64) (bool, error)
+	ReadArrReminders(arrId int64) ([]models.ArrReminder, error)
+	ClaimArrReminder(reminder *models.ArrReminder) (bool, error)
+	DeleteArrReminder(reminder *models.ArrReminder) error
+}
+
+// Scheduler mails members who answered yes or maybe to an arr before it
+// starts. A reminder is due when less than its offset remains. If several
+// are due, e.g. after downtime, only the one closest to the start is sent.
+// Sent reminders are stored, so each is sent once also across restarts.
+// Reminders are only mailed. There is no push, since the devices in
+// cl2014_gcm are registered with GCM, which has been shut down.
+type Scheduler struct {
+	db      Store
+	mail    mailer.Sender
+	offsets []time.Duration
+	url     string
+	now     func() time.Time
+}
+
+func NewScheduler(db Store, mail mailer.Sender, offsets []time.Duration, url string) *Scheduler {
+	sorted := append([]time.Duration(nil), offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &Scheduler{db: db, mail: mail, offsets: sorted, url: url, now: time.Now}
+}
+
+// OffsetsFromConfig returns the configured offsets, skipping those <= 0
+func OffsetsFromConfig(c *config.ReminderConfiguration) []time.Duration {
+	var offsets []time.Duration
+	for _, m := range c.OffsetsMinutes {
+		if m > 0 {
+			offsets = append(offsets, time.Duration(m)*time.Minute)
+		}
+	}
+	return offsets
+}
+
+// Start runs the scheduler every interval, or every minute if interval <= 0
+func (s *Scheduler) Start(interval time.Duration) {
+	if len(s.offsets) == 0 {
+		return
+	}
+	if interval <= 0 {
+		slog.Warn("invalid arr reminder interval, using the default",
+			slog.Duration("interval", interval), slog.Duration("default", defaultInterval))
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for ; true; <-ticker.C {
+			n, err := s.Run()
+			if err != nil {
+				slog.Error("failed to send arr reminders", slog.String("error", err.Error()))
+			}
+			if n > 0 {
+				slog.Info("sent arr reminders", slog.Int("count", n))
+			}
+		}
+	}()
+	slog.Info("arr reminder job started", slog.Duration("interval", interval))
+}
+
+// Run sends the reminders due now and returns the number sent. Failing
+// reminders are logged and tried again on the next run.
+func (s *Scheduler) Run() (int, error) {
+	if len(s.offsets) == 0 {
+		return 0, nil
+	}
+	now := s.now()
+	to := now.Add(s.offsets[len(s.offsets)-1])
+	arrs, err := s.db.ReadArrs(maxArrs, 0, models.ArrFilter{From: &now, To: &to, Now: now})
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int
+	for _, arr := range arrs {
+		offset, ok := s.due(arr, now)
+		if !ok {
+			continue
+		}
+		done, err := s.sentTo(arr.Id, offset)
+		if err != nil {
+			slog.Error("failed to read arr reminders", slog.Int64("arr", arr.Id), slog.String("error", err.Error()))
+			continue
+		}
+		for _, a := range arr.Attendance {
+			if (a.Status != models.AttendanceYes && a.Status != models.AttendanceMaybe) || done[a.MemberNumber] {
+				continue
+			}
+			ok, err := s.remind(arr, a, offset, now)
+			if err != nil {
+				slog.Error("failed to send arr reminder",
+					slog.Int64("arr", arr.Id), slog.Int64("member", a.MemberNumber), slog.String("error", err.Error()))
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	return sent, nil
+}
+
+// due returns the smallest offset that is more than the time left to the
+// start of arr
+func (s *Scheduler) due(arr models.Arr, now time.Time) (time.Duration, bool) {
+	if arr.StartsAt == nil {
+		return 0, false
+	}
+	left := arr.StartsAt.Sub(now)
+	if left <= 0 {
+		return 0, false
+	}
+	for _, o := range s.offsets {
+		if left < o {
+			return o, true
+		}
+	}
+	return 0, false
+}
+
+// sentTo returns the members already reminded of the arr at the offset
+func (s *Scheduler) sentTo(arrId int64, offset time.Duration) (map[int64]bool, error) {
+	reminders, err := s.db.ReadArrReminders(arrId)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(reminders))
+	for _, r := range reminders {
+		if r.Offset == int(offset/time.Minute) {
+			done[r.MemberNumber] = true
+		}
+	}
+	return done, nil
+}
+
+// remind mails one member unless they opted out, are no longer a member,
+// have no address or were already reminded. The reminder is claimed before it is sent, and released
+// if sending fails.
+func (s *Scheduler) remind(arr models.Arr, a models.ArrAttendance, offset time.Duration, now time.Time) (bool, error) {
+	optOut, err := s.db.ReadReminderOptOut(a.MemberNumber)
+	if err != nil || optOut {
+		return false, err
+	}
+	member, err := s.db.ReadMemberByNumber(a.MemberNumber)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if member.Isvalid == nil || !*member.Isvalid || member.Email == nil || strings.TrimSpace(*member.Email) == "" {
+		return false, nil
+	}
+
+	reminder := models.ArrReminder{ArrId: arr.Id, MemberNumber: a.MemberNumber, Offset: int(offset / time.Minute), SentAt: now}
+	claimed, err := s.db.ClaimArrReminder(&reminder)
+	if err != nil || !claimed {
+		return false, err
+	}
+	if err := s.mail.Send(s.message(arr, a.Status, member)); err != nil {
+		if err := s.db.DeleteArrReminder(&reminder); err != nil {
+			slog.Error("failed to release arr reminder", slog.Int64("arr", arr.Id), slog.String("error", err.Error()))
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Scheduler) message(arr models.Arr, status models.AttendanceStatus, member *models.Member) mailer.Message {
+	namn := "An arr"
+	if arr.Namn != nil && *arr.Namn != "" {
+		namn = *arr.Namn
+	}
+	when := arr.StartsAt.In(calendar.Location).Format("Monday 2 January 15:04")
+	if arr.StartDate != nil {
+		if _, allDay, err := calendar.ParseStartDate(*arr.StartDate); err == nil && allDay {
+			when = arr.StartsAt.In(calendar.Location).Format("Monday 2 January")
+		}
+	}
+	answer := "are coming"
+	if status == models.AttendanceMaybe {
+		answer = "might come"
+	}
+	sep := "?"
+	if strings.Contains(s.url, "?") {
+		sep = "&"
+	}
+
+	var b strings.Builder
+	name := ""
+	if member.Name != nil {
+		name = " " + *member.Name
+	}
+	fmt.Fprintf(&b, "Hi%s,\n\n", name)
+	fmt.Fprintf(&b, "%s starts %s", namn, when)
+	if arr.Plats != nil && *arr.Plats != "" {
+		fmt.Fprintf(&b, " at %s", *arr.Plats)
+	}
+	fmt.Fprintf(&b, ". You answered that you %s.\n\n", answer)
+	fmt.Fprintf(&b, "%s%sid=%d\n\n", s.url, sep, arr.Id)
+	b.WriteString("You can turn these reminders off in your settings.\n")
+
+	return mailer.Message{
+		To:      []string{*member.Email},
+		Subject: "Reminder: " + namn + " starts " + when,
+		Body:    b.String(),
+	}
+}
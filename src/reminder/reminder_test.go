@@ -0,0 +1,148 @@
+package reminder
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data/commondb"
+	"github.com/sebastiw/sidan-backend/src/data/commondb/commondbtest"
+	"github.com/sebastiw/sidan-backend/src/mailer"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type recordingSender struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (s *recordingSender) Send(m mailer.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func str(s string) *string { return &s }
+
+func newReminderDB(t *testing.T) *commondb.CommonDatabase {
+	cdb := commondbtest.NewDB(t, &models.Arr{}, &models.ArrAttendance{}, &models.Hetsa{},
+		&models.Member{}, &models.ArrReminder{}, &models.ReminderOptOut{})
+
+	valid := true
+	for _, m := range []models.Member{
+		{Number: 8, Name: str("Åtta"), Email: str("8@example.com"), Isvalid: &valid},
+		{Number: 9, Name: str("Nio"), Email: str("9@example.com"), Isvalid: &valid},
+		{Number: 10, Name: str("Tio"), Email: str("10@example.com"), Isvalid: &valid},
+		{Number: 11, Name: str("Elva"), Isvalid: &valid},
+	} {
+		require.NoError(t, cdb.DB.Create(&m).Error)
+	}
+	return cdb
+}
+
+func TestScheduler(t *testing.T) {
+	cdb := newReminderDB(t)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	starts := now.Add(30 * time.Hour)
+
+	arr, err := cdb.CreateArr(&models.Arr{Namn: str("Sittning"), Plats: str("Kåren"), StartDate: str("2026-10-19 20:00")})
+	require.NoError(t, err)
+	require.NoError(t, cdb.DB.Model(arr).Update("starts_at", starts).Error)
+	for number, status := range map[int64]models.AttendanceStatus{
+		8: models.AttendanceYes, 9: models.AttendanceMaybe, 10: models.AttendanceHetsad, 11: models.AttendanceYes,
+	} {
+		_, err := cdb.SetArrAttendance(arr.Id, number, status)
+		require.NoError(t, err)
+	}
+
+	sender := &recordingSender{}
+	newScheduler := func() *Scheduler {
+		s := NewScheduler(cdb, sender, []time.Duration{2 * time.Hour, 24 * time.Hour}, "https://example.com/arr")
+		s.now = func() time.Time { return now }
+		return s
+	}
+	s := newScheduler()
+
+	// Nothing is due 30 hours before
+	n, err := s.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Yes and maybe with an address get the 24h reminder, hetsad do not
+	now = starts.Add(-23 * time.Hour)
+	n, err = s.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, sender.sent, 2)
+	to := []string{sender.sent[0].To[0], sender.sent[1].To[0]}
+	assert.ElementsMatch(t, []string{"8@example.com", "9@example.com"}, to)
+	assert.Contains(t, sender.sent[0].Subject, "Sittning")
+	assert.Contains(t, sender.sent[0].Body, "https://example.com/arr?id=")
+
+	// Sent reminders are remembered, also by a new scheduler
+	now = now.Add(time.Hour)
+	n, err = newScheduler().Run()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Opted out members do not get the 2h reminder
+	require.NoError(t, cdb.SetReminderOptOut(9, true))
+	now = starts.Add(-time.Hour)
+	n, err = s.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"8@example.com"}, sender.sent[2].To)
+
+	// Nothing after the start
+	now = starts.Add(time.Minute)
+	n, err = s.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestScheduler_Retry(t *testing.T) {
+	cdb := newReminderDB(t)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	starts := now.Add(time.Hour)
+
+	arr, err := cdb.CreateArr(&models.Arr{Namn: str("Pub")})
+	require.NoError(t, err)
+	require.NoError(t, cdb.DB.Model(arr).Update("starts_at", starts).Error)
+	_, err = cdb.SetArrAttendance(arr.Id, 8, models.AttendanceYes)
+	require.NoError(t, err)
+
+	sender := &recordingSender{err: errors.New("smtp down")}
+	s := NewScheduler(cdb, sender, []time.Duration{24 * time.Hour, 2 * time.Hour}, "https://example.com/arr")
+	s.now = func() time.Time { return now }
+
+	n, err := s.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// After downtime only the reminder closest to the start is sent
+	sender.err = nil
+	n, err = s.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	reminders, err := cdb.ReadArrReminders(arr.Id)
+	require.NoError(t, err)
+	require.Len(t, reminders, 1)
+	assert.Equal(t, 120, reminders[0].Offset)
+	assert.Equal(t, now, reminders[0].SentAt.UTC())
+}
+
+func TestScheduler_UnknownMember(t *testing.T) {
+	cdb := newReminderDB(t)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	arr := models.Arr{Id: 1, Namn: str("Pub"), StartsAt: &now}
+
+	s := NewScheduler(cdb, &recordingSender{}, []time.Duration{time.Hour}, "https://example.com/arr")
+	ok, err := s.remind(arr, models.ArrAttendance{ArrId: 1, MemberNumber: 404, Status: models.AttendanceYes}, time.Hour, now)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
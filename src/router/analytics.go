@@ -0,0 +1,121 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/data"
+)
+
+// analyticsDefaultDays is the range used when from is not given
+const analyticsDefaultDays = 30
+
+func NewAnalyticsHandler(db data.Database) AnalyticsHandler {
+	return AnalyticsHandler{db}
+}
+
+type AnalyticsHandler struct {
+	db data.Database
+}
+
+// readDailyVisitorsHandler returns unique visitors and requests per day
+// GET /admin/analytics/visitors?from=2026-10-01&to=2026-10-18
+func (ah AnalyticsHandler) readDailyVisitorsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := analyticsRange(w, r)
+	if !ok {
+		return
+	}
+
+	days, err := ah.db.ReadDailyVisitors(from, to)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(days)
+}
+
+// readUserAgentsHandler returns the most common user agents
+// GET /admin/analytics/user-agents?from&to&take=20
+func (ah AnalyticsHandler) readUserAgentsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := analyticsRange(w, r)
+	if !ok {
+		return
+	}
+	take := MakeDefaultInt(r, "take", "20")
+
+	agents, err := ah.db.ReadTopUserAgents(from, to, take)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(agents)
+}
+
+// readEndpointUsageHandler returns request counts per member and endpoint
+// GET /admin/analytics/endpoints?from&to&member=8&take=100
+func (ah AnalyticsHandler) readEndpointUsageHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := analyticsRange(w, r)
+	if !ok {
+		return
+	}
+	take := MakeDefaultInt(r, "take", "100")
+
+	var sig string
+	if member := MakeDefaultInt(r, "member", "0"); member > 0 {
+		sig = fmt.Sprintf("#%d", member)
+	}
+
+	usage, err := ah.db.ReadEndpointUsage(from, to, sig, take)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// readCacheStatsHandler returns the counters of the database cache, 404 if
+// the cache is disabled
+// GET /admin/cache
+func (ah AnalyticsHandler) readCacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	cache, ok := ah.db.(*data.CachedDatabase)
+	if !ok {
+		http.Error(w, `{"error":"cache is disabled"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cache.Stats())
+}
+
+// analyticsRange reads from and to, defaulting to the last 30 days. Writes
+// 400 and returns false if either is invalid.
+func analyticsRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	from, err := parseExportTime(r.URL.Query().Get("from"), false)
+	if err != nil {
+		http.Error(w, `{"error":"invalid from"}`, http.StatusBadRequest)
+		return from, from, false
+	}
+	to, err := parseExportTime(r.URL.Query().Get("to"), true)
+	if err != nil {
+		http.Error(w, `{"error":"invalid to"}`, http.StatusBadRequest)
+		return from, to, false
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -analyticsDefaultDays)
+	}
+	return from, to, true
+}
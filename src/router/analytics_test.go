@@ -0,0 +1,26 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/data"
+)
+
+func TestReadCacheStats(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewAnalyticsHandler(newFakeDatabase()).readCacheStatsHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/cache", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	NewAnalyticsHandler(data.NewCachedDatabase(newFakeDatabase(), 10, 0)).readCacheStatsHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/cache", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats data.CacheStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, data.CacheStats{}, stats)
+}
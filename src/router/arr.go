@@ -1,17 +1,26 @@
 package router
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/calendar"
+	"github.com/sebastiw/sidan-backend/src/config"
 	"github.com/sebastiw/sidan-backend/src/data"
 	"github.com/sebastiw/sidan-backend/src/models"
 	ru "github.com/sebastiw/sidan-backend/src/router_util"
+	"github.com/sebastiw/sidan-backend/src/views"
 )
 
 func NewArrHandler(db data.Database) ArrHandler {
@@ -31,28 +40,37 @@ func (ah ArrHandler) createArrHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(arr)
+	json.NewEncoder(w).Encode(views.NewArr(*arr, arrAudience(r, arr.Id)))
 }
 
+// readArrHandler returns an arr. Its place and attendance are only shown to
+// members and to guests with a token from its password.
+// GET /db/arr/{id}
 func (ah ArrHandler) readArrHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	arr, err := ah.db.ReadArr(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, `{"error":"arr not found"}`, http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(arr)
+	WriteJSONConditional(w, r, views.NewArr(*arr, arrAudience(r, arr.Id)), time.Time{})
 }
 
 func (ah ArrHandler) updateArrHandler(w http.ResponseWriter, r *http.Request) {
@@ -68,10 +86,11 @@ func (ah ArrHandler) updateArrHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(arr)
+	json.NewEncoder(w).Encode(views.NewArr(*arr, arrAudience(r, arr.Id)))
 }
 
 func (ah ArrHandler) deleteArrHandler(w http.ResponseWriter, r *http.Request) {
@@ -87,22 +106,252 @@ func (ah ArrHandler) deleteArrHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(arr)
+	json.NewEncoder(w).Encode(views.NewArr(*arr, arrAudience(r, arr.Id)))
 }
 
+// readAllArrHandler lists arr, newest first
+// GET /db/arr?take=20&skip=0
+// Optional: upcoming=true or past=true, from and to as RFC 3339 times or
+// dates (to includes the whole day), q as RSQL on namn, plats and organisator
 func (ah ArrHandler) readAllArrHandler(w http.ResponseWriter, r *http.Request) {
 	take := MakeDefaultInt(r, "take", "20")
 	skip := MakeDefaultInt(r, "skip", "0")
-	arrs, err := ah.db.ReadArrs(take, skip)
+
+	filter, err := parseArrFilter(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	arrs, err := ah.db.ReadArrs(take, skip, filter)
+	if err != nil {
+		var invalid *models.InvalidFilterError
+		if errors.As(err, &invalid) {
+			http.Error(w, fmt.Sprintf("invalid RSQL query: %v", err), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSONConditional(w, r, views.NewArrs(arrs, func(a models.Arr) views.Audience {
+		return arrAudience(r, a.Id)
+	}), time.Time{})
+}
+
+// rsvpArrHandler records the authenticated member's answer to an arr, yes by
+// default. A member can also hetsa another member, which does not replace an
+// answer that member already gave.
+// POST /db/arr/{id}/rsvp
+// Body (optional): {"status": "yes"|"maybe"} or {"status": "hetsad", "member": 8}
+func (ah ArrHandler) rsvpArrHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	req := struct {
+		Status models.AttendanceStatus `json:"status"`
+		Member int64                   `json:"member"`
+	}{Status: models.AttendanceYes}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	number := member.Number
+	switch req.Status {
+	case models.AttendanceYes, models.AttendanceMaybe:
+		if req.Member != 0 && req.Member != member.Number {
+			http.Error(w, `{"error":"only hetsad can be set for another member"}`, http.StatusBadRequest)
+			return
+		}
+	case models.AttendanceHetsad:
+		if req.Member == 0 {
+			http.Error(w, `{"error":"member required"}`, http.StatusBadRequest)
+			return
+		}
+		target, err := ah.db.ReadMemberByNumber(req.Member)
+		if err != nil || target == nil || target.Isvalid == nil || !*target.Isvalid {
+			http.Error(w, `{"error":"member not found"}`, http.StatusNotFound)
+			return
+		}
+		number = target.Number
+	default:
+		http.Error(w, `{"error":"status must be yes, maybe or hetsad"}`, http.StatusBadRequest)
+		return
+	}
+
+	arr, err := ah.db.SetArrAttendance(id, number, req.Status)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
 		return
 	}
+	if arr == nil {
+		http.Error(w, `{"error":"arr not found"}`, http.StatusNotFound)
+		return
+	}
 
+	slog.Info(ru.GetRequestId(r), "arr", arr.Fmt(), "member", number, "status", req.Status, "by", member.Number)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(arrs)
+	json.NewEncoder(w).Encode(views.NewArr(*arr, arrAudience(r, arr.Id)))
+}
+
+// unrsvpArrHandler removes the authenticated member's answer to an arr
+// DELETE /db/arr/{id}/rsvp
+func (ah ArrHandler) unrsvpArrHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	arr, err := ah.db.DeleteArrAttendance(id, member.Number)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if arr == nil {
+		http.Error(w, `{"error":"arr not found"}`, http.StatusNotFound)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "arr", arr.Fmt(), "member", member.Number, "status", "none")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views.NewArr(*arr, arrAudience(r, arr.Id)))
+}
+
+// unlockArrHandler lets a guest who knows the password of an arr see its
+// details. The returned token is only valid for reading that arr.
+// POST /db/arr/{id}/unlock
+// Body: {"losen": "..."}
+func (ah ArrHandler) unlockArrHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Losen string `json:"losen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Losen == "" {
+		http.Error(w, `{"error":"losen required"}`, http.StatusBadRequest)
+		return
+	}
+
+	arr, err := ah.db.ReadArr(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, `{"error":"arr not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	// Arr without a password cannot be unlocked
+	losen := deref(arr.Losen)
+	if losen == "" || subtle.ConstantTimeCompare([]byte(req.Losen), []byte(losen)) != 1 {
+		slog.Warn(ru.GetRequestId(r), "wrong arr password", arr.Fmt())
+		http.Error(w, `{"error":"wrong password"}`, http.StatusForbidden)
+		return
+	}
+
+	ttl := time.Duration(config.GetJWT().ArrTokenMinutes) * time.Minute
+	token, err := auth.GenerateArrJWT(arr.Id, ttl, config.GetJWTSecret())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "arr unlocked", arr.Fmt())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+		"scope":        auth.ArrScope(arr.Id),
+		"arr":          views.NewArr(*arr, views.Guest),
+	})
+}
+
+// parseArrFilter reads the filter query parameters of GET /db/arr
+func parseArrFilter(r *http.Request) (models.ArrFilter, error) {
+	query := r.URL.Query()
+	filter := models.ArrFilter{Query: query.Get("q"), Now: time.Now()}
+
+	var err error
+	if filter.Upcoming, err = queryBool(query, "upcoming"); err != nil {
+		return filter, err
+	}
+	if filter.Past, err = queryBool(query, "past"); err != nil {
+		return filter, err
+	}
+	if filter.Upcoming && filter.Past {
+		return filter, errors.New("upcoming and past are exclusive")
+	}
+	if filter.From, err = queryTime(query, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(query, "to", true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// queryBool is true for a parameter given without value
+func queryBool(query url.Values, name string) (bool, error) {
+	if !query.Has(name) {
+		return false, nil
+	}
+	value := query.Get(name)
+	if value == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+	return b, nil
+}
+
+// queryTime reads an RFC 3339 time or a date in the timezone of the arr.
+// With endOfDay a date means the end of that day.
+func queryTime(query url.Values, name string, endOfDay bool) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, calendar.Location)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date or an RFC 3339 time", name)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
 }
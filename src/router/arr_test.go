@@ -0,0 +1,176 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/calendar"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type rsvpDatabase struct {
+	*fakeDatabase
+	member int64
+	status models.AttendanceStatus
+}
+
+func (d *rsvpDatabase) SetArrAttendance(arrId int64, memberNumber int64, status models.AttendanceStatus) (*models.Arr, error) {
+	if arrId != 1 {
+		return nil, nil
+	}
+	d.member, d.status = memberNumber, status
+	return &models.Arr{Id: arrId, Attendance: []models.ArrAttendance{{ArrId: arrId, MemberNumber: memberNumber, Status: status}}}, nil
+}
+
+func (d *rsvpDatabase) DeleteArrAttendance(arrId int64, memberNumber int64) (*models.Arr, error) {
+	d.member, d.status = memberNumber, ""
+	return &models.Arr{Id: arrId}, nil
+}
+
+func rsvp(t *testing.T, db *rsvpDatabase, method string, id string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	token, err := auth.GenerateJWT(8, "member@example.com", []string{auth.WriteArrScope}, "test", []byte(testJWTSecret))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(method, "/db/arr/"+id+"/rsvp", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	req.Header.Set("Authorization", "Bearer "+token)
+	h := NewArrHandler(db)
+	handler := h.rsvpArrHandler
+	if method == http.MethodDelete {
+		handler = h.unrsvpArrHandler
+	}
+	rec := httptest.NewRecorder()
+	auth.NewMiddleware(db).RequireAuth(http.HandlerFunc(handler)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRsvpArrHandler(t *testing.T) {
+	db := &rsvpDatabase{fakeDatabase: newFakeDatabase()}
+	valid := true
+	db.members[1].Isvalid = &valid
+
+	rec := rsvp(t, db, http.MethodPost, "1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, int64(8), db.member)
+	assert.Equal(t, models.AttendanceYes, db.status)
+	assert.Contains(t, rec.Body.String(), `"attendance":[{"member_number":8,"status":"yes"`)
+
+	assert.Equal(t, http.StatusOK, rsvp(t, db, http.MethodPost, "1", `{"status":"maybe"}`).Code)
+	assert.Equal(t, models.AttendanceMaybe, db.status)
+
+	// Only hetsad can be set for someone else
+	assert.Equal(t, http.StatusBadRequest, rsvp(t, db, http.MethodPost, "1", `{"status":"yes","member":9}`).Code)
+	assert.Equal(t, http.StatusOK, rsvp(t, db, http.MethodPost, "1", `{"status":"hetsad","member":9}`).Code)
+	assert.Equal(t, int64(9), db.member)
+	assert.Equal(t, http.StatusNotFound, rsvp(t, db, http.MethodPost, "1", `{"status":"hetsad","member":77}`).Code)
+
+	assert.Equal(t, http.StatusBadRequest, rsvp(t, db, http.MethodPost, "1", `{"status":"kanske"}`).Code)
+	assert.Equal(t, http.StatusNotFound, rsvp(t, db, http.MethodPost, "2", "").Code)
+
+	assert.Equal(t, http.StatusOK, rsvp(t, db, http.MethodDelete, "1", "").Code)
+	assert.Equal(t, int64(8), db.member)
+	assert.Equal(t, models.AttendanceStatus(""), db.status)
+}
+
+func TestParseArrFilter(t *testing.T) {
+	parse := func(query string) (models.ArrFilter, error) {
+		return parseArrFilter(httptest.NewRequest(http.MethodGet, "/db/arr?"+query, nil))
+	}
+
+	filter, err := parse("upcoming&q=" + url.QueryEscape(`namn==*fest*`))
+	require.NoError(t, err)
+	assert.True(t, filter.Upcoming)
+	assert.Equal(t, `namn==*fest*`, filter.Query)
+
+	filter, err = parse("past=false&from=2026-10-01&to=2026-10-31")
+	require.NoError(t, err)
+	assert.False(t, filter.Past)
+	assert.True(t, time.Date(2026, 10, 1, 0, 0, 0, 0, calendar.Location).Equal(*filter.From))
+	assert.True(t, time.Date(2026, 11, 1, 0, 0, 0, 0, calendar.Location).Equal(*filter.To))
+
+	filter, err = parse("from=2026-10-01T18:00:00Z")
+	require.NoError(t, err)
+	assert.True(t, time.Date(2026, 10, 1, 18, 0, 0, 0, time.UTC).Equal(*filter.From))
+
+	for _, bad := range []string{"upcoming&past", "upcoming=kanske", "from=igår", "to=2026-13-01"} {
+		_, err := parse(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestUnlockArr(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	jwtConfig := config.GetJWT()
+	saved := *jwtConfig
+	jwtConfig.ArrTokenMinutes = 10
+	defer func() { *jwtConfig = saved }()
+	db := newFakeDatabase()
+	namn, plats, losen := "Fest", "Hubben", "hemligt"
+	db.arrs = map[int64]models.Arr{
+		3: {Id: 3, Namn: &namn, Plats: &plats, Losen: &losen},
+		4: {Id: 4, Namn: &namn, Plats: &plats},
+	}
+	ah := NewArrHandler(db)
+	mw := auth.NewMiddleware(db)
+
+	unlock := func(id string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rec := httptest.NewRecorder()
+		ah.unlockArrHandler(rec, req)
+		return rec
+	}
+	read := func(handler http.Handler, id string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusForbidden, unlock("3", `{"losen":"fel"}`).Code)
+	assert.Equal(t, http.StatusForbidden, unlock("4", `{"losen":"hemligt"}`).Code)
+	assert.Equal(t, http.StatusNotFound, unlock("5", `{"losen":"hemligt"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, unlock("3", `{}`).Code)
+
+	rec := unlock("3", `{"losen":"hemligt"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		AccessToken string                 `json:"access_token"`
+		Arr         map[string]interface{} `json:"arr"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Hubben", resp.Arr["plats"])
+	assert.NotContains(t, resp.Arr, "losen")
+
+	readArr := mw.OptionalAuth(http.HandlerFunc(ah.readArrHandler))
+	assert.NotContains(t, read(readArr, "3", "").Body.String(), "Hubben")
+	guest := read(readArr, "3", resp.AccessToken).Body.String()
+	assert.Contains(t, guest, "Hubben")
+	assert.NotContains(t, guest, "hemligt")
+	assert.NotContains(t, read(readArr, "4", resp.AccessToken).Body.String(), "Hubben", "token is for arr 3 only")
+
+	member, err := auth.GenerateJWT(8, "member@example.com", nil, "test", []byte(testJWTSecret))
+	require.NoError(t, err)
+	assert.Contains(t, read(readArr, "3", member).Body.String(), "hemligt")
+
+	requireAuth := mw.RequireAuth(http.HandlerFunc(ah.readArrHandler))
+	assert.Equal(t, http.StatusUnauthorized, read(requireAuth, "3", resp.AccessToken).Code)
+	assert.Equal(t, http.StatusNotFound, read(readArr, "5", "").Code)
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -51,8 +52,11 @@ func (ah ArticleHandler) readArticleHandler(w http.ResponseWriter, r *http.Reque
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(article)
+	var lastModified time.Time
+	if article.DateTime != nil {
+		lastModified = *article.DateTime
+	}
+	WriteJSONConditional(w, r, article, lastModified)
 }
 
 func (ah ArticleHandler) updateArticleHandler(w http.ResponseWriter, r *http.Request) {
@@ -103,6 +107,5 @@ func (ah ArticleHandler) readAllArticleHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(articles)
+	WriteJSONConditional(w, r, articles, latestArticleTime(articles))
 }
@@ -6,21 +6,27 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/sebastiw/sidan-backend/src/auth"
 	"github.com/sebastiw/sidan-backend/src/config"
 	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/mailer"
 	"github.com/sebastiw/sidan-backend/src/models"
 )
 
 // NewAuthHandler creates auth handlers with database access
 func NewAuthHandler(db data.Database) *AuthHandler {
-	return &AuthHandler{db: db}
+	return &AuthHandler{db: db, mail: mailer.NewSMTPSender(config.GetMail())}
 }
 
 type AuthHandler struct {
-	db data.Database
+	db   data.Database
+	mail mailer.Sender
+
+	// resets are the password reset mails being sent
+	resets sync.WaitGroup
 }
 
 // Login initiates OAuth2 flow
@@ -34,27 +40,38 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	authURL, status, err := h.beginWebFlow(provider, redirectURI, nil)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Redirect to provider
+	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+}
+
+// beginWebFlow stores a new auth state and returns the provider authorization
+// URL. linkMember is set when the flow links an identity to that member. On
+// failure the status and error are meant for the client.
+func (h *AuthHandler) beginWebFlow(provider string, redirectURI string, linkMember *int64) (string, int, error) {
 	// Get OAuth2 config for provider
 	oauth2Cfg, exists := config.Get().OAuth2[provider]
 	if !exists {
-		http.Error(w, "unknown provider", http.StatusBadRequest)
-		return
+		return "", http.StatusBadRequest, errors.New("unknown provider")
 	}
 
 	providerCfg, err := auth.GetProviderConfig(provider, oauth2Cfg.ClientID,
 		oauth2Cfg.ClientSecret, oauth2Cfg.RedirectURL, oauth2Cfg.Scopes)
 	if err != nil {
 		slog.Error("provider config failed", "provider", provider, "error", err)
-		http.Error(w, "provider configuration error", http.StatusInternalServerError)
-		return
+		return "", http.StatusInternalServerError, errors.New("provider configuration error")
 	}
 
 	// Generate PKCE
 	verifier, err := auth.GeneratePKCEVerifier()
 	if err != nil {
 		slog.Error("PKCE verifier generation failed", "error", err)
-		http.Error(w, "crypto error", http.StatusInternalServerError)
-		return
+		return "", http.StatusInternalServerError, errors.New("crypto error")
 	}
 	challenge := auth.GeneratePKCEChallenge(verifier)
 
@@ -64,27 +81,24 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Store state in database (10 min TTL)
 	authState := &models.AuthState{
-		ID:           state,
-		Provider:     provider,
-		Nonce:        nonce,
-		PKCEVerifier: verifier,
-		RedirectURI:  redirectURI,
-		ExpiresAt:    time.Now().Add(10 * time.Minute),
+		ID:               state,
+		Provider:         provider,
+		Nonce:            nonce,
+		PKCEVerifier:     verifier,
+		RedirectURI:      redirectURI,
+		ExpiresAt:        time.Now().Add(10 * time.Minute),
+		LinkMemberNumber: linkMember,
 	}
 
 	if err := h.db.CreateAuthState(authState); err != nil {
 		slog.Error("failed to store auth state", "error", err)
-		http.Error(w, "storage error", http.StatusInternalServerError)
-		return
+		return "", http.StatusInternalServerError, errors.New("storage error")
 	}
 
-	// Build authorization URL
-	authURL := providerCfg.GetAuthURL(state, challenge)
-
-	slog.Info("oauth2 login initiated", "provider", provider, "state", state[:8]+"...")
+	slog.Info("oauth2 login initiated", "provider", provider, "state", state[:8]+"...", "link", linkMember != nil)
 
-	// Redirect to provider
-	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+	// Build authorization URL
+	return providerCfg.GetAuthURL(state, challenge), http.StatusOK, nil
 }
 
 // Callback handles OAuth2 callback
@@ -134,7 +148,15 @@ func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !userInfo.EmailVerified {
+	// Flows started from /auth/identities/link add an identity instead
+	if authState.LinkMemberNumber != nil {
+		h.linkIdentity(w, r, authState, userInfo)
+		return
+	}
+
+	// Find member by linked identity, or else by email in cl2007_members
+	member, err := h.findMember(authState.Provider, userInfo)
+	if errors.Is(err, errEmailNotVerified) {
 		if authState.RedirectURI != "" {
 			redirectURL := authState.RedirectURI + "?error=email_not_verified&error_description=" + url.QueryEscape("email not verified with provider")
 			http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
@@ -143,9 +165,6 @@ func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "email not verified with provider", http.StatusForbidden)
 		return
 	}
-
-	// Find member by email in cl2007_members table
-	member, err := h.db.ReadMemberByEmail(userInfo.Email)
 	if err != nil {
 		slog.Error("failed to read member", "error", err)
 		http.Error(w, "database error", http.StatusInternalServerError)
@@ -404,12 +423,11 @@ func (h *AuthHandler) DevicePoll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !userInfo.EmailVerified {
+	member, err := h.findMember(authState.Provider, userInfo)
+	if errors.Is(err, errEmailNotVerified) {
 		http.Error(w, `{"error":"email not verified"}`, http.StatusForbidden)
 		return
 	}
-
-	member, err := h.db.ReadMemberByEmail(userInfo.Email)
 	if err != nil {
 		slog.Error("failed to read member", "error", err)
 		http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
@@ -490,12 +508,11 @@ func (h *AuthHandler) DeviceRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !userInfo.EmailVerified {
+	member, err := h.findMember(req.Provider, userInfo)
+	if errors.Is(err, errEmailNotVerified) {
 		http.Error(w, `{"error":"email not verified"}`, http.StatusForbidden)
 		return
 	}
-
-	member, err := h.db.ReadMemberByEmail(userInfo.Email)
 	if err != nil {
 		slog.Error("failed to read member", "error", err)
 		http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
@@ -599,7 +616,13 @@ func (h *AuthHandler) Token(w http.ResponseWriter, r *http.Request) {
 func getScopesForMemberType(member *models.Member) []string {
 	// All valid members get basic scopes
 	if member.Isvalid != nil && *member.Isvalid {
-		return []string{"write:email", "write:image", "write:member", "read:member", "modify:entry", "write:arr", "read:article", "write:article", "filtering", "write:apk"}
+		scopes := []string{"write:email", "write:image", "write:member", "read:member", "modify:entry", "write:arr", "read:article", "write:article", "filtering", "write:apk", "write:poll"}
+		// Administrators are listed by member number in the config. Only they
+		// may export all entries.
+		if config.IsAdmin(member.Number) {
+			scopes = append(scopes, "admin", "export:entry")
+		}
+		return scopes
 	}
 	// Inactive members get limited access
 	return []string{"read:member", "read:article"}
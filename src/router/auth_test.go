@@ -0,0 +1,31 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestGetScopesForMemberType(t *testing.T) {
+	admin := config.GetAdmin()
+	saved := *admin
+	admin.Members = []int64{9}
+	defer func() { *admin = saved }()
+
+	valid := true
+	scopes := getScopesForMemberType(&models.Member{Number: 8, Isvalid: &valid})
+	assert.Contains(t, scopes, auth.WriteArrScope)
+	assert.Contains(t, scopes, auth.WritePollScope)
+	assert.NotContains(t, scopes, auth.AdminScope)
+	assert.NotContains(t, scopes, auth.ExportEntryScope)
+
+	scopes = getScopesForMemberType(&models.Member{Number: 9, Isvalid: &valid})
+	assert.Contains(t, scopes, auth.AdminScope)
+	assert.Contains(t, scopes, auth.ExportEntryScope)
+
+	assert.Equal(t, []string{auth.ReadMemberScope, auth.ReadArticleScope}, getScopesForMemberType(&models.Member{Number: 9}))
+}
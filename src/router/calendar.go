@@ -0,0 +1,230 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/calendar"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+const arrFeedName = "arr.ics"
+
+func NewCalendarHandler(db data.Database) CalendarHandler {
+	return CalendarHandler{db}
+}
+
+type CalendarHandler struct {
+	db data.Database
+}
+
+// readArrCalendarHandler returns all arr as an iCalendar feed. If the request
+// is authenticated the events the member signed up for are marked.
+// GET /calendar/arr.ics
+func (ch CalendarHandler) readArrCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	var sig string
+	if member := auth.GetMember(r); member != nil {
+		sig = fmt.Sprintf("#%d", member.Number)
+	}
+	ch.writeArrCalendar(w, r, sig)
+}
+
+// readMemberArrCalendarHandler returns a personal feed authenticated by the
+// member's feed token, for calendar apps that cannot send a Bearer header.
+// GET /calendar/members/{number}/arr.ics?token=...
+func (ch CalendarHandler) readMemberArrCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	number, err := strconv.ParseInt(vars["number"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid member number"}`, http.StatusBadRequest)
+		return
+	}
+
+	valid, err := checkFeedSecret(ch.db, number, arrFeedName, r.URL.Query().Get("token"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		http.Error(w, `{"error":"invalid feed token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	member, err := ch.db.ReadMemberByNumber(number)
+	if err != nil || member.Isvalid == nil || !*member.Isvalid {
+		http.Error(w, `{"error":"member not found"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ch.writeArrCalendar(w, r, fmt.Sprintf("#%d", member.Number))
+}
+
+// readCalendarFeedHandler returns the subscription URL of the personal feed,
+// creating the feed token on first use
+// GET /calendar/feed
+func (ch CalendarHandler) readCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	ch.writeCalendarFeed(w, r, readFeedSecret)
+}
+
+// renewCalendarFeedHandler replaces the feed token, so that the former
+// subscription URL stops working, and returns the new URL
+// POST /calendar/feed
+func (ch CalendarHandler) renewCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	ch.writeCalendarFeed(w, r, renewFeedSecret)
+}
+
+// deleteCalendarFeedHandler revokes the feed token until the next
+// GET /calendar/feed
+// DELETE /calendar/feed
+func (ch CalendarHandler) deleteCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "revoke feed", arrFeedName, "member", member.Number)
+	if err := ch.db.DeleteFeedSecret(member.Number, arrFeedName); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ch CalendarHandler) writeCalendarFeed(w http.ResponseWriter, r *http.Request, secret func(data.Database, int64, string) (string, error)) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	token, err := secret(ch.db, member.Number, arrFeedName)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	path := fmt.Sprintf("/calendar/members/%d/%s?token=%s", member.Number, arrFeedName, token)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"url":    BaseURL(r) + path,
+		"webcal": "webcal://" + r.Host + path,
+	})
+}
+
+func (ch CalendarHandler) writeArrCalendar(w http.ResponseWriter, r *http.Request, sig string) {
+	take := MakeDefaultInt(r, "take", "500")
+	arrs, err := ch.db.ReadArrs(take, 0, models.ArrFilter{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	feed := calendar.Feed{
+		Name:  "Chalmers Losers arr",
+		Stamp: time.Now(),
+	}
+	for _, arr := range arrs {
+		event, ok := arrToEvent(arr, sig)
+		if !ok {
+			slog.Debug(ru.GetRequestId(r), "unparseable start_date", arr.Fmt())
+			continue
+		}
+		feed.Events = append(feed.Events, event)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="`+arrFeedName+`"`)
+	if err := calendar.WriteFeed(w, feed); err != nil {
+		slog.Warn(ru.GetRequestId(r), "failed to write calendar", err)
+	}
+}
+
+// arrToEvent converts an arr into a calendar event, marking the attendance
+// of sig (e.g. "#8") if given. Without sig the feed is public, so the place
+// and attendance are left out as in GET /db/arr. Returns false if the start
+// date is unusable.
+func arrToEvent(arr models.Arr, sig string) (calendar.Event, bool) {
+	if arr.StartDate == nil {
+		return calendar.Event{}, false
+	}
+	start, allDay, err := calendar.ParseStartDate(*arr.StartDate)
+	if err != nil {
+		return calendar.Event{}, false
+	}
+
+	namn := deref(arr.Namn)
+	var plats string
+	if sig != "" {
+		plats = deref(arr.Plats)
+	}
+	summary := namn
+	if summary == "" {
+		summary = plats
+	}
+	if summary == "" {
+		summary = fmt.Sprintf("Arr %d", arr.Id)
+	}
+
+	var desc []string
+	if o := deref(arr.Organisator); o != "" {
+		desc = append(desc, "Organisatör: "+o)
+	}
+	if d := arr.DeltagareList(); len(d) > 0 && sig != "" {
+		desc = append(desc, "Deltagare: "+strings.Join(d, ", "))
+	}
+	if k := arr.KanskeList(); len(k) > 0 && sig != "" {
+		desc = append(desc, "Kanske: "+strings.Join(k, ", "))
+	}
+
+	event := calendar.Event{
+		UID:         fmt.Sprintf("arr-%d@chalmerslosers.com", arr.Id),
+		Summary:     summary,
+		Location:    plats,
+		Description: strings.Join(desc, "\n"),
+		Start:       start,
+		AllDay:      allDay,
+		Organizer:   deref(arr.Organisator),
+	}
+
+	if sig != "" {
+		event.Attendee = sig
+		if containsString(arr.DeltagareList(), sig) {
+			event.AttendeeStat = calendar.PartStatAccepted
+		} else if containsString(arr.KanskeList(), sig) {
+			event.AttendeeStat = calendar.PartStatTentative
+		}
+	}
+
+	return event, true
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
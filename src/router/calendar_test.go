@@ -0,0 +1,104 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// feedDatabase keeps feed secrets in memory
+type feedDatabase struct {
+	*fakeDatabase
+	secrets map[feedKey]models.FeedSecret
+}
+
+func newFeedDatabase() *feedDatabase {
+	db := newFakeDatabase()
+	valid := true
+	for i := range db.members {
+		db.members[i].Isvalid = &valid
+	}
+	return &feedDatabase{fakeDatabase: db, secrets: map[feedKey]models.FeedSecret{}}
+}
+
+type feedKey struct {
+	memberNumber int64
+	feed         string
+}
+
+func (f *feedDatabase) ReadFeedSecret(memberNumber int64, feed string) (*models.FeedSecret, error) {
+	secret, ok := f.secrets[feedKey{memberNumber, feed}]
+	if !ok {
+		return nil, nil
+	}
+	return &secret, nil
+}
+
+func (f *feedDatabase) CreateFeedSecret(secret *models.FeedSecret) (*models.FeedSecret, error) {
+	if _, ok := f.secrets[feedKey{secret.MemberNumber, secret.Feed}]; !ok {
+		f.secrets[feedKey{secret.MemberNumber, secret.Feed}] = *secret
+	}
+	return f.ReadFeedSecret(secret.MemberNumber, secret.Feed)
+}
+
+func (f *feedDatabase) UpdateFeedSecret(secret *models.FeedSecret) error {
+	f.secrets[feedKey{secret.MemberNumber, secret.Feed}] = *secret
+	return nil
+}
+
+func (f *feedDatabase) DeleteFeedSecret(memberNumber int64, feed string) error {
+	delete(f.secrets, feedKey{memberNumber, feed})
+	return nil
+}
+
+func (f *feedDatabase) ReadArrs(take int, skip int, filter models.ArrFilter) ([]models.Arr, error) {
+	return nil, nil
+}
+
+func TestCalendarFeed_RenewAndRevoke(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	handler := Mux(newFeedDatabase())
+	token, err := auth.GenerateJWT(8, "member@example.com", nil, "test", []byte(testJWTSecret))
+	require.NoError(t, err)
+
+	request := func(method, target string, bearer bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if bearer {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	feedPath := func(method string) string {
+		rec := request(method, "/calendar/feed", true)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var feed map[string]string
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&feed))
+		u, err := url.Parse(feed["url"])
+		require.NoError(t, err)
+		return u.RequestURI()
+	}
+
+	first := feedPath(http.MethodGet)
+	assert.Equal(t, first, feedPath(http.MethodGet))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, first, false).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/calendar/members/9/arr.ics?"+url.Values{"token": {""}}.Encode(), false).Code)
+
+	// Renewing revokes the former URL
+	renewed := feedPath(http.MethodPost)
+	assert.NotEqual(t, first, renewed)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, first, false).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, renewed, false).Code)
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/calendar/feed", true).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, renewed, false).Code)
+}
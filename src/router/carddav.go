@@ -0,0 +1,395 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/carddav"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+	"github.com/sebastiw/sidan-backend/src/vcard"
+)
+
+const (
+	membersVCardName = "members.vcf"
+	cardDAVRoot      = "/carddav/"
+	cardDAVBook      = "/carddav/members/"
+	cardDAVBookName  = "Chalmers Losers"
+	vcardContentType = "text/vcard; charset=utf-8"
+)
+
+func NewCardDAVHandler(db data.Database) CardDAVHandler {
+	return CardDAVHandler{db}
+}
+
+// CardDAVHandler serves the member directory as vCards, both as a single
+// file and as a read-only CardDAV address book (RFC 6352)
+type CardDAVHandler struct {
+	db data.Database
+}
+
+// readMembersVCardHandler exports the members as one vCard 4.0 file
+// GET /db/members.vcf?onlyValid=true
+func (ch CardDAVHandler) readMembersVCardHandler(w http.ResponseWriter, r *http.Request) {
+	onlyValid := MakeDefaultBool(r, "onlyValid", "true")
+	members, err := ch.db.ReadMembers(onlyValid)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	cards := make([]vcard.Card, len(members))
+	for i, m := range members {
+		cards[i] = memberCard(m, BaseURL(r))
+	}
+
+	w.Header().Set("Content-Type", vcardContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+membersVCardName+`"`)
+	if err := vcard.WriteCards(w, cards); err != nil {
+		slog.Warn(ru.GetRequestId(r), "failed to write vcards", err)
+	}
+}
+
+// readCardDAVCredentialsHandler returns the address book URL and the
+// credentials to use for it, since phones cannot send a Bearer header. The
+// password is created on first use.
+// GET /carddav/credentials
+func (ch CardDAVHandler) readCardDAVCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	ch.writeCardDAVCredentials(w, r, readFeedSecret)
+}
+
+// renewCardDAVCredentialsHandler replaces the password, so that phones using
+// the former one are locked out, and returns the new credentials
+// POST /carddav/credentials
+func (ch CardDAVHandler) renewCardDAVCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	ch.writeCardDAVCredentials(w, r, renewFeedSecret)
+}
+
+// deleteCardDAVCredentialsHandler revokes the password until the next
+// GET /carddav/credentials
+// DELETE /carddav/credentials
+func (ch CardDAVHandler) deleteCardDAVCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "revoke feed", membersVCardName, "member", member.Number)
+	if err := ch.db.DeleteFeedSecret(member.Number, membersVCardName); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ch CardDAVHandler) writeCardDAVCredentials(w http.ResponseWriter, r *http.Request, secret func(data.Database, int64, string) (string, error)) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	password, err := secret(ch.db, member.Number, membersVCardName)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"url":      BaseURL(r) + cardDAVRoot,
+		"username": strconv.FormatInt(member.Number, 10),
+		"password": password,
+	})
+}
+
+// authorize lets the request through if it has read:member, either from a
+// Bearer token (set by OptionalAuth) or from Basic auth with the member
+// number and the password of /carddav/credentials
+func (ch CardDAVHandler) authorize(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.HasScope(r, auth.ReadMemberScope) {
+			next(w, r)
+			return
+		}
+
+		if user, password, ok := r.BasicAuth(); ok && ch.checkBasicAuth(r, user, password) {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="sidan", charset="UTF-8"`)
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+	})
+}
+
+// checkBasicAuth tells whether user is the number of a member with
+// read:member and password is the member's current CardDAV password
+func (ch CardDAVHandler) checkBasicAuth(r *http.Request, user string, password string) bool {
+	number, err := strconv.ParseInt(user, 10, 64)
+	if err != nil {
+		return false
+	}
+	valid, err := checkFeedSecret(ch.db, number, membersVCardName, password)
+	if err != nil {
+		slog.Warn(ru.GetRequestId(r), "unable to check carddav password", err)
+		return false
+	}
+	if !valid {
+		return false
+	}
+	member, err := ch.db.ReadMemberByNumber(number)
+	return err == nil && slices.Contains(getScopesForMemberType(member), auth.ReadMemberScope)
+}
+
+// optionsHandler advertises the address book extension
+func (ch CardDAVHandler) optionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, addressbook")
+	w.Header().Set("Allow", "OPTIONS, GET, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// wellKnownHandler points clients at the service root (RFC 6764)
+// /.well-known/carddav
+func (ch CardDAVHandler) wellKnownHandler(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, cardDAVRoot, http.StatusMovedPermanently)
+}
+
+// propfindRootHandler describes the service root, which is also the
+// principal and its address book home
+// PROPFIND /carddav/
+func (ch CardDAVHandler) propfindRootHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := parsePropfind(w, r)
+	if !ok {
+		return
+	}
+
+	resources := []carddav.Resource{{
+		Href: cardDAVRoot,
+		Props: map[xml.Name]string{
+			carddav.ResourceType:         `<collection xmlns="DAV:"/>`,
+			carddav.DisplayName:          carddav.Text(cardDAVBookName),
+			carddav.CurrentUserPrincipal: carddav.Href(cardDAVRoot),
+			carddav.PrincipalURL:         carddav.Href(cardDAVRoot),
+			carddav.AddressbookHomeSet:   carddav.Href(cardDAVRoot),
+		},
+	}}
+	if r.Header.Get("Depth") != "0" {
+		members, ok := ch.readMembers(w)
+		if !ok {
+			return
+		}
+		resources = append(resources, bookResource(members, BaseURL(r)))
+	}
+	carddav.WriteMultistatus(w, resources, req)
+}
+
+// propfindBookHandler describes the address book and, with Depth 1, its
+// cards
+// PROPFIND /carddav/members/
+func (ch CardDAVHandler) propfindBookHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := parsePropfind(w, r)
+	if !ok {
+		return
+	}
+	members, ok := ch.readMembers(w)
+	if !ok {
+		return
+	}
+
+	base := BaseURL(r)
+	resources := []carddav.Resource{bookResource(members, base)}
+	if r.Header.Get("Depth") != "0" {
+		for _, m := range members {
+			resources = append(resources, cardResource(m, base))
+		}
+	}
+	carddav.WriteMultistatus(w, resources, req)
+}
+
+// propfindCardHandler describes a single card
+// PROPFIND /carddav/members/{number}.vcf
+func (ch CardDAVHandler) propfindCardHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := parsePropfind(w, r)
+	if !ok {
+		return
+	}
+	member, ok := ch.readCardMember(w, r)
+	if !ok {
+		return
+	}
+	carddav.WriteMultistatus(w, []carddav.Resource{cardResource(*member, BaseURL(r))}, req)
+}
+
+// reportBookHandler answers addressbook-multiget and addressbook-query. The
+// query filters are ignored and every card is returned.
+// REPORT /carddav/members/
+func (ch CardDAVHandler) reportBookHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := carddav.ParseReport(r.Body)
+	if err == carddav.ErrUnsupportedReport {
+		http.Error(w, `{"error":"unsupported report"}`, http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"invalid report body"}`, http.StatusBadRequest)
+		return
+	}
+
+	members, ok := ch.readMembers(w)
+	if !ok {
+		return
+	}
+
+	base := BaseURL(r)
+	var resources []carddav.Resource
+	if !report.Multiget {
+		for _, m := range members {
+			resources = append(resources, cardResource(m, base))
+		}
+		carddav.WriteMultistatus(w, resources, report.Props)
+		return
+	}
+
+	byHref := make(map[string]carddav.Resource, len(members))
+	for _, m := range members {
+		res := cardResource(m, base)
+		byHref[res.Href] = res
+	}
+	for _, href := range report.Hrefs {
+		res, ok := byHref[strings.TrimPrefix(href, base)]
+		if !ok {
+			res = carddav.Resource{Href: href}
+		}
+		resources = append(resources, res)
+	}
+	carddav.WriteMultistatus(w, resources, report.Props)
+}
+
+// readCardHandler returns a single member's vCard
+// GET /carddav/members/{number}.vcf
+func (ch CardDAVHandler) readCardHandler(w http.ResponseWriter, r *http.Request) {
+	member, ok := ch.readCardMember(w, r)
+	if !ok {
+		return
+	}
+
+	card := vcard.Marshal(memberCard(*member, BaseURL(r)))
+	etag := cardETag(card)
+	w.Header().Set("ETag", etag)
+	if isNotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", vcardContentType)
+	w.Write(card)
+}
+
+// readMembers returns the valid members, which make up the address book
+func (ch CardDAVHandler) readMembers(w http.ResponseWriter) ([]models.Member, bool) {
+	members, err := ch.db.ReadMembers(true)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+	return members, true
+}
+
+// readCardMember finds the valid member of the card in the path
+func (ch CardDAVHandler) readCardMember(w http.ResponseWriter, r *http.Request) (*models.Member, bool) {
+	number, err := strconv.ParseInt(mux.Vars(r)["number"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid member number"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	member, err := ch.db.ReadMemberByNumber(number)
+	if err != nil || member.Isvalid == nil || !*member.Isvalid {
+		http.Error(w, `{"error":"card not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+	return member, true
+}
+
+func parsePropfind(w http.ResponseWriter, r *http.Request) (carddav.PropRequest, bool) {
+	req, err := carddav.ParsePropfind(r.Body)
+	if err != nil {
+		http.Error(w, `{"error":"invalid propfind body"}`, http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+func bookResource(members []models.Member, base string) carddav.Resource {
+	ctag := sha256.New()
+	for _, m := range members {
+		ctag.Write(vcard.Marshal(memberCard(m, base)))
+	}
+
+	return carddav.Resource{
+		Href: cardDAVBook,
+		Props: map[xml.Name]string{
+			carddav.ResourceType:         `<collection xmlns="DAV:"/><addressbook xmlns="urn:ietf:params:xml:ns:carddav"/>`,
+			carddav.DisplayName:          carddav.Text(cardDAVBookName),
+			carddav.AddressbookDesc:      carddav.Text("Medlemmar i Chalmers Losers"),
+			carddav.CurrentUserPrincipal: carddav.Href(cardDAVRoot),
+			carddav.GetCTag:              hex.EncodeToString(ctag.Sum(nil)),
+			carddav.SupportedReportSet: `<supported-report xmlns="DAV:"><report><addressbook-multiget xmlns="urn:ietf:params:xml:ns:carddav"/></report></supported-report>` +
+				`<supported-report xmlns="DAV:"><report><addressbook-query xmlns="urn:ietf:params:xml:ns:carddav"/></report></supported-report>`,
+		},
+	}
+}
+
+func cardResource(m models.Member, base string) carddav.Resource {
+	card := vcard.Marshal(memberCard(m, base))
+	return carddav.Resource{
+		Href: fmt.Sprintf("%s%d.vcf", cardDAVBook, m.Number),
+		Props: map[xml.Name]string{
+			carddav.ResourceType:   "",
+			carddav.GetETag:        carddav.Text(cardETag(card)),
+			carddav.GetContentType: vcardContentType,
+			carddav.AddressData:    carddav.Text(string(card)),
+		},
+	}
+}
+
+func cardETag(card []byte) string {
+	sum := sha256.Sum256(card)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// memberCard maps a member to a contact. Uploaded pictures are served from
+// /file/ on base.
+func memberCard(m models.Member, base string) vcard.Card {
+	card := vcard.Card{
+		UID:      fmt.Sprintf("member-%d@chalmerslosers.com", m.Number),
+		Nickname: fmt.Sprintf("#%d", m.Number),
+		FullName: deref(m.Name),
+		Email:    deref(m.Email),
+		Phone:    deref(m.Phone),
+		Address:  deref(m.Adress),
+	}
+	if picture := deref(m.Picture); picture != "" {
+		if strings.HasPrefix(picture, "http://") || strings.HasPrefix(picture, "https://") {
+			card.Photo = picture
+		} else {
+			card.Photo = base + "/file/" + strings.TrimPrefix(picture, "/")
+		}
+	}
+	return card
+}
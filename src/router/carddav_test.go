@@ -0,0 +1,132 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+)
+
+func newCardDAVDatabase() *fakeDatabase {
+	db := newFakeDatabase()
+	valid := true
+	for i := range db.members {
+		db.members[i].Isvalid = &valid
+	}
+	return db
+}
+
+func TestReadMembersVCardHandler(t *testing.T) {
+	db := newCardDAVDatabase()
+	body := serve(t, db, NewCardDAVHandler(db).readMembersVCardHandler, 8, auth.ReadMemberScope)
+
+	assert.Equal(t, 2, strings.Count(body, "BEGIN:VCARD\r\nVERSION:4.0\r\n"))
+	assert.Contains(t, body, "NICKNAME:#8\r\n")
+	assert.Contains(t, body, "EMAIL:member@example.com\r\n")
+	for _, secret := range secrets {
+		assert.NotContains(t, body, secret)
+	}
+}
+
+func TestCardDAV_BasicAuth(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := newFeedDatabase()
+	ch := NewCardDAVHandler(db)
+	handler := ch.authorize(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	request := func(user, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PROPFIND", cardDAVBook, nil)
+		req.SetBasicAuth(user, password)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	password, err := readFeedSecret(db, 8, membersVCardName)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, request("8", password).Code)
+
+	rec := request("9", password)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Basic")
+
+	// The calendar feed token is no CardDAV password
+	token, err := readFeedSecret(db, 8, arrFeedName)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, request("8", token).Code)
+
+	// Renewing locks out the former password, deleting any
+	renewed, err := renewFeedSecret(db, 8, membersVCardName)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, request("8", password).Code)
+	assert.Equal(t, http.StatusNoContent, request("8", renewed).Code)
+	require.NoError(t, db.DeleteFeedSecret(8, membersVCardName))
+	assert.Equal(t, http.StatusUnauthorized, request("8", renewed).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("8", "").Code)
+}
+
+func TestCardDAV_PropfindBook(t *testing.T) {
+	db := newCardDAVDatabase()
+	req := httptest.NewRequest("PROPFIND", cardDAVBook, strings.NewReader(`<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop><d:resourcetype/><d:getetag/><cs:getctag/><d:quota-used-bytes/></d:prop>
+</d:propfind>`))
+	req.Header.Set("Depth", "1")
+	rec := httptest.NewRecorder()
+	NewCardDAVHandler(db).propfindBookHandler(rec, req)
+
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "<href>/carddav/members/</href>")
+	assert.Contains(t, body, `<addressbook xmlns="urn:ietf:params:xml:ns:carddav"/>`)
+	assert.Contains(t, body, "<href>/carddav/members/8.vcf</href>")
+	assert.Contains(t, body, "<href>/carddav/members/9.vcf</href>")
+	assert.Contains(t, body, `<getctag xmlns="http://calendarserver.org/ns/">`)
+	assert.Contains(t, body, `<quota-used-bytes xmlns="DAV:"></quota-used-bytes>`)
+	assert.Contains(t, body, "HTTP/1.1 404 Not Found")
+	assert.NotContains(t, body, "BEGIN:VCARD")
+}
+
+func TestCardDAV_Multiget(t *testing.T) {
+	db := newCardDAVDatabase()
+	req := httptest.NewRequest("REPORT", cardDAVBook, strings.NewReader(`<?xml version="1.0"?>
+<c:addressbook-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:carddav">
+  <d:prop><d:getetag/><c:address-data/></d:prop>
+  <d:href>/carddav/members/8.vcf</d:href>
+  <d:href>/carddav/members/1234.vcf</d:href>
+</c:addressbook-multiget>`))
+	rec := httptest.NewRecorder()
+	NewCardDAVHandler(db).reportBookHandler(rec, req)
+
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "NICKNAME:#8")
+	assert.NotContains(t, body, "NICKNAME:#9")
+	assert.Contains(t, body, "<href>/carddav/members/1234.vcf</href><status>HTTP/1.1 404 Not Found</status>")
+}
+
+func TestCardDAV_ReadCard(t *testing.T) {
+	db := newCardDAVDatabase()
+	ch := NewCardDAVHandler(db)
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/carddav/members/8.vcf", nil), map[string]string{"number": "8"})
+	rec := httptest.NewRecorder()
+	ch.readCardHandler(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, vcardContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "NICKNAME:#8")
+
+	req = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/carddav/members/8.vcf", nil), map[string]string{"number": "8"})
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	ch.readCardHandler(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
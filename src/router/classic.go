@@ -0,0 +1,204 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// parseClassicUsername reads the member number from a classic username,
+// "#8" or "8". Prospects and suspects have no password.
+func parseClassicUsername(username string) (int64, bool) {
+	username = strings.TrimPrefix(strings.TrimSpace(username), "#")
+	number, err := strconv.ParseInt(username, 10, 64)
+	if err != nil || number <= 0 {
+		return 0, false
+	}
+	return number, true
+}
+
+// checkLockedOut writes 429 with Retry-After and returns true if the member
+// is locked out after too many failed logins
+func (h *AuthHandler) checkLockedOut(w http.ResponseWriter, number int64) bool {
+	lockout, err := h.db.ReadLoginLockout(number)
+	if err != nil {
+		slog.Error("failed to read login lockout", "member_number", number, "error", err)
+		http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
+		return true
+	}
+	now := time.Now()
+	if !lockout.IsLocked(now) {
+		return false
+	}
+	seconds := int(math.Ceil(lockout.LockedUntil.Sub(now).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, `{"error":"too many failed logins, try again later"}`, http.StatusTooManyRequests)
+	return true
+}
+
+// verifyPassword checks password against the member and counts failures
+// towards the lockout. A correct classic password is rehashed into the bcrypt
+// password column.
+func (h *AuthHandler) verifyPassword(member *models.Member, password string) bool {
+	ok, legacy := auth.CheckPassword(member, password)
+	if !ok {
+		cfg := config.GetPassword()
+		lockout, err := h.db.RecordFailedLogin(member.Number, time.Now(), cfg.MaxAttempts, time.Duration(cfg.LockoutSeconds)*time.Second)
+		if err != nil {
+			slog.Error("failed to record failed login", "member_number", member.Number, "error", err)
+		} else if lockout.IsLocked(time.Now()) {
+			slog.Warn("account locked after failed logins", "member_number", member.Number, "until", lockout.LockedUntil)
+		}
+		return false
+	}
+
+	if err := h.db.ClearFailedLogins(member.Number); err != nil {
+		slog.Warn("failed to clear failed logins", "member_number", member.Number, "error", err)
+	}
+	if legacy {
+		hash, err := auth.HashPassword(password)
+		if err == nil {
+			err = h.db.SetMemberPassword(member.Number, hash)
+		}
+		if err != nil {
+			slog.Error("failed to rehash classic password", "member_number", member.Number, "error", err)
+		} else {
+			slog.Info("classic password rehashed", "member_number", member.Number)
+		}
+	}
+	return true
+}
+
+// ClassicLogin logs in with member number and password, and issues the same
+// tokens as the OAuth2 web flow. password_change_required is true when the
+// password does not meet the current password policy.
+// POST /auth/classic/login
+// Body: {"username": "#8", "password": "..."}
+func (h *AuthHandler) ClassicLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" || req.Password == "" {
+		http.Error(w, `{"error":"username and password required"}`, http.StatusBadRequest)
+		return
+	}
+
+	number, ok := parseClassicUsername(req.Username)
+	if !ok {
+		http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	if h.checkLockedOut(w, number) {
+		return
+	}
+
+	member, err := h.db.ReadMemberByNumber(number)
+	if err != nil || member == nil || member.Isvalid == nil || !*member.Isvalid {
+		slog.Warn("classic login for unknown or invalid member", "member_number", number)
+		http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	if !h.verifyPassword(member, req.Password) {
+		slog.Warn("classic login failed", "member_number", number)
+		http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	email := deref(member.Email)
+	scopes := getScopesForMemberType(member)
+	jwtToken, err := auth.GenerateJWT(member.Number, email, scopes, auth.ClassicProvider, config.GetJWTSecret())
+	if err != nil {
+		slog.Error("JWT generation failed", "error", err)
+		http.Error(w, `{"error":"token generation failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	refreshToken := auth.GenerateState()
+	session := &models.Session{
+		Token:        refreshToken,
+		MemberNumber: member.Number,
+		Email:        email,
+		Provider:     auth.ClassicProvider,
+		ExpiresAt:    time.Now().Add(30 * 24 * time.Hour),
+	}
+	if err := h.db.CreateSession(session); err != nil {
+		slog.Error("failed to store session", "error", err)
+		http.Error(w, `{"error":"storage error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("login successful", "provider", auth.ClassicProvider, "member", member.Id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token":  jwtToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    28800,
+		"member": map[string]interface{}{
+			"number": member.Number,
+			"email":  email,
+			"name":   deref(member.Name),
+		},
+		"scopes":                   scopes,
+		"password_change_required": config.GetPasswordPolicy().Validate(req.Password) != nil,
+	})
+}
+
+// ChangePassword sets the authenticated member's password. The current
+// password is required if the member has one. The new password must meet the
+// password policy.
+// PUT /auth/classic/password
+// Body: {"current_password": "...", "new_password": "..."}
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if h.checkLockedOut(w, member.Number) {
+		return
+	}
+	if auth.HasPassword(member) && !h.verifyPassword(member, req.CurrentPassword) {
+		http.Error(w, `{"error":"current password is wrong"}`, http.StatusForbidden)
+		return
+	}
+
+	if err := config.GetPasswordPolicy().Validate(req.NewPassword); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.NewPassword)
+	if err == nil {
+		err = h.db.SetMemberPassword(member.Number, hash)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("password changed", "member_number", member.Number)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type classicDatabase struct {
+	*fakeDatabase
+	lockouts map[int64]*models.LoginLockout
+	sessions []models.Session
+}
+
+func newClassicDatabase() *classicDatabase {
+	db := &classicDatabase{fakeDatabase: newFakeDatabase(), lockouts: map[int64]*models.LoginLockout{}}
+	valid := true
+	classic := "hemligt"
+	db.members[0].Isvalid = &valid
+	db.members[0].Password = nil
+	db.members[0].Password_classic = &classic
+	return db
+}
+
+func (c *classicDatabase) SetMemberPassword(number int64, hash string) error {
+	for i := range c.members {
+		if c.members[i].Number == number {
+			c.members[i].Password = &hash
+			c.members[i].Password_classic = nil
+		}
+	}
+	return nil
+}
+
+func (c *classicDatabase) ReadLoginLockout(number int64) (*models.LoginLockout, error) {
+	return c.lockouts[number], nil
+}
+
+func (c *classicDatabase) RecordFailedLogin(number int64, at time.Time, maxAttempts int, lockFor time.Duration) (*models.LoginLockout, error) {
+	l, ok := c.lockouts[number]
+	if !ok {
+		l = &models.LoginLockout{MemberNumber: number}
+		c.lockouts[number] = l
+	}
+	l.FailedAttempts++
+	if l.FailedAttempts >= maxAttempts {
+		until := at.Add(lockFor)
+		l.LockedUntil = &until
+		l.FailedAttempts = 0
+	}
+	return l, nil
+}
+
+func (c *classicDatabase) ClearFailedLogins(number int64) error {
+	delete(c.lockouts, number)
+	return nil
+}
+
+func (c *classicDatabase) CreateSession(session *models.Session) error {
+	c.sessions = append(c.sessions, *session)
+	return nil
+}
+
+func classicLogin(db *classicDatabase, username string, password string) *httptest.ResponseRecorder {
+	body := `{"username":"` + username + `","password":"` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/classic/login", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	NewAuthHandler(db).ClassicLogin(rec, req)
+	return rec
+}
+
+func TestClassicLogin_RehashesClassicPassword(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := newClassicDatabase()
+
+	rec := classicLogin(db, "#8", "hemligt")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		AccessToken            string `json:"access_token"`
+		RefreshToken           string `json:"refresh_token"`
+		PasswordChangeRequired bool   `json:"password_change_required"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	claims, err := auth.ValidateJWT(resp.AccessToken, []byte(testJWTSecret))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), claims.MemberNumber)
+	assert.Equal(t, getScopesForMemberType(&db.members[0]), claims.Scopes)
+	require.Len(t, db.sessions, 1)
+	assert.Equal(t, resp.RefreshToken, db.sessions[0].Token)
+	assert.Equal(t, auth.ClassicProvider, db.sessions[0].Provider)
+	assert.True(t, resp.PasswordChangeRequired)
+
+	// The classic password is now a bcrypt hash, and still logs in
+	assert.Nil(t, db.members[0].Password_classic)
+	assert.True(t, strings.HasPrefix(*db.members[0].Password, "$2"))
+	assert.Equal(t, http.StatusOK, classicLogin(db, "8", "hemligt").Code)
+}
+
+func TestClassicLogin_Lockout(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	password := config.GetPassword()
+	saved := *password
+	password.MaxAttempts, password.LockoutSeconds = 3, 60
+	defer func() { *password = saved }()
+	db := newClassicDatabase()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, classicLogin(db, "#8", "fel").Code)
+	}
+	// Locked, even with the right password
+	rec := classicLogin(db, "#8", "hemligt")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// Unknown members and prospects get the same answer as a wrong password
+	assert.Equal(t, http.StatusUnauthorized, classicLogin(db, "#77", "hemligt").Code)
+	assert.Equal(t, http.StatusUnauthorized, classicLogin(db, "P12", "hemligt").Code)
+}
+
+func TestChangePassword_EnforcesPolicy(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	password := config.GetPassword()
+	saved := *password
+	password.Policy, password.MaxAttempts, password.LockoutSeconds = "medium", 5, 60
+	defer func() { *password = saved }()
+	db := newClassicDatabase()
+	handler := auth.NewMiddleware(db).RequireAuth(http.HandlerFunc(NewAuthHandler(db).ChangePassword))
+
+	change := func(body string) int {
+		token, err := auth.GenerateJWT(8, "member@example.com", nil, "test", []byte(testJWTSecret))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/auth/classic/password", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, change(`{"current_password":"fel","new_password":"Nytt1234"}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, change(`{"current_password":"hemligt","new_password":"kort"}`))
+	assert.Equal(t, http.StatusNoContent, change(`{"current_password":"hemligt","new_password":"Nytt1234"}`))
+
+	ok, _ := auth.CheckPassword(&db.members[0], "Nytt1234")
+	assert.True(t, ok)
+}
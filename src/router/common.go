@@ -41,3 +41,12 @@ func CheckError(w http.ResponseWriter, r *http.Request, err error) {
 		panic(err.Error())
 	}
 }
+
+// BaseURL is the scheme and host the client used to reach the server
+func BaseURL(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// WriteJSONConditional encodes v as the JSON response with a strong ETag
+// computed from the encoded body, and Last-Modified if lastModified is set.
+// Responds 304 Not Modified when If-None-Match matches. Since the ETag is
+// computed after per-viewer filtering, viewers that see different content
+// get different ETags.
+//
+// Last-Modified only tells when the content was written. Likes, edits,
+// permissions and the viewer change responses without moving it, so
+// If-Modified-Since is not evaluated.
+func WriteJSONConditional(w http.ResponseWriter, r *http.Request, v interface{}, lastModified time.Time) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Add("Vary", "Authorization")
+	if r.Header.Get("Authorization") != "" {
+		h.Set("Cache-Control", "private, no-cache")
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// isNotModified evaluates If-None-Match according to RFC 9110 section
+// 13.1.2
+func isNotModified(r *http.Request, etag string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		// If-None-Match uses the weak comparison function
+		if candidate != "" && strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func latestEntryTime(entries []models.Entry) time.Time {
+	var latest time.Time
+	for _, e := range entries {
+		if e.DateTime.After(latest) {
+			latest = e.DateTime
+		}
+	}
+	return latest
+}
+
+func latestArticleTime(articles []models.Article) time.Time {
+	var latest time.Time
+	for _, a := range articles {
+		if a.DateTime != nil && a.DateTime.After(latest) {
+			latest = *a.DateTime
+		}
+	}
+	return latest
+}
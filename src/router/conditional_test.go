@@ -0,0 +1,127 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func conditionalGet(v interface{}, lastModified time.Time, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, val := range headers {
+		req.Header.Set(k, val)
+	}
+	rec := httptest.NewRecorder()
+	WriteJSONConditional(rec, req, v, lastModified)
+	return rec
+}
+
+func TestWriteJSONConditional_ETag(t *testing.T) {
+	v := map[string]string{"hej": "då"}
+	rec := conditionalGet(v, time.Time{}, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.JSONEq(t, `{"hej":"då"}`, rec.Body.String())
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	rec = conditionalGet(v, time.Time{}, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+
+	// Weak and listed validators match too
+	rec = conditionalGet(v, time.Time{}, map[string]string{"If-None-Match": `"other", W/` + etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = conditionalGet(v, time.Time{}, map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"hej":"då"}`, rec.Body.String())
+
+	// Changed content gets a new ETag
+	rec = conditionalGet(map[string]string{"hej": "igen"}, time.Time{}, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestWriteJSONConditional_LastModified(t *testing.T) {
+	modified := time.Date(2026, 10, 18, 12, 0, 0, 500, time.UTC)
+	rec := conditionalGet("x", modified, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Sun, 18 Oct 2026 12:00:00 GMT", rec.Header().Get("Last-Modified"))
+
+	// Last-Modified is informational, only the ETag revalidates
+	since := map[string]string{"If-Modified-Since": "Sun, 18 Oct 2026 12:00:00 GMT"}
+	assert.Equal(t, http.StatusOK, conditionalGet("x", modified, since).Code)
+	since["If-None-Match"] = rec.Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, conditionalGet("x", modified, since).Code)
+}
+
+func TestReadEntries_ChangedAfterLike(t *testing.T) {
+	db := newFakeDatabase()
+	db.entries[0].DateTime = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	eh := NewEntryHandler(db)
+
+	get := func(handler http.HandlerFunc, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/db/entries", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		for k, val := range headers {
+			req.Header.Set(k, val)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	for name, handler := range map[string]http.HandlerFunc{"list": eh.readAllEntryHandler, "entry": eh.readEntryHandler} {
+		db.entries[0].Likes = 0
+		rec := get(handler, nil)
+		require.Equal(t, http.StatusOK, rec.Code, name)
+		lastModified, etag := rec.Header().Get("Last-Modified"), rec.Header().Get("ETag")
+
+		// A like does not move the entry datetime
+		db.entries[0].Likes++
+		rec = get(handler, map[string]string{"If-Modified-Since": lastModified})
+		assert.Equal(t, http.StatusOK, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), `"likes":1`, name)
+		assert.Equal(t, lastModified, rec.Header().Get("Last-Modified"), name)
+
+		rec = get(handler, map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusOK, rec.Code, name)
+		assert.Equal(t, http.StatusNotModified, get(handler, map[string]string{"If-None-Match": rec.Header().Get("ETag")}).Code, name)
+	}
+}
+
+func TestWriteJSONConditional_PerViewer(t *testing.T) {
+	// The same poll seen anonymously and by a member who voted
+	vote := 1
+	anonymous := models.Poll{Id: 1, Theme: "Supa?", Yae: "Ja", Nay: "Nej"}
+	voter := anonymous
+	voter.MyVote = &vote
+
+	rec := conditionalGet(anonymous, time.Time{}, nil)
+	anonymousETag := rec.Header().Get("ETag")
+	rec = conditionalGet(voter, time.Time{}, map[string]string{"Authorization": "Bearer token", "If-None-Match": anonymousETag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, anonymousETag, rec.Header().Get("ETag"))
+	assert.Contains(t, rec.Body.String(), `"my_vote":1`)
+	assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "Authorization", rec.Header().Get("Vary"))
+}
+
+func TestIsNotModified_OnlyGet(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("If-None-Match", "*")
+	assert.False(t, isNotModified(req, `"x"`))
+
+	req = httptest.NewRequest(http.MethodHead, "/", nil)
+	req.Header.Set("If-None-Match", "*")
+	assert.True(t, isNotModified(req, `"x"`))
+}
@@ -4,16 +4,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/sebastiw/sidan-backend/src/auth"
 	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/hosts"
 	"github.com/sebastiw/sidan-backend/src/models"
 	ru "github.com/sebastiw/sidan-backend/src/router_util"
+	"github.com/sebastiw/sidan-backend/src/views"
 )
 
 // GetMemberFromContext retrieves member from auth context or returns nil
@@ -22,11 +26,17 @@ func GetMemberFromContext(r *http.Request) *models.Member {
 }
 
 func NewEntryHandler(db data.Database) EntryHandler {
-	return EntryHandler{db}
+	return EntryHandler{
+		db:        db,
+		resolver:  hosts.NewCachedResolver(net.DefaultResolver, time.Hour, 10000),
+		hostNames: hosts.NewMatcher(db.ReadHostPatterns, 5*time.Minute),
+	}
 }
 
 type EntryHandler struct {
-	db data.Database
+	db        data.Database
+	resolver  hosts.Resolver
+	hostNames *hosts.Matcher
 }
 
 func (eh EntryHandler) createEntryHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,15 +48,22 @@ func (eh EntryHandler) createEntryHandler(w http.ResponseWriter, r *http.Request
 	e.Sig = fmt.Sprintf("#%d", claims.MemberNumber)
 	e.Email = claims.Email
 
+	// Record where the entry was written from, as the legacy site did
+	ip := ru.ClientIP(r)
+	host := hosts.Lookup(r.Context(), eh.resolver, ip)
+	e.Ip = &ip
+	e.Host = &host
+
 	slog.Debug(ru.GetRequestId(r), "entry", e)
 	entry, err := eh.db.CreateEntry(&e)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(entry)
+	json.NewEncoder(w).Encode(views.NewEntry(*entry, entryAudience(r)))
 }
 
 func (eh EntryHandler) readEntryHandler(w http.ResponseWriter, r *http.Request) {
@@ -67,11 +84,12 @@ func (eh EntryHandler) readEntryHandler(w http.ResponseWriter, r *http.Request)
 		viewerMemberID = &member.Number
 	}
 
+	eh.hostNames.Annotate(entry)
+
 	// Apply message filtering based on permissions
 	FilterEntryMessage(entry, viewerMemberID)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(entry)
+	WriteJSONConditional(w, r, views.NewEntry(*entry, entryAudience(r)), entry.DateTime)
 }
 
 func (eh EntryHandler) updateEntryHandler(w http.ResponseWriter, r *http.Request) {
@@ -87,10 +105,11 @@ func (eh EntryHandler) updateEntryHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(entry)
+	json.NewEncoder(w).Encode(views.NewEntry(*entry, entryAudience(r)))
 }
 
 func (eh EntryHandler) deleteEntryHandler(w http.ResponseWriter, r *http.Request) {
@@ -106,10 +125,11 @@ func (eh EntryHandler) deleteEntryHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(entry)
+	json.NewEncoder(w).Encode(views.NewEntry(*entry, entryAudience(r)))
 }
 
 // Responses:
@@ -122,9 +142,12 @@ func (eh EntryHandler) readAllEntryHandler(w http.ResponseWriter, r *http.Reques
 	take := MakeDefaultInt(r, "take", "20")
 	skip := MakeDefaultInt(r, "skip", "0")
 	rsqlQuery := r.URL.Query().Get("q")
+	sort := r.URL.Query().Get("sort")
+	fields := splitFields(r.URL.Query().Get("fields"))
+	filterName := r.URL.Query().Get("filter")
 	
-	// Security check: filtering requires 'filtering' scope
-	if rsqlQuery != "" {
+	// Security check: filtering and sorting requires 'filtering' scope
+	if rsqlQuery != "" || sort != "" || filterName != "" {
 		scopes := auth.GetScopes(r)
 		hasFilteringScope := false
 		if scopes != nil {
@@ -143,8 +166,31 @@ func (eh EntryHandler) readAllEntryHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 	
-	// Pass raw RSQL query to database layer
-	entries, err := eh.db.ReadEntries(take, skip, rsqlQuery)
+	// Resolve a saved filter, combining it with any explicit q and sort
+	if filterName != "" {
+		member := GetMemberFromContext(r)
+		if member == nil {
+			http.Error(w, `{"error":"saved filters require authentication"}`, http.StatusUnauthorized)
+			return
+		}
+		saved, err := eh.db.ReadSavedFilterByName(member.Number, filterName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if saved == nil {
+			http.Error(w, `{"error":"filter not found"}`, http.StatusNotFound)
+			return
+		}
+		rsqlQuery = combineRSQL(saved.Query, rsqlQuery)
+		if sort == "" {
+			sort = saved.Sort
+		}
+	}
+
+	// Pass raw RSQL query, sort and fieldset to database layer
+	entries, err := eh.db.ReadEntries(take, skip, rsqlQuery, sort, fields)
 	if err != nil {
 		// Check if it's an RSQL parsing error (400) vs database error (500)
 		if strings.Contains(err.Error(), "RSQL") || strings.Contains(err.Error(), "not allowed") {
@@ -164,11 +210,19 @@ func (eh EntryHandler) readAllEntryHandler(w http.ResponseWriter, r *http.Reques
 		viewerMemberID = &member.Number
 	}
 
+	for i := range entries {
+		eh.hostNames.Annotate(&entries[i])
+	}
+
 	// Apply message filtering to all entries
 	FilterEntriesMessages(entries, viewerMemberID)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(entries)
+	body := views.NewEntries(entries, entryAudience(r))
+	if len(fields) > 0 {
+		WriteJSONConditional(w, r, ProjectEntries(body, fields), latestEntryTime(entries))
+		return
+	}
+	WriteJSONConditional(w, r, body, latestEntryTime(entries))
 }
 
 func (eh EntryHandler) likeEntryHandler(w http.ResponseWriter, r *http.Request) {
@@ -183,7 +237,7 @@ func (eh EntryHandler) likeEntryHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	sig := strconv.FormatInt(member.Number, 10)
-	host := r.RemoteAddr
+	host := hosts.Lookup(r.Context(), eh.resolver, ru.ClientIP(r))
 	err := eh.db.LikeEntry(id, sig, host)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -193,3 +247,14 @@ func (eh EntryHandler) likeEntryHandler(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// combineRSQL joins two RSQL expressions with AND, either may be empty
+func combineRSQL(a string, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return "(" + a + ");(" + b + ")"
+}
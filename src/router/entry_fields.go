@@ -0,0 +1,54 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// entryFieldJSONKeys maps RSQL keys to their JSON names where they differ
+var entryFieldJSONKeys = map[string]string{
+	"kumpaner": "sidekicks",
+}
+
+func splitFields(s string) []string {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// ProjectEntries reduces entry views to a sparse fieldset. The fields have
+// already been validated by the database layer; id is always included. Fields
+// the view does not expose are left out.
+func ProjectEntries(entries []interface{}, fields []string) []map[string]json.RawMessage {
+	keys := map[string]bool{"id": true}
+	for _, f := range fields {
+		if k, ok := entryFieldJSONKeys[f]; ok {
+			f = k
+		}
+		keys[f] = true
+	}
+
+	projected := make([]map[string]json.RawMessage, 0, len(entries))
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(b, &all); err != nil {
+			continue
+		}
+		sparse := make(map[string]json.RawMessage, len(keys))
+		for k := range keys {
+			if v, ok := all[k]; ok {
+				sparse[k] = v
+			}
+		}
+		projected = append(projected, sparse)
+	}
+	return projected
+}
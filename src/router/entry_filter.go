@@ -15,6 +15,12 @@ import (
 // - Has specific user_ids and (requester in list OR requester is author) → show message with prefix
 // - Has specific user_ids and requester NOT in list → show only "hemlis" and clear all other fields
 func FilterEntryMessage(entry *models.Entry, viewerMemberID *int64) {
+	// Addresses are only shown to members, the nickname is public
+	if viewerMemberID == nil {
+		entry.Ip = nil
+		entry.Host = nil
+	}
+
 	// No permissions = public entry, show full message
 	if len(entry.Permissions) == 0 {
 		return
@@ -85,6 +91,7 @@ func redactEntry(entry *models.Entry) {
 	entry.Place = ""
 	entry.Ip = nil
 	entry.Host = nil
+	entry.HostName = ""
 	entry.Lat = nil
 	entry.Lon = nil
 	entry.Olsug = nil
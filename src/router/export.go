@@ -0,0 +1,168 @@
+package router
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+	"github.com/sebastiw/sidan-backend/src/views"
+)
+
+var exportCSVHeader = []string{
+	"id", "datetime", "msg", "status", "cl", "sig", "email", "place",
+	"olsug", "enheter", "lat", "lon", "report",
+	"likes", "secret", "personal_secret", "sidekicks",
+}
+
+func NewExportHandler(db data.Database) ExportHandler {
+	return ExportHandler{db}
+}
+
+type ExportHandler struct {
+	db data.Database
+}
+
+// exportEntriesHandler streams the entry archive as JSON Lines or CSV
+// GET /export/entries?format=jsonl|csv&from=2003-01-01&to=2004-01-01
+func (eh ExportHandler) exportEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "jsonl"
+	}
+	if format != "jsonl" && format != "csv" {
+		http.Error(w, `{"error":"format must be jsonl or csv"}`, http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseExportTime(r.URL.Query().Get("from"), false)
+	if err != nil {
+		http.Error(w, `{"error":"invalid from"}`, http.StatusBadRequest)
+		return
+	}
+	to, err := parseExportTime(r.URL.Query().Get("to"), true)
+	if err != nil {
+		http.Error(w, `{"error":"invalid to"}`, http.StatusBadRequest)
+		return
+	}
+
+	var viewerMemberID *int64
+	if member := GetMemberFromContext(r); member != nil {
+		viewerMemberID = &member.Number
+	}
+
+	flusher, _ := w.(http.Flusher)
+	filename := fmt.Sprintf("entries.%s", format)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	count := 0
+	var write func(*models.Entry) error
+	var flush func()
+
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write(exportCSVHeader)
+		write = func(e *models.Entry) error {
+			return cw.Write(entryCSVRecord(e))
+		}
+		flush = cw.Flush
+	default:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		write = func(e *models.Entry) error {
+			return enc.Encode(views.NewEntry(*e, views.Member))
+		}
+		flush = func() {}
+	}
+
+	err = eh.db.ExportEntries(from, to, func(e *models.Entry) error {
+		FilterEntryMessage(e, viewerMemberID)
+		if err := write(e); err != nil {
+			return err
+		}
+		count++
+		if count%500 == 0 {
+			flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	flush()
+
+	if err != nil {
+		// Headers are most likely already sent, so only log
+		slog.Error(ru.GetRequestId(r), "export failed", err.Error(), "rows", count)
+		return
+	}
+	slog.Info(ru.GetRequestId(r), "exported entries", count, "format", format)
+}
+
+// parseExportTime accepts a date (2006-01-02) or RFC 3339 timestamp. A date
+// used as upper bound includes the whole day.
+func parseExportTime(s string, upper bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func entryCSVRecord(e *models.Entry) []string {
+	sideKicks := make([]string, len(e.SideKicks))
+	for i, s := range e.SideKicks {
+		sideKicks[i] = s.Number
+	}
+	return []string{
+		strconv.FormatInt(e.Id, 10),
+		e.DateTime.Format(time.RFC3339),
+		e.Msg,
+		optInt(e.Status),
+		strconv.FormatInt(e.Cl, 10),
+		e.Sig,
+		e.Email,
+		e.Place,
+		optInt(e.Olsug),
+		strconv.FormatInt(e.Enheter, 10),
+		optFloat(e.Lat),
+		optFloat(e.Lon),
+		strconv.FormatBool(e.Report),
+		strconv.FormatInt(e.Likes, 10),
+		strconv.FormatBool(e.Secret),
+		strconv.FormatBool(e.PersonalSecret),
+		strings.Join(sideKicks, " "),
+	}
+}
+
+func optInt(i *int64) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.FormatInt(*i, 10)
+}
+
+func optFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// readFeedSecret returns the member's secret of feed, creating one if the
+// feed has none yet
+func readFeedSecret(db data.Database, memberNumber int64, feed string) (string, error) {
+	stored, err := db.ReadFeedSecret(memberNumber, feed)
+	if err != nil {
+		return "", err
+	}
+	if stored == nil {
+		secret, err := newFeedSecret(memberNumber, feed)
+		if err != nil {
+			return "", err
+		}
+		if stored, err = db.CreateFeedSecret(secret); err != nil {
+			return "", err
+		}
+	}
+	return stored.Secret, nil
+}
+
+// renewFeedSecret replaces the member's secret of feed, which revokes the
+// former one
+func renewFeedSecret(db data.Database, memberNumber int64, feed string) (string, error) {
+	secret, err := newFeedSecret(memberNumber, feed)
+	if err != nil {
+		return "", err
+	}
+	if err := db.UpdateFeedSecret(secret); err != nil {
+		return "", err
+	}
+	return secret.Secret, nil
+}
+
+// checkFeedSecret tells whether given is the member's current secret of feed
+func checkFeedSecret(db data.Database, memberNumber int64, feed string, given string) (bool, error) {
+	stored, err := db.ReadFeedSecret(memberNumber, feed)
+	if err != nil || stored == nil {
+		return false, err
+	}
+	return auth.CheckFeedSecret(given, stored.Secret), nil
+}
+
+func newFeedSecret(memberNumber int64, feed string) (*models.FeedSecret, error) {
+	secret, err := auth.NewFeedSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &models.FeedSecret{MemberNumber: memberNumber, Feed: feed, Secret: secret, CreatedAt: time.Now()}, nil
+}
@@ -0,0 +1,163 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+func NewFilterHandler(db data.Database) FilterHandler {
+	return FilterHandler{db}
+}
+
+type FilterHandler struct {
+	db data.Database
+}
+
+// writeFilterError answers a failed save of a filter: 400 for an invalid
+// filter, 409 for a name the member already uses and 500 otherwise
+func writeFilterError(w http.ResponseWriter, err error) {
+	var invalid *models.InvalidFilterError
+	switch {
+	case errors.As(err, &invalid):
+		http.Error(w, fmt.Sprintf("invalid filter: %v", err), http.StatusBadRequest)
+	case errors.Is(err, models.ErrFilterNameTaken):
+		http.Error(w, `{"error":"you already have a filter with this name"}`, http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (fh FilterHandler) createFilterHandler(w http.ResponseWriter, r *http.Request) {
+	var f models.SavedFilter
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	member := auth.GetMember(r)
+	f.Id = 0
+	f.MemberNumber = member.Number
+
+	slog.Info(ru.GetRequestId(r), "filter", f.Fmt())
+	filter, err := fh.db.CreateSavedFilter(&f)
+	if err != nil {
+		writeFilterError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filter)
+}
+
+func (fh FilterHandler) readFilterHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := fh.loadFilter(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filter)
+}
+
+func (fh FilterHandler) updateFilterHandler(w http.ResponseWriter, r *http.Request) {
+	existing, ok := fh.loadFilter(w, r)
+	if !ok {
+		return
+	}
+	member := auth.GetMember(r)
+	if existing.MemberNumber != member.Number {
+		http.Error(w, `{"error":"only the owner can change a filter"}`, http.StatusForbidden)
+		return
+	}
+
+	var f models.SavedFilter
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+	f.Id = existing.Id
+	f.MemberNumber = existing.MemberNumber
+
+	slog.Debug(ru.GetRequestId(r), "filter", f.Fmt())
+	filter, err := fh.db.UpdateSavedFilter(&f)
+	if err != nil {
+		writeFilterError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filter)
+}
+
+func (fh FilterHandler) deleteFilterHandler(w http.ResponseWriter, r *http.Request) {
+	existing, ok := fh.loadFilter(w, r)
+	if !ok {
+		return
+	}
+	member := auth.GetMember(r)
+	if existing.MemberNumber != member.Number {
+		http.Error(w, `{"error":"only the owner can delete a filter"}`, http.StatusForbidden)
+		return
+	}
+
+	slog.Debug(ru.GetRequestId(r), "filter", existing.Fmt())
+	filter, err := fh.db.DeleteSavedFilter(existing)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filter)
+}
+
+func (fh FilterHandler) readAllFilterHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	filters, err := fh.db.ReadSavedFilters(member.Number)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filters)
+}
+
+// loadFilter reads the filter in the path and checks that the authenticated
+// member may see it. Writes the error response and returns false otherwise.
+func (fh FilterHandler) loadFilter(w http.ResponseWriter, r *http.Request) (*models.SavedFilter, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return nil, false
+	}
+
+	filter, err := fh.db.ReadSavedFilter(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	member := auth.GetMember(r)
+	if filter == nil || !filter.VisibleTo(member.Number) {
+		http.Error(w, `{"error":"filter not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+	return filter, true
+}
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+func TestWriteFilterError(t *testing.T) {
+	for err, code := range map[error]int{
+		&models.InvalidFilterError{Err: errors.New("RSQL parse error")}: http.StatusBadRequest,
+		models.ErrFilterNameTaken:        http.StatusConflict,
+		errors.New("connection refused"): http.StatusInternalServerError,
+	} {
+		rec := httptest.NewRecorder()
+		writeFilterError(rec, err)
+		assert.Equal(t, code, rec.Code, err.Error())
+	}
+}
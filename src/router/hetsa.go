@@ -0,0 +1,232 @@
+package router
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/mailer"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+const hetsaCodeAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+func NewHetsaHandler(db data.Database) HetsaHandler {
+	return HetsaHandler{db, mailer.NewSMTPSender(config.GetMail())}
+}
+
+type HetsaHandler struct {
+	db   data.Database
+	mail mailer.Sender
+}
+
+// hetsaInvitation is what the invitee sees of an invitation
+type hetsaInvitation struct {
+	Hetsa       models.Hetsa `json:"hetsa"`
+	Namn        *string      `json:"namn"`
+	StartDate   *string      `json:"start_date"`
+	Plats       *string      `json:"plats"`
+	Organisator *string      `json:"organisator"`
+}
+
+// newHetsaCode returns a random invitation code for the mystring column.
+// Bytes above the largest multiple of the alphabet size are skipped, so every
+// character is equally likely.
+func newHetsaCode() (string, error) {
+	const limit = 256 - 256%len(hetsaCodeAlphabet)
+	code := make([]byte, 0, 12)
+	b := make([]byte, 16)
+	for len(code) < cap(code) {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) < limit && len(code) < cap(code) {
+				code = append(code, hetsaCodeAlphabet[int(c)%len(hetsaCodeAlphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// createHetsaHandler invites someone outside the club to an arr by mail
+// POST /db/arr/{id}/hetsa
+// Body: {"name": "...", "email": "...", "comment": "..."}
+func (hh HetsaHandler) createHetsaHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name    string `json:"name"`
+		Email   string `json:"email"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil || req.Name == "" {
+		http.Error(w, `{"error":"name and a valid email required"}`, http.StatusBadRequest)
+		return
+	}
+
+	arr, err := hh.db.ReadArr(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, `{"error":"arr not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	code, err := newHetsaCode()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	hetsa, err := hh.db.CreateHetsa(&models.Hetsa{
+		ArrId:    arr.Id,
+		HetsadBy: member.Number,
+		Name:     req.Name,
+		Email:    address.Address,
+		Comment:  strings.TrimSpace(req.Comment),
+		Code:     code,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if err := hh.mail.Send(hetsaMail(hetsa, arr, member)); err != nil {
+		slog.Error(ru.GetRequestId(r), "failed to send hetsa", hetsa.Fmt(), "error", err)
+		http.Error(w, `{"error":"invitation saved but the mail could not be sent"}`, http.StatusBadGateway)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "hetsa", hetsa.Fmt())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hetsa)
+}
+
+func hetsaMail(hetsa *models.Hetsa, arr *models.Arr, by *models.Member) mailer.Message {
+	url := config.GetHetsa().URL
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	namn := deref(arr.Namn)
+	if namn == "" {
+		namn = "an arr"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", hetsa.Name)
+	fmt.Fprintf(&b, "#%d %s of Chalmers Losers hetsar you to come to %s", by.Number, deref(by.Name), namn)
+	if d := deref(arr.StartDate); d != "" {
+		fmt.Fprintf(&b, " on %s", d)
+	}
+	if p := deref(arr.Plats); p != "" {
+		fmt.Fprintf(&b, " at %s", p)
+	}
+	b.WriteString(".\n\n")
+	if hetsa.Comment != "" {
+		fmt.Fprintf(&b, "%s\n\n", hetsa.Comment)
+	}
+	fmt.Fprintf(&b, "Tell us if you are coming:\n\n%s%scode=%s\n", url, sep, hetsa.Code)
+
+	return mailer.Message{
+		To:      []string{hetsa.Email},
+		Subject: "You are hetsad to " + namn,
+		Body:    b.String(),
+	}
+}
+
+// readHetsaHandler shows an invitation to the invitee
+// GET /hetsa/{code}
+func (hh HetsaHandler) readHetsaHandler(w http.ResponseWriter, r *http.Request) {
+	hetsa, ok := hh.loadHetsa(w, r)
+	if !ok {
+		return
+	}
+	invitation := hetsaInvitation{Hetsa: *hetsa}
+	if arr, err := hh.db.ReadArr(hetsa.ArrId); err == nil {
+		invitation.Namn = arr.Namn
+		invitation.StartDate = arr.StartDate
+		invitation.Plats = arr.Plats
+		invitation.Organisator = arr.Organisator
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitation)
+}
+
+func (hh HetsaHandler) loadHetsa(w http.ResponseWriter, r *http.Request) (*models.Hetsa, bool) {
+	hetsa, err := hh.db.ReadHetsaByCode(mux.Vars(r)["code"])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+	if hetsa == nil {
+		http.Error(w, `{"error":"invitation not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+	return hetsa, true
+}
+
+// acceptHetsaHandler and declineHetsaHandler record the invitee's answer.
+// The invitee may change their mind.
+// POST /hetsa/{code}/accept
+// POST /hetsa/{code}/decline
+func (hh HetsaHandler) acceptHetsaHandler(w http.ResponseWriter, r *http.Request) {
+	hh.respond(w, r, models.HetsaAccepted)
+}
+
+func (hh HetsaHandler) declineHetsaHandler(w http.ResponseWriter, r *http.Request) {
+	hh.respond(w, r, models.HetsaDeclined)
+}
+
+func (hh HetsaHandler) respond(w http.ResponseWriter, r *http.Request, status models.HetsaStatus) {
+	hetsa, err := hh.db.RespondHetsa(mux.Vars(r)["code"], status, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if hetsa == nil {
+		http.Error(w, `{"error":"invitation not found"}`, http.StatusNotFound)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "hetsa", hetsa.Fmt())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hetsa)
+}
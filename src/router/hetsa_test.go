@@ -0,0 +1,129 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type hetsaDatabase struct {
+	*fakeDatabase
+	hetsor []models.Hetsa
+}
+
+func (d *hetsaDatabase) CreateHetsa(h *models.Hetsa) (*models.Hetsa, error) {
+	h.Id = int64(len(d.hetsor) + 1)
+	h.Status = models.HetsaPending
+	d.hetsor = append(d.hetsor, *h)
+	return h, nil
+}
+
+func (d *hetsaDatabase) ReadHetsaByCode(code string) (*models.Hetsa, error) {
+	for i := range d.hetsor {
+		if d.hetsor[i].Code == code {
+			h := d.hetsor[i]
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *hetsaDatabase) RespondHetsa(code string, status models.HetsaStatus, at time.Time) (*models.Hetsa, error) {
+	for i := range d.hetsor {
+		if d.hetsor[i].Code == code {
+			d.hetsor[i].Status, d.hetsor[i].RespondedAt = status, &at
+			h := d.hetsor[i]
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestHetsa(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := &hetsaDatabase{fakeDatabase: newFakeDatabase()}
+	namn, plats := "Fest", "Hubben"
+	db.arrs = map[int64]models.Arr{3: {Id: 3, Namn: &namn, Plats: &plats}}
+	sender := &recordingSender{}
+	hh := HetsaHandler{db: db, mail: sender}
+
+	createFor := func(id string, body string) *httptest.ResponseRecorder {
+		token, err := auth.GenerateJWT(8, "member@example.com", []string{auth.WriteArrScope}, "test", []byte(testJWTSecret))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.NewMiddleware(db).RequireAuth(http.HandlerFunc(hh.createHetsaHandler)).ServeHTTP(rec, req)
+		return rec
+	}
+	create := func(body string) *httptest.ResponseRecorder {
+		return createFor("3", body)
+	}
+	public := func(handler http.HandlerFunc, code string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req = mux.SetURLVars(req, map[string]string{"code": code})
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, create(`{"name":"Gäst","email":"not an email"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"name":" ","email":"gast@example.com"}`).Code)
+	assert.Equal(t, http.StatusNotFound, createFor("4", `{"name":"Gäst","email":"gast@example.com"}`).Code)
+	assert.Empty(t, sender.sent)
+
+	rec := create(`{"name":"Gäst","email":"Gäst <gast@example.com>","comment":"Ta med öl"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "gast@example.com")
+	require.Len(t, db.hetsor, 1)
+	hetsa := db.hetsor[0]
+	assert.Equal(t, int64(3), hetsa.ArrId)
+	assert.Equal(t, int64(8), hetsa.HetsadBy)
+	assert.Equal(t, "gast@example.com", hetsa.Email)
+	assert.Regexp(t, `^[a-z0-9]{12}$`, hetsa.Code)
+
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, []string{"gast@example.com"}, sender.sent[0].To)
+	assert.Contains(t, sender.sent[0].Body, "Fest")
+	assert.Contains(t, sender.sent[0].Body, "Ta med öl")
+	assert.Contains(t, sender.sent[0].Body, "code="+hetsa.Code)
+
+	rec = public(hh.readHetsaHandler, hetsa.Code)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var invitation hetsaInvitation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invitation))
+	assert.Equal(t, "Hubben", deref(invitation.Plats))
+
+	require.Equal(t, http.StatusOK, public(hh.acceptHetsaHandler, hetsa.Code).Code)
+	assert.Equal(t, models.HetsaAccepted, db.hetsor[0].Status)
+	require.Equal(t, http.StatusOK, public(hh.declineHetsaHandler, hetsa.Code).Code)
+	assert.Equal(t, models.HetsaDeclined, db.hetsor[0].Status)
+
+	assert.Equal(t, http.StatusNotFound, public(hh.readHetsaHandler, "unknown").Code)
+	assert.Equal(t, http.StatusNotFound, public(hh.acceptHetsaHandler, "unknown").Code)
+}
+
+func TestNewHetsaCode(t *testing.T) {
+	seen := map[rune]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := newHetsaCode()
+		require.NoError(t, err)
+		assert.Regexp(t, `^[a-z0-9]{12}$`, code)
+		for _, c := range code {
+			seen[c] = true
+		}
+	}
+	// 1200 characters cover the whole alphabet
+	assert.Len(t, seen, len(hetsaCodeAlphabet))
+}
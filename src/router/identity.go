@@ -0,0 +1,194 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+var errEmailNotVerified = errors.New("email not verified")
+
+// findMember resolves the member of a provider login: first by an identity
+// linked to the provider account, then by verified email. Email logins do not
+// link the account; that is only done through linkIdentity, so an unlinked
+// account or a changed email does not keep access. Returns nil if no member
+// matches.
+func (h *AuthHandler) findMember(provider string, userInfo *auth.UserInfo) (*models.Member, error) {
+	if userInfo.ProviderUserID != "" {
+		identity, err := h.db.ReadIdentity(provider, userInfo.ProviderUserID)
+		if err != nil {
+			return nil, err
+		}
+		if identity != nil {
+			member, err := h.db.ReadMemberByNumber(identity.MemberNumber)
+			if err == nil && member.Isvalid != nil && *member.Isvalid {
+				if err := h.db.UpdateIdentityLogin(identity.Id, userInfo.Email, time.Now()); err != nil {
+					slog.Warn("failed to record identity login", "identity", identity.Id, "error", err)
+				}
+				return member, nil
+			}
+			slog.Warn("identity of unknown or invalid member", "provider", provider, "member_number", identity.MemberNumber)
+		}
+	}
+
+	if !userInfo.EmailVerified {
+		return nil, errEmailNotVerified
+	}
+	return h.db.ReadMemberByEmail(userInfo.Email)
+}
+
+// linkIdentity finishes a web flow started from /auth/identities/link by
+// linking the provider account to the member who started it
+func (h *AuthHandler) linkIdentity(w http.ResponseWriter, r *http.Request, authState *models.AuthState, userInfo *auth.UserInfo) {
+	fail := func(code string, description string, status int) {
+		if authState.RedirectURI != "" {
+			redirectURL := authState.RedirectURI + "?error=" + code + "&error_description=" + url.QueryEscape(description)
+			http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, description), status)
+	}
+
+	number := *authState.LinkMemberNumber
+	if userInfo.ProviderUserID == "" {
+		fail("invalid_identity", "provider returned no user id", http.StatusBadGateway)
+		return
+	}
+
+	identity, err := h.db.ReadIdentity(authState.Provider, userInfo.ProviderUserID)
+	if err != nil {
+		slog.Error("failed to read identity", "error", err)
+		fail("server_error", "database error", http.StatusInternalServerError)
+		return
+	}
+	if identity != nil && identity.MemberNumber != number {
+		slog.Warn("identity already linked", "provider", authState.Provider, "member_number", identity.MemberNumber, "requested_by", number)
+		fail("identity_taken", "account is linked to another member", http.StatusConflict)
+		return
+	}
+	if identity == nil {
+		identity, err = h.db.CreateIdentity(&models.Identity{
+			MemberNumber:   number,
+			Provider:       authState.Provider,
+			ProviderUserID: userInfo.ProviderUserID,
+			Email:          userInfo.Email,
+		})
+		if err != nil {
+			slog.Error("failed to link identity", "error", err)
+			fail("server_error", "database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	slog.Info("identity linked", "provider", authState.Provider, "member_number", number, "email", userInfo.Email)
+
+	if authState.RedirectURI != "" {
+		redirectURL := authState.RedirectURI + "?linked=" + url.QueryEscape(authState.Provider)
+		http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identity)
+}
+
+// ReadIdentities lists the login identities of the authenticated member
+// GET /auth/identities
+func (h *AuthHandler) ReadIdentities(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.db.ReadIdentities(member.Number)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identities)
+}
+
+// LinkIdentity starts a web flow that links a provider account to the
+// authenticated member. The client sends the user to the returned url; the
+// callback redirects to redirect_uri with linked=<provider> or an error.
+// POST /auth/identities/link
+// Body: {"provider": "github", "redirect_uri": "https://..."}
+func (h *AuthHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Provider    string `json:"provider"`
+		RedirectURI string `json:"redirect_uri"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Provider == "" {
+		http.Error(w, `{"error":"provider required"}`, http.StatusBadRequest)
+		return
+	}
+
+	number := member.Number
+	authURL, status, err := h.beginWebFlow(req.Provider, req.RedirectURI, &number)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": authURL})
+}
+
+// UnlinkIdentity removes one of the authenticated member's identities.
+// Email login keeps working regardless.
+// DELETE /auth/identities/{id}
+func (h *AuthHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	identities, err := h.db.ReadIdentities(member.Number)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	for _, identity := range identities {
+		if identity.Id != id {
+			continue
+		}
+		if _, err := h.db.DeleteIdentity(&identity); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		slog.Info(ru.GetRequestId(r), "identity unlinked", identity.Fmt())
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Error(w, `{"error":"identity not found"}`, http.StatusNotFound)
+}
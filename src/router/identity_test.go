@@ -0,0 +1,94 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type identityDatabase struct {
+	*fakeDatabase
+	identities []models.Identity
+}
+
+func (i *identityDatabase) ReadIdentity(provider string, providerUserID string) (*models.Identity, error) {
+	for _, identity := range i.identities {
+		if identity.Provider == provider && identity.ProviderUserID == providerUserID {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (i *identityDatabase) CreateIdentity(identity *models.Identity) (*models.Identity, error) {
+	identity.Id = int64(len(i.identities) + 1)
+	i.identities = append(i.identities, *identity)
+	return identity, nil
+}
+
+func (i *identityDatabase) UpdateIdentityLogin(id int64, email string, at time.Time) error {
+	return nil
+}
+
+func newIdentityDatabase() *identityDatabase {
+	db := &identityDatabase{fakeDatabase: newCardDAVDatabase()}
+	github := "github@example.com"
+	db.members[1].Email = &github
+	return db
+}
+
+func TestFindMember_ByLinkedIdentity(t *testing.T) {
+	db := newIdentityDatabase()
+	db.identities = []models.Identity{{Id: 1, MemberNumber: 8, Provider: "github", ProviderUserID: "1234"}}
+	h := NewAuthHandler(db)
+
+	// The provider email belongs to another member and is not even verified
+	member, err := h.findMember("github", &auth.UserInfo{ProviderUserID: "1234", Email: "github@example.com"})
+	require.NoError(t, err)
+	require.NotNil(t, member)
+	assert.Equal(t, int64(8), member.Number)
+
+	// The same user id at another provider is a different account
+	_, err = h.findMember("google", &auth.UserInfo{ProviderUserID: "1234", Email: "github@example.com"})
+	assert.ErrorIs(t, err, errEmailNotVerified)
+}
+
+func TestFindMember_EmailFallback(t *testing.T) {
+	db := newIdentityDatabase()
+	h := NewAuthHandler(db)
+
+	member, err := h.findMember("google", &auth.UserInfo{ProviderUserID: "g-9", Email: "github@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	require.NotNil(t, member)
+	assert.Equal(t, int64(9), member.Number)
+
+	// Email logins do not link the account
+	assert.Empty(t, db.identities)
+
+	// Without a link, access ends when the email of the member changes
+	other := "other@example.com"
+	db.members[1].Email = &other
+	member, err = h.findMember("google", &auth.UserInfo{ProviderUserID: "g-9", Email: "github@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Nil(t, member)
+
+	member, err = h.findMember("google", &auth.UserInfo{ProviderUserID: "g-0", Email: "nobody@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Nil(t, member)
+}
+
+func TestFindMember_InvalidMemberIdentity(t *testing.T) {
+	db := newIdentityDatabase()
+	invalid := false
+	db.members[0].Isvalid = &invalid
+	db.identities = []models.Identity{{Id: 1, MemberNumber: 8, Provider: "github", ProviderUserID: "1234"}}
+
+	member, err := NewAuthHandler(db).findMember("github", &auth.UserInfo{ProviderUserID: "1234", Email: "unknown@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Nil(t, member)
+}
@@ -2,18 +2,28 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/avatar"
+	"github.com/sebastiw/sidan-backend/src/config"
 	"github.com/sebastiw/sidan-backend/src/data"
 	"github.com/sebastiw/sidan-backend/src/models"
 	ru "github.com/sebastiw/sidan-backend/src/router_util"
+	"github.com/sebastiw/sidan-backend/src/views"
 )
 
+// maxAvatarUpload is the largest accepted avatar upload, same as /file/image
+const maxAvatarUpload = 10 << 20
+
 func NewMemberHandler(db data.Database) MemberHandler {
 	return MemberHandler{db}
 }
@@ -22,7 +32,13 @@ type MemberHandler struct {
 	db data.Database
 }
 
+// createMemberHandler adds a member. Only administrators may.
 func (mh MemberHandler) createMemberHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.HasScope(r, auth.AdminScope) {
+		http.Error(w, `{"error":"creating members requires admin scope"}`, http.StatusForbidden)
+		return
+	}
+
 	var m models.Member
 	_ = json.NewDecoder(r.Body).Decode(&m)
 
@@ -31,82 +47,202 @@ func (mh MemberHandler) createMemberHandler(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(member)
+	json.NewEncoder(w).Encode(views.NewMember(*member, memberAudience(r, member.Number)))
 }
 
 func (mh MemberHandler) readMemberHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+	member, ok := mh.loadMember(w, r)
+	if !ok {
+		return
 	}
+	WriteJSONConditional(w, r, views.NewMember(*member, memberAudience(r, member.Number)), time.Time{})
+}
 
-	member, err := mh.db.ReadMember(id)
+func (mh MemberHandler) readMemberUnauthedHandler(w http.ResponseWriter, r *http.Request) {
+	member, ok := mh.loadMember(w, r)
+	if !ok {
+		return
+	}
+	WriteJSONConditional(w, r, views.NewMember(*member, views.Public), time.Time{})
+}
+
+// readMemberEventsHandler returns membership events after an id, oldest
+// first, so that consumers can follow them
+// GET /db/members/events?after=0&take=100&member=8
+func (mh MemberHandler) readMemberEventsHandler(w http.ResponseWriter, r *http.Request) {
+	after := MakeDefaultInt(r, "after", "0")
+	take := MakeDefaultInt(r, "take", "100")
+	member := MakeDefaultInt(r, "member", "0")
+
+	events, err := mh.db.ReadMemberEvents(int64(after), take, int64(member))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(member)
+	json.NewEncoder(w).Encode(events)
 }
 
-func (mh MemberHandler) readMemberUnauthedHandler(w http.ResponseWriter, r *http.Request) {
+func (mh MemberHandler) loadMember(w http.ResponseWriter, r *http.Request) (*models.Member, bool) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return nil, false
 	}
 
 	member, err := mh.db.ReadMember(id)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+	return member, true
+}
+
+// updateMemberHandler lets administrators edit any member. Other members may
+// only edit their own self-service fields, see patchMeHandler.
+func (mh MemberHandler) updateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	var m models.Member
+	_ = json.NewDecoder(r.Body).Decode(&m)
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+
+	if !auth.HasScope(r, auth.AdminScope) {
+		caller := auth.GetMember(r)
+		if caller == nil || caller.Id != int64(id) {
+			http.Error(w, `{"error":"editing other members requires admin scope"}`, http.StatusForbidden)
+			return
+		}
+		if fields := adminOnlyFields(m); len(fields) > 0 {
+			http.Error(w, fmt.Sprintf(`{"error":"changing %s requires admin scope"}`, strings.Join(fields, ", ")), http.StatusForbidden)
+			return
+		}
 	}
 
-	b, err := json.Marshal(member)
+	if fe := validateMember(m); len(fe) > 0 {
+		writeFieldErrors(w, fe)
+		return
+	}
+
+	slog.Debug(ru.GetRequestId(r), "member", m.Fmt())
+	m.Id = int64(id)
+	member, err := mh.db.UpdateMember(&m)
 	if err != nil {
-		slog.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views.NewMember(*member, memberAudience(r, member.Number)))
+}
+
+// readMeHandler returns the authenticated member
+// GET /me
+func (mh MemberHandler) readMeHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	WriteJSONConditional(w, r, views.NewMember(*member, views.Self), time.Time{})
+}
+
+// patchMeHandler changes the authenticated member's phone, address, im,
+// picture and history. Any other field is rejected.
+// PATCH /me
+func (mh MemberHandler) patchMeHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
 	}
 
-	liteMemberData := models.MemberLite{}
+	var patch models.MemberPatch
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patch); err != nil {
+		http.Error(w, `{"error":"only phone, address, im, picture and history can be changed"}`, http.StatusBadRequest)
+		return
+	}
+
+	if fe := validateMemberPatch(patch); len(fe) > 0 {
+		writeFieldErrors(w, fe)
+		return
+	}
 
-	err = json.Unmarshal(b, &liteMemberData)
+	slog.Info(ru.GetRequestId(r), "patch member", member.Number)
+	updated, err := mh.db.PatchMember(member.Id, patch)
 	if err != nil {
-		slog.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(liteMemberData)
+	json.NewEncoder(w).Encode(views.NewMember(*updated, views.Self))
 }
 
-func (mh MemberHandler) updateMemberHandler(w http.ResponseWriter, r *http.Request) {
-	var m models.Member
-	_ = json.NewDecoder(r.Body).Decode(&m)
+// updateMePictureHandler replaces the authenticated member's avatar with
+// the uploaded image (multipart field data)
+// PUT /me/picture
+func (mh MemberHandler) updateMePictureHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
 
-	vars := mux.Vars(r)
-	id, _ := strconv.Atoi(vars["id"])
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarUpload)
+	file, _, err := r.FormFile("data")
+	if err != nil {
+		http.Error(w, `{"error":"missing image in form field data"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
 
-	slog.Debug(ru.GetRequestId(r), "member", m.Fmt())
-	m.Id = int64(id)
-	member, err := mh.db.UpdateMember(&m)
+	res, err := avatar.Process(file, config.GetServer().StaticPath, member.Number)
+	if errors.Is(err, avatar.ErrUnsupported) || errors.Is(err, avatar.ErrTooLarge) {
+		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "avatar", member.Number, "picture", res.Canonical)
+	updated, err := mh.db.PatchMember(member.Id, models.MemberPatch{Picture: &res.Canonical})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if err := res.RemoveOlder(); err != nil {
+		slog.Warn(ru.GetRequestId(r), "unable to remove old avatars", err.Error())
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(member)
+	json.NewEncoder(w).Encode(views.NewMember(*updated, views.Self))
 }
 
+// deleteMemberHandler removes a member. Only administrators may.
 func (mh MemberHandler) deleteMemberHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.HasScope(r, auth.AdminScope) {
+		http.Error(w, `{"error":"deleting members requires admin scope"}`, http.StatusForbidden)
+		return
+	}
+
 	var m models.Member
 	_ = json.NewDecoder(r.Body).Decode(&m)
 
@@ -119,46 +255,40 @@ func (mh MemberHandler) deleteMemberHandler(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(member)
+	json.NewEncoder(w).Encode(views.NewMember(*member, memberAudience(r, member.Number)))
 }
 
 func (mh MemberHandler) readAllMemberHandler(w http.ResponseWriter, r *http.Request) {
-	onlyValid := MakeDefaultBool(r, "onlyValid", "false")
-	members, err := mh.db.ReadMembers(onlyValid)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+	members, ok := mh.loadMembers(w, r)
+	if !ok {
+		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(members)
+	WriteJSONConditional(w, r, views.NewMembers(members, func(m models.Member) views.Audience {
+		return memberAudience(r, m.Number)
+	}), time.Time{})
 }
 
 func (mh MemberHandler) readAllMemberUnauthedHandler(w http.ResponseWriter, r *http.Request) {
+	members, ok := mh.loadMembers(w, r)
+	if !ok {
+		return
+	}
+	WriteJSONConditional(w, r, views.NewMembers(members, func(models.Member) views.Audience {
+		return views.Public
+	}), time.Time{})
+}
+
+func (mh MemberHandler) loadMembers(w http.ResponseWriter, r *http.Request) ([]models.Member, bool) {
 	onlyValid := MakeDefaultBool(r, "onlyValid", "false")
 	members, err := mh.db.ReadMembers(onlyValid)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
 	}
-
-	b, err := json.Marshal(members)
-	if err != nil {
-		slog.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-
-	liteMemberData := []models.MemberLite{}
-
-	err = json.Unmarshal(b, &liteMemberData)
-	if err != nil {
-		slog.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(liteMemberData)
+	return members, true
 }
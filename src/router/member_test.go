@@ -0,0 +1,160 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type pictureDatabase struct {
+	*fakeDatabase
+	patched *models.MemberPatch
+}
+
+func (p *pictureDatabase) PatchMember(id int64, patch models.MemberPatch) (*models.Member, error) {
+	p.patched = &patch
+	m, err := p.ReadMember(id)
+	if err != nil {
+		return nil, err
+	}
+	m.Picture = patch.Picture
+	return m, nil
+}
+
+func pictureRequest(t *testing.T, body []byte) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("data", "avatar.png")
+	require.NoError(t, err)
+	part.Write(body)
+	require.NoError(t, mw.Close())
+
+	token, err := auth.GenerateJWT(8, "member@example.com", []string{auth.WriteImageScope}, "test", []byte(testJWTSecret))
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPut, "/me/picture", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestUpdateMePictureHandler(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	static := config.GetServer().StaticPath
+	config.GetServer().StaticPath = t.TempDir()
+	defer func() { config.GetServer().StaticPath = static }()
+
+	db := &pictureDatabase{fakeDatabase: newFakeDatabase()}
+	handler := auth.NewMiddleware(db).RequireAuth(http.HandlerFunc(NewMemberHandler(db).updateMePictureHandler))
+
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 300, 200))))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, pictureRequest(t, img.Bytes()))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NotNil(t, db.patched)
+	assert.Regexp(t, `^avatars/8/[0-9a-f]+-256\.png$`, *db.patched.Picture)
+	assert.Contains(t, rec.Body.String(), *db.patched.Picture)
+	_, err := os.Stat(filepath.Join(config.GetServer().StaticPath, *db.patched.Picture))
+	assert.NoError(t, err)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, pictureRequest(t, []byte("not an image")))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+type adminMemberDatabase struct {
+	*fakeDatabase
+	created []models.Member
+	updated []models.Member
+	deleted []int64
+}
+
+func (d *adminMemberDatabase) CreateMember(m *models.Member) (*models.Member, error) {
+	d.created = append(d.created, *m)
+	return m, nil
+}
+
+func (d *adminMemberDatabase) UpdateMember(m *models.Member) (*models.Member, error) {
+	d.updated = append(d.updated, *m)
+	return m, nil
+}
+
+func (d *adminMemberDatabase) DeleteMember(m *models.Member) (*models.Member, error) {
+	d.deleted = append(d.deleted, m.Id)
+	return m, nil
+}
+
+func TestCreateDeleteMemberRequiresAdmin(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := &adminMemberDatabase{fakeDatabase: newFakeDatabase()}
+	mh := NewMemberHandler(db)
+
+	do := func(handler http.HandlerFunc, method string, scopes ...string) int {
+		token, err := auth.GenerateJWT(8, "member@example.com", scopes, "test", []byte(testJWTSecret))
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, "/db/members", bytes.NewBufferString(`{"number":1234}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "2"})
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.NewMiddleware(db).RequireAuth(handler).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// write:member is given to every member, it is not enough
+	assert.Equal(t, http.StatusForbidden, do(mh.createMemberHandler, http.MethodPost, auth.WriteMemberScope))
+	assert.Equal(t, http.StatusForbidden, do(mh.deleteMemberHandler, http.MethodDelete, auth.WriteMemberScope))
+	assert.Empty(t, db.created)
+	assert.Empty(t, db.deleted)
+
+	assert.Equal(t, http.StatusOK, do(mh.createMemberHandler, http.MethodPost, auth.WriteMemberScope, auth.AdminScope))
+	assert.Equal(t, http.StatusOK, do(mh.deleteMemberHandler, http.MethodDelete, auth.WriteMemberScope, auth.AdminScope))
+	require.Len(t, db.created, 1)
+	assert.Equal(t, int64(1234), db.created[0].Number)
+	assert.Equal(t, []int64{2}, db.deleted)
+}
+
+func TestUpdateMemberRefusesPasswords(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := &adminMemberDatabase{fakeDatabase: newFakeDatabase()}
+	mh := NewMemberHandler(db)
+
+	do := func(body string, scopes ...string) int {
+		token, err := auth.GenerateJWT(8, "member@example.com", scopes, "test", []byte(testJWTSecret))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/db/members/1", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.NewMiddleware(db).RequireAuth(http.HandlerFunc(mh.updateMemberHandler)).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Member #8 has id 1, so these are edits of the caller's own member
+	for _, field := range []string{"password", "password_classic", "password_resetstring", "password_classic_resetstring"} {
+		body := fmt.Sprintf(`{"%s":"hemligt"}`, field)
+		assert.Equal(t, http.StatusBadRequest, do(body, auth.WriteMemberScope), field)
+		assert.Equal(t, http.StatusBadRequest, do(body, auth.WriteMemberScope, auth.AdminScope), field)
+	}
+	assert.Empty(t, db.updated)
+
+	assert.Equal(t, http.StatusOK, do(`{"phone":"031-123456"}`, auth.WriteMemberScope))
+	require.Len(t, db.updated, 1)
+	assert.Nil(t, db.updated[0].Password)
+}
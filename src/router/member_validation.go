@@ -0,0 +1,118 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+var (
+	phonePattern   = regexp.MustCompile(`^\+?[0-9][0-9 ()/-]{2,29}$`)
+	emailPattern   = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	picturePattern = regexp.MustCompile(`^[A-Za-z0-9._~/-]+$`)
+)
+
+// fieldErrors maps JSON field names to what is wrong with them
+type fieldErrors map[string]string
+
+func (fe fieldErrors) maxLength(field string, value *string, max int) {
+	if value != nil && utf8.RuneCountInString(*value) > max {
+		fe[field] = "too long"
+	}
+}
+
+func (fe fieldErrors) pattern(field string, value *string, re *regexp.Regexp) {
+	// Empty clears the field and is always allowed
+	if value != nil && *value != "" && !re.MatchString(*value) {
+		fe[field] = "invalid format"
+	}
+}
+
+func (fe fieldErrors) picture(value *string) {
+	if value == nil || *value == "" {
+		return
+	}
+	v := *value
+	if strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "http://") {
+		if strings.ContainsAny(v, " \t\r\n\"'<>") {
+			fe["picture"] = "invalid url"
+		}
+	} else if !picturePattern.MatchString(v) || strings.Contains(v, "..") {
+		fe["picture"] = "must be an uploaded file name or an http(s) url"
+	}
+	fe.maxLength("picture", value, 1024)
+}
+
+// validateMemberPatch checks the fields a member may change on themself.
+// Lengths follow the cl2007_members columns.
+func validateMemberPatch(p models.MemberPatch) fieldErrors {
+	fe := fieldErrors{}
+	fe.pattern("phone", p.Phone, phonePattern)
+	fe.maxLength("address", p.Adress, 511)
+	fe.maxLength("im", p.Im, 100)
+	fe.picture(p.Picture)
+	fe.maxLength("history", p.History, 65535)
+	return fe
+}
+
+// validateMember checks a full member update made by an administrator
+func validateMember(m models.Member) fieldErrors {
+	fe := validateMemberPatch(models.MemberPatch{
+		Phone:   m.Phone,
+		Adress:  m.Adress,
+		Im:      &m.Im,
+		Picture: m.Picture,
+		History: m.History,
+	})
+	// 0 keeps the number, or gives the next free one to a new member
+	if m.Number < 0 || m.Number > 9999 {
+		fe["number"] = "must be between 1 and 9999, or 0 to keep it"
+	}
+	fe.maxLength("name", m.Name, 255)
+	fe.pattern("email", m.Email, emailPattern)
+	fe.maxLength("email", m.Email, 255)
+	fe.maxLength("title", m.Title, 255)
+	fe.maxLength("address_url", m.Adressurl, 65535)
+	if m.Password != nil || m.Password_classic != nil || m.Password_classic_resetstring != nil || m.Password_resetstring != nil {
+		fe["password"] = "passwords cannot be set here"
+	}
+	return fe
+}
+
+// adminOnlyFields returns the fields set in m that members cannot change on
+// themselves
+func adminOnlyFields(m models.Member) []string {
+	var fields []string
+	if m.Number != 0 {
+		fields = append(fields, "number")
+	}
+	if m.Isvalid != nil {
+		fields = append(fields, "is_valid")
+	}
+	if m.Name != nil {
+		fields = append(fields, "name")
+	}
+	if m.Email != nil {
+		fields = append(fields, "email")
+	}
+	if m.Title != nil {
+		fields = append(fields, "title")
+	}
+	if m.Adressurl != nil {
+		fields = append(fields, "address_url")
+	}
+	return fields
+}
+
+func writeFieldErrors(w http.ResponseWriter, fe fieldErrors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "invalid fields",
+		"fields": fe,
+	})
+}
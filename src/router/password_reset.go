@@ -0,0 +1,153 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/mailer"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// RequestPasswordReset mails a single-use reset link to the member with the
+// given email or username. The member is looked up and mailed after the
+// response, which is the same and as fast whether or not a member matched,
+// so it cannot be used to find out who is a member.
+// POST /auth/password/reset
+// Body: {"email": "..."} or {"username": "#8"}
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Email == "" && req.Username == "") {
+		http.Error(w, `{"error":"email or username required"}`, http.StatusBadRequest)
+		return
+	}
+
+	h.resets.Add(1)
+	go func() {
+		defer h.resets.Done()
+		member, err := h.findResetMember(req.Email, req.Username)
+		if err != nil {
+			slog.Error("failed to read member for password reset", "error", err)
+		}
+		if member != nil {
+			if err := h.sendPasswordReset(member); err != nil {
+				slog.Error("failed to send password reset", "member_number", member.Number, "error", err)
+			} else {
+				slog.Info("password reset sent", "member_number", member.Number)
+			}
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// findResetMember returns the valid member with an email matching the
+// request, or nil
+func (h *AuthHandler) findResetMember(email string, username string) (*models.Member, error) {
+	if email != "" {
+		return h.db.ReadMemberByEmail(email)
+	}
+	number, ok := parseClassicUsername(username)
+	if !ok {
+		return nil, nil
+	}
+	member, err := h.db.ReadMemberByNumber(number)
+	if err != nil || member == nil || member.Isvalid == nil || !*member.Isvalid {
+		return nil, nil
+	}
+	return member, nil
+}
+
+func (h *AuthHandler) sendPasswordReset(member *models.Member) error {
+	email := deref(member.Email)
+	if email == "" {
+		return fmt.Errorf("member has no email")
+	}
+
+	cfg := config.GetPassword()
+	ttl := time.Duration(cfg.ResetMinutes) * time.Minute
+	token, stored := auth.GenerateResetToken(member.Number, time.Now().Add(ttl))
+	if err := h.db.SetPasswordResetString(member.Number, stored); err != nil {
+		return err
+	}
+
+	sep := "?"
+	if strings.Contains(cfg.ResetURL, "?") {
+		sep = "&"
+	}
+	link := cfg.ResetURL + sep + "token=" + url.QueryEscape(token)
+	return h.mail.Send(mailer.Message{
+		To:      []string{email},
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi #%d,\n\n"+
+			"Someone asked to reset the password of your account. Follow the link\n"+
+			"below to choose a new password. The link works once, for %d minutes.\n\n"+
+			"%s\n\n"+
+			"If it was not you, ignore this mail and your password stays the same.\n",
+			member.Number, cfg.ResetMinutes, link),
+	})
+}
+
+// ConfirmPasswordReset sets a new password with a token from a reset mail,
+// and logs the member out everywhere
+// POST /auth/password/reset/confirm
+// Body: {"token": "...", "new_password": "..."}
+func (h *AuthHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, `{"error":"token required"}`, http.StatusBadRequest)
+		return
+	}
+
+	invalid := func() {
+		http.Error(w, `{"error":"invalid or expired token"}`, http.StatusBadRequest)
+	}
+	number, ok := auth.ParseResetToken(req.Token)
+	if !ok {
+		invalid()
+		return
+	}
+	member, err := h.db.ReadMemberByNumber(number)
+	if err != nil || member == nil || member.Password_resetstring == nil ||
+		!auth.CheckResetToken(req.Token, *member.Password_resetstring, time.Now()) {
+		invalid()
+		return
+	}
+
+	if err := config.GetPasswordPolicy().Validate(req.NewPassword); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	done, err := h.db.ResetMemberPassword(number, *member.Password_resetstring, hash)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if !done {
+		invalid()
+		return
+	}
+
+	slog.Info("password reset", "member_number", number)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/mailer"
+)
+
+type recordingSender struct {
+	sent []mailer.Message
+}
+
+func (s *recordingSender) Send(m mailer.Message) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+type resetDatabase struct {
+	*classicDatabase
+	revoked []int64
+}
+
+func (d *resetDatabase) SetPasswordResetString(number int64, reset string) error {
+	for i := range d.members {
+		if d.members[i].Number == number {
+			d.members[i].Password_resetstring = &reset
+		}
+	}
+	return nil
+}
+
+func (d *resetDatabase) ResetMemberPassword(number int64, reset string, hash string) (bool, error) {
+	for i := range d.members {
+		m := &d.members[i]
+		if m.Number == number && m.Password_resetstring != nil && *m.Password_resetstring == reset {
+			m.Password, m.Password_classic, m.Password_resetstring = &hash, nil, nil
+			d.revoked = append(d.revoked, number)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	password := config.GetPassword()
+	saved := *password
+	password.Policy, password.ResetURL, password.ResetMinutes = "medium", "https://example.com/reset", 60
+	defer func() { *password = saved }()
+
+	db := &resetDatabase{classicDatabase: newClassicDatabase()}
+	sender := &recordingSender{}
+	h := &AuthHandler{db: db, mail: sender}
+
+	post := func(handler http.HandlerFunc, body string) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	// Unknown emails get the same answer, but no mail
+	assert.Equal(t, http.StatusAccepted, post(h.RequestPasswordReset, `{"email":"nobody@example.com"}`))
+	h.resets.Wait()
+	assert.Empty(t, sender.sent)
+
+	assert.Equal(t, http.StatusAccepted, post(h.RequestPasswordReset, `{"email":"member@example.com"}`))
+	h.resets.Wait()
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, []string{"member@example.com"}, sender.sent[0].To)
+	link := regexp.MustCompile(`https://example\.com/reset\?token=\S+`).FindString(sender.sent[0].Body)
+	require.NotEmpty(t, link)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+
+	// The token itself is never stored
+	require.NotNil(t, db.members[0].Password_resetstring)
+	assert.NotContains(t, *db.members[0].Password_resetstring, token)
+
+	assert.Equal(t, http.StatusBadRequest, post(h.ConfirmPasswordReset, `{"token":"8.wrong","new_password":"Nytt1234"}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, post(h.ConfirmPasswordReset, `{"token":"`+token+`","new_password":"kort"}`))
+	assert.Equal(t, http.StatusNoContent, post(h.ConfirmPasswordReset, `{"token":"`+token+`","new_password":"Nytt1234"}`))
+	assert.Equal(t, []int64{8}, db.revoked)
+	ok, _ := auth.CheckPassword(&db.members[0], "Nytt1234")
+	assert.True(t, ok)
+
+	// Single use
+	assert.Equal(t, http.StatusBadRequest, post(h.ConfirmPasswordReset, `{"token":"`+token+`","new_password":"Annat1234"}`))
+}
+
+type blockingSender struct {
+	release chan struct{}
+}
+
+func (s *blockingSender) Send(m mailer.Message) error {
+	<-s.release
+	return nil
+}
+
+func TestPasswordReset_AnswersBeforeSending(t *testing.T) {
+	sender := &blockingSender{release: make(chan struct{})}
+	h := &AuthHandler{db: &resetDatabase{classicDatabase: newClassicDatabase()}, mail: sender}
+
+	// The answer does not wait for the mail, so it takes as long as for
+	// unknown emails
+	rec := httptest.NewRecorder()
+	h.RequestPasswordReset(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email":"member@example.com"}`)))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	close(sender.release)
+	h.resets.Wait()
+}
@@ -0,0 +1,305 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+const (
+	maxPollOptions   = 10
+	maxPollThemeLen  = 255
+	maxPollOptionLen = 50
+	defaultPollYae   = "Ja"
+	defaultPollNay   = "Nej"
+)
+
+func NewPollHandler(db data.Database) PollHandler {
+	return PollHandler{db}
+}
+
+type PollHandler struct {
+	db data.Database
+}
+
+// pollRequest creates or changes a poll. Options replaces yae and nay when
+// given: the first two become yae and nay and the rest extra options.
+type pollRequest struct {
+	Theme   string   `json:"theme"`
+	Yae     string   `json:"yae"`
+	Nay     string   `json:"nay"`
+	Options []string `json:"options"`
+}
+
+// poll validates the request and returns it as a poll
+func (pr pollRequest) poll() (*models.Poll, fieldErrors) {
+	fe := fieldErrors{}
+	poll := &models.Poll{Theme: strings.TrimSpace(pr.Theme)}
+	if poll.Theme == "" {
+		fe["theme"] = "required"
+	} else if utf8.RuneCountInString(poll.Theme) > maxPollThemeLen {
+		fe["theme"] = "too long"
+	}
+
+	options := pr.Options
+	if len(options) == 0 {
+		options = []string{pr.Yae, pr.Nay}
+		if strings.TrimSpace(pr.Yae) == "" {
+			options[0] = defaultPollYae
+		}
+		if strings.TrimSpace(pr.Nay) == "" {
+			options[1] = defaultPollNay
+		}
+	} else if len(options) < 2 || len(options) > maxPollOptions {
+		fe["options"] = fmt.Sprintf("between 2 and %d options", maxPollOptions)
+		return poll, fe
+	}
+
+	for i, o := range options {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			fe["options"] = "options cannot be empty"
+		} else if utf8.RuneCountInString(o) > maxPollOptionLen {
+			fe["options"] = "too long"
+		}
+		switch i {
+		case 0:
+			poll.Yae = o
+		case 1:
+			poll.Nay = o
+		default:
+			poll.Extra = append(poll.Extra, models.PollOption{Text: o})
+		}
+	}
+	return poll, fe
+}
+
+// createPollHandler starts a new poll
+// POST /polls
+// Body: {"theme": "Supa?", "yae": "Ja", "nay": "Nej"} or {"theme": "...", "options": ["a", "b", "c"]}
+func (ph PollHandler) createPollHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req pollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+		return
+	}
+	p, fe := req.poll()
+	if len(fe) > 0 {
+		writeFieldErrors(w, fe)
+		return
+	}
+	p.CreatedBy = &member.Number
+
+	poll, err := ph.db.CreatePoll(p)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "poll", poll.Fmt())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(poll)
+}
+
+// readPollHandler returns a poll with its results, and the vote of the
+// member if authenticated
+// GET /polls/{id}
+func (ph PollHandler) readPollHandler(w http.ResponseWriter, r *http.Request) {
+	poll, ok := ph.loadPoll(w, r)
+	if !ok {
+		return
+	}
+	if member := auth.GetMember(r); member != nil {
+		vote, err := ph.db.ReadPollVote(poll.Id, member.Number)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if vote != nil {
+			poll.MyVote = &vote.Vote
+		}
+	}
+	WriteJSONConditional(w, r, poll, time.Time{})
+}
+
+// readAllPollHandler returns polls with their results, newest first
+// GET /polls?take=20&skip=0
+func (ph PollHandler) readAllPollHandler(w http.ResponseWriter, r *http.Request) {
+	take := MakeDefaultInt(r, "take", "20")
+	skip := MakeDefaultInt(r, "skip", "0")
+	polls, err := ph.db.ReadPolls(take, skip)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSONConditional(w, r, polls, time.Time{})
+}
+
+// updatePollHandler changes the theme and options of a poll. Only its
+// creator or an admin may, and the options only until someone has voted.
+// PUT /polls/{id}
+func (ph PollHandler) updatePollHandler(w http.ResponseWriter, r *http.Request) {
+	stored, ok := ph.loadPoll(w, r)
+	if !ok || !ph.canModify(w, r, stored) {
+		return
+	}
+
+	var req pollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+		return
+	}
+	p, fe := req.poll()
+	if len(fe) > 0 {
+		writeFieldErrors(w, fe)
+		return
+	}
+
+	p.Id = stored.Id
+	slog.Debug(ru.GetRequestId(r), "poll", p.Fmt())
+	poll, err := ph.db.UpdatePoll(p)
+	if errors.Is(err, models.ErrPollHasVotes) {
+		http.Error(w, `{"error":"options cannot change after voting has started"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if poll == nil {
+		http.Error(w, `{"error":"poll not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(poll)
+}
+
+// deletePollHandler removes a poll with its votes. Only its creator or an
+// admin may.
+// DELETE /polls/{id}
+func (ph PollHandler) deletePollHandler(w http.ResponseWriter, r *http.Request) {
+	poll, ok := ph.loadPoll(w, r)
+	if !ok || !ph.canModify(w, r, poll) {
+		return
+	}
+
+	deleted, err := ph.db.DeletePoll(poll.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, `{"error":"poll not found"}`, http.StatusNotFound)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "poll deleted", poll.Fmt())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// votePollHandler records the vote of the member, one per poll. Returns the
+// poll with the updated results.
+// POST /polls/{id}/vote
+// Body: {"vote": 1}
+func (ph PollHandler) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Vote *int `json:"vote"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Vote == nil {
+		http.Error(w, `{"error":"vote required"}`, http.StatusBadRequest)
+		return
+	}
+
+	poll, err := ph.db.VotePoll(id, member.Number, *req.Vote, time.Now())
+	switch {
+	case errors.Is(err, models.ErrPollOption):
+		http.Error(w, `{"error":"poll has no such option"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, models.ErrPollVoted):
+		http.Error(w, `{"error":"already voted"}`, http.StatusConflict)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	case poll == nil:
+		http.Error(w, `{"error":"poll not found"}`, http.StatusNotFound)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "poll", poll.Id, "member", member.Number, "vote", *req.Vote)
+	poll.MyVote = req.Vote
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(poll)
+}
+
+// canModify tells whether the member may change the poll, and writes 403 if
+// not. Old polls have no creator and can only be changed by admins.
+func (ph PollHandler) canModify(w http.ResponseWriter, r *http.Request, poll *models.Poll) bool {
+	if auth.HasScope(r, auth.AdminScope) {
+		return true
+	}
+	member := auth.GetMember(r)
+	if member != nil && poll.CreatedBy != nil && *poll.CreatedBy == member.Number {
+		return true
+	}
+	http.Error(w, `{"error":"only the creator of the poll or an admin can change it"}`, http.StatusForbidden)
+	return false
+}
+
+func (ph PollHandler) loadPoll(w http.ResponseWriter, r *http.Request) (*models.Poll, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	poll, err := ph.db.ReadPoll(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+	if poll == nil {
+		http.Error(w, `{"error":"poll not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+	return poll, true
+}
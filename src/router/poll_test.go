@@ -0,0 +1,187 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type pollDatabase struct {
+	*fakeDatabase
+	polls map[int64]*models.Poll
+	votes map[int64]map[int64]int
+}
+
+func (d *pollDatabase) CreatePoll(p *models.Poll) (*models.Poll, error) {
+	p.Id = int64(len(d.polls) + 1)
+	for i := range p.Extra {
+		p.Extra[i].Vote = models.PollVoteExtra + i
+	}
+	d.polls[p.Id] = p
+	d.votes[p.Id] = map[int64]int{}
+	return d.ReadPoll(p.Id)
+}
+
+func (d *pollDatabase) ReadPoll(id int64) (*models.Poll, error) {
+	p, ok := d.polls[id]
+	if !ok {
+		return nil, nil
+	}
+	poll := *p
+	counts := map[int]int64{}
+	for _, v := range d.votes[id] {
+		counts[v]++
+	}
+	poll.SetResults(counts)
+	return &poll, nil
+}
+
+func (d *pollDatabase) UpdatePoll(p *models.Poll) (*models.Poll, error) {
+	d.polls[p.Id].Theme = p.Theme
+	return d.ReadPoll(p.Id)
+}
+
+func (d *pollDatabase) DeletePoll(id int64) (bool, error) {
+	_, ok := d.polls[id]
+	delete(d.polls, id)
+	return ok, nil
+}
+
+func (d *pollDatabase) VotePoll(id int64, memberNumber int64, vote int, at time.Time) (*models.Poll, error) {
+	p, ok := d.polls[id]
+	if !ok {
+		return nil, nil
+	}
+	if !p.HasOption(vote) {
+		return nil, models.ErrPollOption
+	}
+	if _, voted := d.votes[id][memberNumber]; voted {
+		return nil, models.ErrPollVoted
+	}
+	d.votes[id][memberNumber] = vote
+	return d.ReadPoll(id)
+}
+
+func (d *pollDatabase) ReadPollVote(id int64, memberNumber int64) (*models.PollVote, error) {
+	v, ok := d.votes[id][memberNumber]
+	if !ok {
+		return nil, nil
+	}
+	return &models.PollVote{PollId: id, MemberNumber: &memberNumber, Vote: v}, nil
+}
+
+func TestPolls(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := &pollDatabase{fakeDatabase: newFakeDatabase(), polls: map[int64]*models.Poll{}, votes: map[int64]map[int64]int{}}
+	ph := NewPollHandler(db)
+
+	do := func(handler http.HandlerFunc, method string, id string, number int64, body string, scopes ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		middleware := auth.NewMiddleware(db).OptionalAuth
+		if number != 0 {
+			token, err := auth.GenerateJWT(number, "member@example.com", scopes, "test", []byte(testJWTSecret))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+			middleware = auth.NewMiddleware(db).RequireAuth
+		}
+		rec := httptest.NewRecorder()
+		middleware(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(ph.createPollHandler, http.MethodPost, "", 8, `{"theme":""}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"theme":"required"`)
+	rec = do(ph.createPollHandler, http.MethodPost, "", 8, `{"theme":"Vad?","options":["a"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// yae and nay default to Ja and Nej
+	rec = do(ph.createPollHandler, http.MethodPost, "", 8, `{"theme":"Supa?"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var poll models.Poll
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &poll))
+	assert.Equal(t, "Ja", poll.Yae)
+	assert.Equal(t, "Nej", poll.Nay)
+
+	rec = do(ph.createPollHandler, http.MethodPost, "", 8, `{"theme":"Vilken öl?","options":["Lager","Ale","Stout"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &poll))
+	assert.Equal(t, int64(2), poll.Id)
+	assert.Equal(t, []models.PollResult{{Vote: 1, Text: "Lager"}, {Vote: 0, Text: "Ale"}, {Vote: 2, Text: "Stout"}}, poll.Results)
+
+	// One vote per member, for an option of the poll
+	rec = do(ph.votePollHandler, http.MethodPost, "2", 9, `{"vote":2}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &poll))
+	assert.Equal(t, int64(1), poll.Votes)
+	assert.Equal(t, 2, *poll.MyVote)
+	assert.Equal(t, http.StatusConflict, do(ph.votePollHandler, http.MethodPost, "2", 9, `{"vote":1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(ph.votePollHandler, http.MethodPost, "2", 8, `{"vote":5}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(ph.votePollHandler, http.MethodPost, "2", 8, `{}`).Code)
+	assert.Equal(t, http.StatusNotFound, do(ph.votePollHandler, http.MethodPost, "7", 8, `{"vote":1}`).Code)
+
+	// Results are public, the own vote only for the member
+	rec = do(ph.readPollHandler, http.MethodGet, "2", 0, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "my_vote")
+	assert.Contains(t, rec.Body.String(), `"votes":1`)
+	rec = do(ph.readPollHandler, http.MethodGet, "2", 9, "")
+	assert.Contains(t, rec.Body.String(), `"my_vote":2`)
+
+	// Only the creator or an admin can change a poll
+	assert.Equal(t, http.StatusForbidden, do(ph.updatePollHandler, http.MethodPut, "2", 9, `{"theme":"Öl?"}`).Code)
+	rec = do(ph.updatePollHandler, http.MethodPut, "2", 8, `{"theme":"Vilken öl ikväll?","options":["Lager","Ale","Stout"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Vilken öl ikväll?")
+	assert.Equal(t, http.StatusForbidden, do(ph.deletePollHandler, http.MethodDelete, "2", 9, "").Code)
+	assert.Equal(t, http.StatusNoContent, do(ph.deletePollHandler, http.MethodDelete, "2", 9, "", auth.AdminScope).Code)
+	assert.Equal(t, http.StatusNotFound, do(ph.readPollHandler, http.MethodGet, "2", 0, "").Code)
+}
+
+func TestPollJSON(t *testing.T) {
+	// The MySQL driver reads the date column as a time
+	date := time.Date(2007, 12, 10, 0, 0, 0, 0, time.UTC)
+	clock := "19:39:09"
+	b, err := json.Marshal(models.Poll{Id: 683, Theme: "Ringen?", Date: &date, Time: &clock})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"date":"2007-12-10"`)
+	assert.Contains(t, string(b), `"time":"19:39:09"`)
+
+	// Old polls without a date
+	b, err = json.Marshal(models.Poll{Id: 1})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"date":null`)
+	assert.Contains(t, string(b), `"time":null`)
+}
+
+func TestPollWritesRequireScope(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	handler := Mux(newFakeDatabase())
+
+	// Inactive members only get read:member and read:article
+	token, err := auth.GenerateJWT(8, "member@example.com", []string{auth.ReadMemberScope, auth.ReadArticleScope}, "test", []byte(testJWTSecret))
+	require.NoError(t, err)
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/polls"},
+		{http.MethodPut, "/polls/1"},
+		{http.MethodDelete, "/polls/1"},
+		{http.MethodPost, "/polls/1/vote"},
+	} {
+		req := httptest.NewRequest(route.method, route.path, strings.NewReader(`{"theme":"Supa?","vote":1}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code, route.method+" "+route.path)
+	}
+}
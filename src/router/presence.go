@@ -0,0 +1,79 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/presence"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+// presenceHeartbeat keeps idle event streams from being closed by proxies
+const presenceHeartbeat = 30 * time.Second
+
+func NewPresenceHandler(tracker *presence.Tracker) PresenceHandler {
+	return PresenceHandler{tracker}
+}
+
+type PresenceHandler struct {
+	tracker *presence.Tracker
+}
+
+// readPresenceHandler returns the members currently online
+// GET /presence
+func (ph PresenceHandler) readPresenceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ph.tracker.Online())
+}
+
+// presenceEventsHandler streams presence changes as server-sent events,
+// starting with the members online when connecting
+// GET /presence/events
+func (ph PresenceHandler) presenceEventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, `{"error":"streaming unsupported"}`, http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := ph.tracker.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	for _, p := range ph.tracker.Online() {
+		writePresenceEvent(w, presence.Event{Type: presence.Online, MemberNumber: p.MemberNumber, At: p.OnlineSince})
+	}
+	flusher.Flush()
+	slog.Debug(ru.GetRequestId(r), "presence subscriber", "connected")
+
+	heartbeat := time.NewTicker(presenceHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			slog.Debug(ru.GetRequestId(r), "presence subscriber", "disconnected")
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			writePresenceEvent(w, e)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writePresenceEvent(w http.ResponseWriter, e presence.Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+}
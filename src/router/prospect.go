@@ -2,16 +2,20 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/sebastiw/sidan-backend/src/auth"
 	"github.com/sebastiw/sidan-backend/src/data"
 	"github.com/sebastiw/sidan-backend/src/models"
 	ru "github.com/sebastiw/sidan-backend/src/router_util"
+	"github.com/sebastiw/sidan-backend/src/views"
 )
 
 func NewProspectHandler(db data.Database) ProspectHandler {
@@ -31,10 +35,11 @@ func (ph ProspectHandler) createProspectHandler(w http.ResponseWriter, r *http.R
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(prospect)
+	json.NewEncoder(w).Encode(views.NewProspect(*prospect, readMemberAudience(r)))
 }
 
 func (ph ProspectHandler) readProspectHandler(w http.ResponseWriter, r *http.Request) {
@@ -43,16 +48,17 @@ func (ph ProspectHandler) readProspectHandler(w http.ResponseWriter, r *http.Req
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	prospect, err := ph.db.ReadProspect(id)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(prospect)
+	WriteJSONConditional(w, r, views.NewProspect(*prospect, readMemberAudience(r)), time.Time{})
 }
 
 func (ph ProspectHandler) updateProspectHandler(w http.ResponseWriter, r *http.Request) {
@@ -68,10 +74,11 @@ func (ph ProspectHandler) updateProspectHandler(w http.ResponseWriter, r *http.R
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(prospect)
+	json.NewEncoder(w).Encode(views.NewProspect(*prospect, readMemberAudience(r)))
 }
 
 func (ph ProspectHandler) deleteProspectHandler(w http.ResponseWriter, r *http.Request) {
@@ -87,10 +94,62 @@ func (ph ProspectHandler) deleteProspectHandler(w http.ResponseWriter, r *http.R
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(prospect)
+	json.NewEncoder(w).Encode(views.NewProspect(*prospect, readMemberAudience(r)))
+}
+
+// promoteProspectHandler makes a prospect a full member. The body may choose
+// the member number, otherwise the next free number is used. The new member
+// gets member scopes on their next login, and the promotion is stored as
+// member events.
+// POST /db/prospects/{id}/promote
+// Body (optional): {"number": 123}
+func (ph ProspectHandler) promoteProspectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Number int64 `json:"number"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Number < 0 {
+			http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	promotedBy := int64(0)
+	if m := auth.GetMember(r); m != nil {
+		promotedBy = m.Number
+	}
+	member, prospect, err := ph.db.PromoteProspect(id, req.Number, promotedBy)
+	switch {
+	case errors.Is(err, models.ErrNotProspect), errors.Is(err, models.ErrMemberNumberTaken):
+		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusConflict)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	case member == nil:
+		http.Error(w, `{"error":"prospect not found"}`, http.StatusNotFound)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "prospect promoted", prospect.Fmt(), "member", member.Fmt(), "promoted_by", promotedBy)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"member":   views.NewMember(*member, memberAudience(r, member.Number)),
+		"prospect": views.NewProspect(*prospect, readMemberAudience(r)),
+	})
 }
 
 func (ph ProspectHandler) readAllProspectHandler(w http.ResponseWriter, r *http.Request) {
@@ -99,10 +158,10 @@ func (ph ProspectHandler) readAllProspectHandler(w http.ResponseWriter, r *http.
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(prospects)
+	WriteJSONConditional(w, r, views.NewProspects(prospects, readMemberAudience(r)), time.Time{})
 }
 
 func (ph ProspectHandler) readProspectUnauthedHandler(w http.ResponseWriter, r *http.Request) {
@@ -111,21 +170,17 @@ func (ph ProspectHandler) readProspectUnauthedHandler(w http.ResponseWriter, r *
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	prospect, err := ph.db.ReadProspect(id)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models.ProspectLite{
-		Id:      prospect.Id,
-		Status:  prospect.Status,
-		Number:  prospect.Number,
-		History: prospect.History,
-	})
+	WriteJSONConditional(w, r, views.NewProspect(*prospect, views.Public), time.Time{})
 }
 
 func (ph ProspectHandler) readAllProspectUnauthedHandler(w http.ResponseWriter, r *http.Request) {
@@ -134,18 +189,8 @@ func (ph ProspectHandler) readAllProspectUnauthedHandler(w http.ResponseWriter,
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
-	lite := make([]models.ProspectLite, len(prospects))
-	for i, p := range prospects {
-		lite[i] = models.ProspectLite{
-			Id:      p.Id,
-			Status:  p.Status,
-			Number:  p.Number,
-			History: p.History,
-		}
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(lite)
+	WriteJSONConditional(w, r, views.NewProspects(prospects, views.Public), time.Time{})
 }
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type promoteDatabase struct {
+	*fakeDatabase
+	number int64
+	by     int64
+	err    error
+}
+
+func (p *promoteDatabase) PromoteProspect(id int64, number int64, by int64) (*models.Member, *models.Prospect, error) {
+	p.number, p.by = number, by
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+	if id != 1 {
+		return nil, nil, nil
+	}
+	valid := true
+	prospect := p.prospects[0]
+	prospect.Status = models.StatusPromoted
+	return &models.Member{Id: 3, Number: 1337, Name: &prospect.Name, Isvalid: &valid}, &prospect, nil
+}
+
+func promote(t *testing.T, db *promoteDatabase, id string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	token, err := auth.GenerateJWT(8, "member@example.com", []string{auth.AdminScope}, "test", []byte(testJWTSecret))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/db/prospects/"+id+"/promote", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	auth.NewMiddleware(db).RequireAuth(http.HandlerFunc(NewProspectHandler(db).promoteProspectHandler)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPromoteProspectHandler(t *testing.T) {
+	db := &promoteDatabase{fakeDatabase: newFakeDatabase()}
+
+	rec := promote(t, db, "1", "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, int64(0), db.number)
+	assert.Equal(t, int64(8), db.by)
+	assert.Contains(t, rec.Body.String(), `"number":1337`)
+	assert.Contains(t, rec.Body.String(), `"status":"#"`)
+
+	rec = promote(t, db, "1", `{"number":1337}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, int64(1337), db.number)
+
+	assert.Equal(t, http.StatusNotFound, promote(t, db, "2", "").Code)
+	assert.Equal(t, http.StatusBadRequest, promote(t, db, "1", `{"number":-1}`).Code)
+
+	db.err = models.ErrMemberNumberTaken
+	assert.Equal(t, http.StatusConflict, promote(t, db, "1", `{"number":8}`).Code)
+}
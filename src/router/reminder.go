@@ -0,0 +1,72 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/data"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+func NewReminderHandler(db data.Database) ReminderHandler {
+	return ReminderHandler{db}
+}
+
+type ReminderHandler struct {
+	db data.Database
+}
+
+// reminderSettings tells whether the member gets reminders of arr
+type reminderSettings struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// readRemindersHandler returns whether the member gets reminders of arr
+// GET /reminders
+func (rh ReminderHandler) readRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	optOut, err := rh.db.ReadReminderOptOut(member.Number)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	enabled := !optOut
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reminderSettings{Enabled: &enabled})
+}
+
+// updateRemindersHandler opts the member out of reminders of arr, or back in
+// PUT /reminders
+func (rh ReminderHandler) updateRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var settings reminderSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil || settings.Enabled == nil {
+		http.Error(w, `{"error":"enabled is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := rh.db.SetReminderOptOut(member.Number, !*settings.Enabled); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	slog.Info(ru.GetRequestId(r), "member", member.Number, "reminders", *settings.Enabled)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
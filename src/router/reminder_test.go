@@ -0,0 +1,61 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+)
+
+type reminderDatabase struct {
+	*fakeDatabase
+	optOut map[int64]bool
+}
+
+func (d *reminderDatabase) ReadReminderOptOut(memberNumber int64) (bool, error) {
+	return d.optOut[memberNumber], nil
+}
+
+func (d *reminderDatabase) SetReminderOptOut(memberNumber int64, optOut bool) error {
+	d.optOut[memberNumber] = optOut
+	return nil
+}
+
+func TestReminders(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := &reminderDatabase{fakeDatabase: newFakeDatabase(), optOut: map[int64]bool{}}
+	rh := NewReminderHandler(db)
+
+	token, err := auth.GenerateJWT(8, "member@example.com", nil, "test", []byte(testJWTSecret))
+	require.NoError(t, err)
+	do := func(handler http.HandlerFunc, method string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/reminders", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.NewMiddleware(db).RequireAuth(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(rh.readRemindersHandler, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"enabled":true}`, rec.Body.String())
+
+	rec = do(rh.updateRemindersHandler, http.MethodPut, `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(rh.updateRemindersHandler, http.MethodPut, `{"enabled":false}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, db.optOut[8])
+
+	rec = do(rh.readRemindersHandler, http.MethodGet, "")
+	assert.JSONEq(t, `{"enabled":false}`, rec.Body.String())
+
+	rec = do(rh.updateRemindersHandler, http.MethodPut, `{"enabled":true}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, db.optOut[8])
+}
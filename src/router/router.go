@@ -3,15 +3,22 @@ package router
 import (
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 
+	"github.com/sebastiw/sidan-backend/src/analytics"
 	"github.com/sebastiw/sidan-backend/src/data"
 	a "github.com/sebastiw/sidan-backend/src/auth"
 	"github.com/sebastiw/sidan-backend/src/config"
+	"github.com/sebastiw/sidan-backend/src/hosts"
+	"github.com/sebastiw/sidan-backend/src/mailer"
+	"github.com/sebastiw/sidan-backend/src/presence"
+	"github.com/sebastiw/sidan-backend/src/ratelimit"
+	"github.com/sebastiw/sidan-backend/src/reminder"
 	ru "github.com/sebastiw/sidan-backend/src/router_util"
 )
 
@@ -35,12 +42,24 @@ func (w *statusWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
-func LogHTTP(handler http.Handler) http.HandlerFunc {
+// Flush lets streaming handlers push data through the logging wrapper
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// LogHTTP logs every request and, if recorder is not nil, queues it for the
+// request analytics
+func LogHTTP(recorder *analytics.Recorder, handler http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		sw := statusWriter{ResponseWriter: w}
 		handler.ServeHTTP(&sw, r)
 		duration := time.Now().Sub(start)
+		if recorder != nil {
+			recorder.Record(r)
+		}
 		slog.Debug("http-request",
 			slog.String("RequestId",  ru.GetRequestId(r)),
 			slog.String("Host",       r.Host),
@@ -72,26 +91,68 @@ func corsHeaders(router http.Handler) http.Handler {
 			"http://localhost",
 			"http://localhost:*",
 		},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
 		AllowCredentials: true,
 	})
 	return corsHandler.Handler(router)
 }
 
+// routeLimit returns the limit of the named route from the config, or the
+// given default
+func routeLimit(name string, requests int, window time.Duration) ratelimit.Limit {
+	if c, ok := config.GetRateLimit(name); ok {
+		return ratelimit.Limit{Requests: c.Requests, Window: time.Duration(c.WindowSeconds) * time.Second}
+	}
+	return ratelimit.Limit{Requests: requests, Window: window}
+}
+
 func Mux(db data.Database) http.Handler {
 	r := mux.NewRouter()
 
 	// Create middleware for protected endpoints
 	authMiddleware := a.NewMiddleware(db)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+
+	// Members are online until 5 minutes after their last authenticated request
+	tracker := presence.NewTracker(5 * time.Minute)
+	tracker.StartExpiryJob(time.Minute)
+	authMiddleware.TrackPresence(tracker)
 
 	// Auth handlers (public endpoints)
 	authHandler := NewAuthHandler(db)
 	r.HandleFunc("/auth/login", authHandler.Login).Methods("GET", "OPTIONS")
 	r.HandleFunc("/auth/callback", authHandler.Callback).Methods("GET", "OPTIONS")
 	r.HandleFunc("/auth/refresh", authHandler.Token).Methods("POST", "OPTIONS")
-	r.HandleFunc("/auth/device/start", authHandler.DeviceStart).Methods("POST", "OPTIONS")
-	r.HandleFunc("/auth/device/poll", authHandler.DevicePoll).Methods("POST", "OPTIONS")
+	r.Handle("/auth/device/start",
+		limiter.Limit("device_start", routeLimit("device_start", 10, time.Minute))(
+			http.HandlerFunc(authHandler.DeviceStart),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/auth/device/poll",
+		limiter.Limit("device_poll", routeLimit("device_poll", 30, time.Minute))(
+			http.HandlerFunc(authHandler.DevicePoll),
+		),
+	).Methods("POST", "OPTIONS")
 	r.HandleFunc("/auth/device/refresh", authHandler.DeviceRefresh).Methods("POST", "OPTIONS")
 
+	// Classic member number and password login
+	r.Handle("/auth/classic/login",
+		limiter.Limit("classic_login", routeLimit("classic_login", 10, time.Minute))(
+			http.HandlerFunc(authHandler.ClassicLogin),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/auth/classic/password", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.ChangePassword))).Methods("PUT", "OPTIONS")
+	r.Handle("/auth/password/reset",
+		limiter.Limit("password_reset", routeLimit("password_reset", 5, time.Hour))(
+			http.HandlerFunc(authHandler.RequestPasswordReset),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/auth/password/reset/confirm",
+		limiter.Limit("password_reset_confirm", routeLimit("password_reset_confirm", 10, time.Minute))(
+			http.HandlerFunc(authHandler.ConfirmPasswordReset),
+		),
+	).Methods("POST", "OPTIONS")
+
 	// Web auth aliases (preferred paths, /auth/{login,callback,refresh,logout} are deprecated)
 	r.HandleFunc("/auth/web/login", authHandler.Login).Methods("GET", "OPTIONS")
 	r.HandleFunc("/auth/web/callback", authHandler.Callback).Methods("GET", "OPTIONS")
@@ -102,6 +163,11 @@ func Mux(db data.Database) http.Handler {
 	r.Handle("/auth/logout", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.Logout))).Methods("POST", "OPTIONS")
 	r.Handle("/auth/web/logout", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.Logout))).Methods("POST", "OPTIONS")
 
+	// Login identities linked to the authenticated member
+	r.Handle("/auth/identities", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.ReadIdentities))).Methods("GET", "OPTIONS")
+	r.Handle("/auth/identities/link", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.LinkIdentity))).Methods("POST", "OPTIONS")
+	r.Handle("/auth/identities/{id:[0-9]+}", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.UnlinkIdentity))).Methods("DELETE", "OPTIONS")
+
 	// Start cleanup job (runs every 15 minutes)
 	a.StartCleanupJob(db, 15*time.Minute)
 
@@ -131,7 +197,11 @@ func Mux(db data.Database) http.Handler {
 	// Entry endpoints
 	dbEh := NewEntryHandler(db)
 	r.Handle("/db/entries",
-		authMiddleware.RequireAuth(http.HandlerFunc(dbEh.createEntryHandler)),
+		authMiddleware.RequireAuth(
+			limiter.Limit("create_entry", routeLimit("create_entry", 10, time.Minute))(
+				http.HandlerFunc(dbEh.createEntryHandler),
+			),
+		),
 	).Methods("POST", "OPTIONS")
 	r.Handle("/db/entries/{id:[0-9]+}",
 		authMiddleware.OptionalAuth(http.HandlerFunc(dbEh.readEntryHandler)),
@@ -155,10 +225,60 @@ func Mux(db data.Database) http.Handler {
 	).Methods("GET", "OPTIONS")
 	r.Handle("/db/entries/{id:[0-9]+}/like",
 		authMiddleware.RequireAuth(
-			http.HandlerFunc(dbEh.likeEntryHandler),
+			limiter.Limit("like_entry", routeLimit("like_entry", 60, time.Minute))(
+				http.HandlerFunc(dbEh.likeEntryHandler),
+			),
 		),
 	).Methods("POST", "OPTIONS")
 
+	// Export endpoints
+	exH := NewExportHandler(db)
+	r.Handle("/export/entries",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.ExportEntryScope)(
+				http.HandlerFunc(exH.exportEntriesHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+
+	// Saved entry filter endpoints
+	dbFh := NewFilterHandler(db)
+	r.Handle("/db/filters",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.FilteringScope)(
+				http.HandlerFunc(dbFh.createFilterHandler),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/db/filters",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.FilteringScope)(
+				http.HandlerFunc(dbFh.readAllFilterHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/db/filters/{id:[0-9]+}",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.FilteringScope)(
+				http.HandlerFunc(dbFh.readFilterHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/db/filters/{id:[0-9]+}",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.FilteringScope)(
+				http.HandlerFunc(dbFh.updateFilterHandler),
+			),
+		),
+	).Methods("PUT", "OPTIONS")
+	r.Handle("/db/filters/{id:[0-9]+}",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.FilteringScope)(
+				http.HandlerFunc(dbFh.deleteFilterHandler),
+			),
+		),
+	).Methods("DELETE", "OPTIONS")
+
 	// Member endpoints (with optional auth for read operations)
 	dbMh := NewMemberHandler(db)
 	r.Handle("/db/members",
@@ -168,6 +288,13 @@ func Mux(db data.Database) http.Handler {
 			),
 		),
 	).Methods("POST", "OPTIONS")
+	r.Handle("/db/members/events",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.AdminScope)(
+				http.HandlerFunc(dbMh.readMemberEventsHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
 	r.Handle("/db/members/{id:[0-9]+}",
 		authMiddleware.OptionalAuth(
 			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -194,6 +321,19 @@ func Mux(db data.Database) http.Handler {
 			),
 		),
 	).Methods("PUT", "OPTIONS")
+	r.Handle("/me",
+		authMiddleware.RequireAuth(http.HandlerFunc(dbMh.readMeHandler)),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/me",
+		authMiddleware.RequireAuth(http.HandlerFunc(dbMh.patchMeHandler)),
+	).Methods("PATCH", "OPTIONS")
+	r.Handle("/me/picture",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteImageScope)(
+				http.HandlerFunc(dbMh.updateMePictureHandler),
+			),
+		),
+	).Methods("PUT", "OPTIONS")
 	r.Handle("/db/members/{id:[0-9]+}",
 		authMiddleware.RequireAuth(
 			authMiddleware.RequireScope(a.WriteMemberScope)(
@@ -262,6 +402,13 @@ func Mux(db data.Database) http.Handler {
 			),
 		),
 	).Methods("DELETE", "OPTIONS")
+	r.Handle("/db/prospects/{id:[0-9]+}/promote",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.AdminScope)(
+				http.HandlerFunc(dbPh.promoteProspectHandler),
+			),
+		),
+	).Methods("POST", "OPTIONS")
 	r.Handle("/db/prospects",
 		authMiddleware.OptionalAuth(
 			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -290,7 +437,9 @@ func Mux(db data.Database) http.Handler {
 			),
 		),
 	).Methods("POST", "OPTIONS")
-	r.HandleFunc("/db/arr/{id:[0-9]+}", dbAh.readArrHandler).Methods("GET", "OPTIONS")
+	r.Handle("/db/arr/{id:[0-9]+}",
+		authMiddleware.OptionalAuth(http.HandlerFunc(dbAh.readArrHandler)),
+	).Methods("GET", "OPTIONS")
 	r.Handle("/db/arr/{id:[0-9]+}",
 		authMiddleware.RequireAuth(
 			authMiddleware.RequireScope(a.WriteArrScope)(
@@ -305,7 +454,257 @@ func Mux(db data.Database) http.Handler {
 			),
 		),
 	).Methods("DELETE", "OPTIONS")
-	r.HandleFunc("/db/arr", dbAh.readAllArrHandler).Methods("GET", "OPTIONS")
+	r.Handle("/db/arr",
+		authMiddleware.OptionalAuth(http.HandlerFunc(dbAh.readAllArrHandler)),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/db/arr/{id:[0-9]+}/unlock",
+		limiter.Limit("arr_unlock", routeLimit("arr_unlock", 10, time.Minute))(
+			http.HandlerFunc(dbAh.unlockArrHandler),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/db/arr/{id:[0-9]+}/rsvp",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				http.HandlerFunc(dbAh.rsvpArrHandler),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/db/arr/{id:[0-9]+}/rsvp",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				http.HandlerFunc(dbAh.unrsvpArrHandler),
+			),
+		),
+	).Methods("DELETE", "OPTIONS")
+
+	// Hetsa: invitations of people outside the club, answered by code
+	hetsaH := NewHetsaHandler(db)
+	r.Handle("/db/arr/{id:[0-9]+}/hetsa",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				limiter.Limit("create_hetsa", routeLimit("create_hetsa", 20, time.Hour))(
+					http.HandlerFunc(hetsaH.createHetsaHandler),
+				),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/hetsa/{code:[a-zA-Z0-9]+}",
+		limiter.Limit("hetsa", routeLimit("hetsa", 30, time.Minute))(
+			http.HandlerFunc(hetsaH.readHetsaHandler),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/hetsa/{code:[a-zA-Z0-9]+}/accept",
+		limiter.Limit("hetsa", routeLimit("hetsa", 30, time.Minute))(
+			http.HandlerFunc(hetsaH.acceptHetsaHandler),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/hetsa/{code:[a-zA-Z0-9]+}/decline",
+		limiter.Limit("hetsa", routeLimit("hetsa", 30, time.Minute))(
+			http.HandlerFunc(hetsaH.declineHetsaHandler),
+		),
+	).Methods("POST", "OPTIONS")
+
+	// Suplogg: drinking logs of arr, counted live by the participants
+	supH := NewSuploggHandler(db)
+	r.Handle("/db/suplogg",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				http.HandlerFunc(supH.createSuploggHandler),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+	r.HandleFunc("/db/suplogg", supH.readAllSuploggHandler).Methods("GET", "OPTIONS")
+	r.HandleFunc("/db/suplogg/leaderboard", supH.readSuploggLeaderboardHandler).Methods("GET", "OPTIONS")
+	r.HandleFunc("/db/suplogg/{id:[0-9]+}", supH.readSuploggHandler).Methods("GET", "OPTIONS")
+	r.Handle("/db/suplogg/{id:[0-9]+}",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				http.HandlerFunc(supH.updateSuploggHandler),
+			),
+		),
+	).Methods("PUT", "OPTIONS")
+	r.Handle("/db/suplogg/{id:[0-9]+}",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				http.HandlerFunc(supH.deleteSuploggHandler),
+			),
+		),
+	).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/db/suplogg/{id:[0-9]+}/results", supH.readSuploggResultsHandler).Methods("GET", "OPTIONS")
+	r.Handle("/db/suplogg/{id:[0-9]+}/enheter",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				limiter.Limit("suplogg_enheter", routeLimit("suplogg_enheter", 60, time.Minute))(
+					http.HandlerFunc(supH.addEnheterHandler),
+				),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/db/suplogg/{id:[0-9]+}/close",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WriteArrScope)(
+				http.HandlerFunc(supH.closeSuploggHandler),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+
+	// Polls, one vote per member
+	pollH := NewPollHandler(db)
+	r.Handle("/polls",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WritePollScope)(
+				limiter.Limit("create_poll", routeLimit("create_poll", 10, time.Hour))(
+					http.HandlerFunc(pollH.createPollHandler),
+				),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+	r.HandleFunc("/polls", pollH.readAllPollHandler).Methods("GET", "OPTIONS")
+	r.Handle("/polls/{id:[0-9]+}",
+		authMiddleware.OptionalAuth(http.HandlerFunc(pollH.readPollHandler)),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/polls/{id:[0-9]+}",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WritePollScope)(
+				http.HandlerFunc(pollH.updatePollHandler),
+			),
+		),
+	).Methods("PUT", "OPTIONS")
+	r.Handle("/polls/{id:[0-9]+}",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WritePollScope)(
+				http.HandlerFunc(pollH.deletePollHandler),
+			),
+		),
+	).Methods("DELETE", "OPTIONS")
+	r.Handle("/polls/{id:[0-9]+}/vote",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.WritePollScope)(
+				limiter.Limit("poll_vote", routeLimit("poll_vote", 30, time.Minute))(
+					http.HandlerFunc(pollH.votePollHandler),
+				),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+
+	// Calendar endpoints
+	calH := NewCalendarHandler(db)
+	r.Handle("/calendar/arr.ics",
+		authMiddleware.OptionalAuth(http.HandlerFunc(calH.readArrCalendarHandler)),
+	).Methods("GET", "OPTIONS")
+	r.HandleFunc("/calendar/members/{number:[0-9]+}/arr.ics", calH.readMemberArrCalendarHandler).Methods("GET", "OPTIONS")
+	r.Handle("/calendar/feed",
+		authMiddleware.RequireAuth(http.HandlerFunc(calH.readCalendarFeedHandler)),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/calendar/feed",
+		authMiddleware.RequireAuth(http.HandlerFunc(calH.renewCalendarFeedHandler)),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/calendar/feed",
+		authMiddleware.RequireAuth(http.HandlerFunc(calH.deleteCalendarFeedHandler)),
+	).Methods("DELETE", "OPTIONS")
+
+	// Reminder mails before arr, members can opt out
+	remH := NewReminderHandler(db)
+	r.Handle("/reminders", authMiddleware.RequireAuth(http.HandlerFunc(remH.readRemindersHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/reminders", authMiddleware.RequireAuth(http.HandlerFunc(remH.updateRemindersHandler))).Methods("PUT", "OPTIONS")
+
+	// Contacts endpoints, vCard export and a read-only CardDAV address book
+	cardH := NewCardDAVHandler(db)
+	r.Handle("/db/members.vcf",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.ReadMemberScope)(
+				http.HandlerFunc(cardH.readMembersVCardHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/carddav/credentials",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.ReadMemberScope)(
+				http.HandlerFunc(cardH.readCardDAVCredentialsHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/carddav/credentials",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.ReadMemberScope)(
+				http.HandlerFunc(cardH.renewCardDAVCredentialsHandler),
+			),
+		),
+	).Methods("POST", "OPTIONS")
+	r.Handle("/carddav/credentials",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.ReadMemberScope)(
+				http.HandlerFunc(cardH.deleteCardDAVCredentialsHandler),
+			),
+		),
+	).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/.well-known/carddav", cardH.wellKnownHandler).Methods("GET", "PROPFIND")
+	r.HandleFunc("/carddav{slash:/?}", cardH.optionsHandler).Methods("OPTIONS")
+	r.HandleFunc("/carddav/members{slash:/?}", cardH.optionsHandler).Methods("OPTIONS")
+	r.HandleFunc("/carddav/members/{number:[0-9]+}.vcf", cardH.optionsHandler).Methods("OPTIONS")
+	r.Handle("/carddav{slash:/?}",
+		authMiddleware.OptionalAuth(cardH.authorize(cardH.propfindRootHandler)),
+	).Methods("PROPFIND")
+	r.Handle("/carddav/members{slash:/?}",
+		authMiddleware.OptionalAuth(cardH.authorize(cardH.propfindBookHandler)),
+	).Methods("PROPFIND")
+	r.Handle("/carddav/members{slash:/?}",
+		authMiddleware.OptionalAuth(cardH.authorize(cardH.reportBookHandler)),
+	).Methods("REPORT")
+	r.Handle("/carddav/members/{number:[0-9]+}.vcf",
+		authMiddleware.OptionalAuth(cardH.authorize(cardH.propfindCardHandler)),
+	).Methods("PROPFIND")
+	r.Handle("/carddav/members/{number:[0-9]+}.vcf",
+		authMiddleware.OptionalAuth(cardH.authorize(cardH.readCardHandler)),
+	).Methods("GET", "HEAD")
+
+	// Presence endpoints
+	presH := NewPresenceHandler(tracker)
+	r.Handle("/presence",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.ReadMemberScope)(
+				http.HandlerFunc(presH.readPresenceHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/presence/events",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.ReadMemberScope)(
+				http.HandlerFunc(presH.presenceEventsHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+
+	// Analytics endpoints
+	anH := NewAnalyticsHandler(db)
+	r.Handle("/admin/analytics/visitors",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.AdminScope)(
+				http.HandlerFunc(anH.readDailyVisitorsHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/admin/analytics/user-agents",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.AdminScope)(
+				http.HandlerFunc(anH.readUserAgentsHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/admin/analytics/endpoints",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.AdminScope)(
+				http.HandlerFunc(anH.readEndpointUsageHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
+	r.Handle("/admin/cache",
+		authMiddleware.RequireAuth(
+			authMiddleware.RequireScope(a.AdminScope)(
+				http.HandlerFunc(anH.readCacheStatsHandler),
+			),
+		),
+	).Methods("GET", "OPTIONS")
 
 	// Article endpoints
 	dbArth := NewArticleHandler(db)
@@ -357,5 +756,16 @@ func Mux(db data.Database) http.Handler {
 		}).Methods("GET")
 	}
 
-	return corsHeaders(ru.Tracing(nextRequestId)(LogHTTP(r)))
+	var recorder *analytics.Recorder
+	if c := config.GetAnalytics(); c.Enabled {
+		recorder = analytics.NewRecorder(db, analytics.PolicyFromConfig(c), hosts.NewCachedResolver(net.DefaultResolver, time.Hour, 10000), 100, 10*time.Second)
+		analytics.StartRetentionJob(db, c.RetentionDays, 24*time.Hour)
+	}
+
+	if c := config.GetReminder(); c.Enabled {
+		scheduler := reminder.NewScheduler(db, mailer.NewSMTPSender(config.GetMail()), reminder.OffsetsFromConfig(c), c.URL)
+		scheduler.Start(time.Duration(c.IntervalSeconds) * time.Second)
+	}
+
+	return corsHeaders(ru.Tracing(nextRequestId)(LogHTTP(recorder, r)))
 }
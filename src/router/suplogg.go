@@ -0,0 +1,374 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
+)
+
+func NewSuploggHandler(db data.Database) SuploggHandler {
+	return SuploggHandler{db}
+}
+
+type SuploggHandler struct {
+	db data.Database
+}
+
+// suploggResults is the final or running tally of a suplogg
+type suploggResults struct {
+	Id       int64                  `json:"id"`
+	Name     string                 `json:"name"`
+	Total    int64                  `json:"total"`
+	ClosedAt *time.Time             `json:"closed_at"`
+	Results  []models.SuploggResult `json:"results"`
+}
+
+func newSuploggResults(s models.Suplogg) suploggResults {
+	results := make([]models.SuploggResult, len(s.Deltagare))
+	for i, d := range s.Deltagare {
+		results[i] = models.SuploggResult{Name: d.Name, Enheter: d.Enheter}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Enheter != results[j].Enheter {
+			return results[i].Enheter > results[j].Enheter
+		}
+		return results[i].Name < results[j].Name
+	})
+	return suploggResults{
+		Id:       s.Id,
+		Name:     s.Name,
+		Total:    s.Total(),
+		ClosedAt: s.ClosedAt,
+		Results:  models.RankSuplogg(results),
+	}
+}
+
+// createSuploggHandler starts the suplogg of an arr, named after the arr
+// unless a name is given
+// POST /db/suplogg
+// Body: {"id": 123, "name": "..."}
+func (sh SuploggHandler) createSuploggHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Id   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Id == 0 {
+		http.Error(w, `{"error":"arr id required"}`, http.StatusBadRequest)
+		return
+	}
+
+	arr, err := sh.db.ReadArr(req.Id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, `{"error":"arr not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	existing, err := sh.db.ReadSuplogg(arr.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		http.Error(w, `{"error":"arr already has a suplogg"}`, http.StatusConflict)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = deref(arr.Namn)
+	}
+	suplogg, err := sh.db.CreateSuplogg(&models.Suplogg{Id: arr.Id, Name: name, StartedBy: member.Number})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "suplogg", suplogg.Fmt())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(suplogg)
+}
+
+func (sh SuploggHandler) readSuploggHandler(w http.ResponseWriter, r *http.Request) {
+	suplogg, ok := sh.loadSuplogg(w, r)
+	if !ok {
+		return
+	}
+	WriteJSONConditional(w, r, suplogg, time.Time{})
+}
+
+func (sh SuploggHandler) readAllSuploggHandler(w http.ResponseWriter, r *http.Request) {
+	take := MakeDefaultInt(r, "take", "20")
+	skip := MakeDefaultInt(r, "skip", "0")
+	suploggs, err := sh.db.ReadSuploggs(take, skip)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSONConditional(w, r, suploggs, time.Time{})
+}
+
+// updateSuploggHandler renames a suplogg
+// PUT /db/suplogg/{id}
+// Body: {"name": "..."}
+func (sh SuploggHandler) updateSuploggHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+	var s models.Suplogg
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.Id = id
+	slog.Debug(ru.GetRequestId(r), "suplogg", s.Fmt())
+	suplogg, err := sh.db.UpdateSuplogg(&s)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if suplogg == nil {
+		http.Error(w, `{"error":"suplogg not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suplogg)
+}
+
+func (sh SuploggHandler) deleteSuploggHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := sh.db.DeleteSuplogg(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, `{"error":"suplogg not found"}`, http.StatusNotFound)
+		return
+	}
+
+	slog.Info(ru.GetRequestId(r), "suplogg deleted", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addEnheterHandler counts enheter as they are drunk, one by default. A
+// negative count corrects a miscount. Members count for themselves; a name
+// counts for a guest without an account.
+// POST /db/suplogg/{id}/enheter
+// Body (optional): {"count": 1, "name": "GuiGui"}
+func (sh SuploggHandler) addEnheterHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	req := struct {
+		Count int64  `json:"count"`
+		Name  string `json:"name"`
+	}{Count: 1}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	name := fmt.Sprintf("#%d", member.Number)
+	if guest := strings.TrimSpace(req.Name); guest != "" && guest != name {
+		if strings.HasPrefix(guest, "#") || len(guest) > 50 {
+			http.Error(w, `{"error":"name must be a guest of at most 50 characters"}`, http.StatusBadRequest)
+			return
+		}
+		name = guest
+	}
+
+	deltagare, err := sh.db.AddSuploggEnheter(id, name, req.Count)
+	if errors.Is(err, models.ErrSuploggClosed) {
+		http.Error(w, `{"error":"suplogg is closed"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if deltagare == nil {
+		http.Error(w, `{"error":"suplogg not found"}`, http.StatusNotFound)
+		return
+	}
+
+	slog.Debug(ru.GetRequestId(r), "suplogg", id, "name", deltagare.Name, "enheter", deltagare.Enheter)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deltagare)
+}
+
+// closeSuploggHandler stops the counting. With kommentar the final tally is
+// also written as a kommentar signed by the member closing the suplogg. A
+// closed suplogg without its kommentar accepts kommentar again, so that a
+// failed kommentar can be retried.
+// POST /db/suplogg/{id}/close
+// Body (optional): {"kommentar": true}
+func (sh SuploggHandler) closeSuploggHandler(w http.ResponseWriter, r *http.Request) {
+	member := auth.GetMember(r)
+	if member == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Kommentar bool `json:"kommentar"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	suplogg, err := sh.db.CloseSuplogg(id, time.Now())
+	if errors.Is(err, models.ErrSuploggClosed) {
+		// The kommentar can be posted again if it failed when closing
+		if suplogg, err = sh.db.ReadSuplogg(id); err == nil && (!req.Kommentar || suplogg == nil || suplogg.EntryId != nil) {
+			http.Error(w, `{"error":"suplogg is closed"}`, http.StatusConflict)
+			return
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if suplogg == nil {
+		http.Error(w, `{"error":"suplogg not found"}`, http.StatusNotFound)
+		return
+	}
+	slog.Info(ru.GetRequestId(r), "suplogg closed", suplogg.Fmt())
+
+	if req.Kommentar {
+		entry, err := sh.db.CreateEntry(&models.Entry{
+			Msg:   suploggKommentar(*suplogg),
+			Sig:   fmt.Sprintf("#%d", member.Number),
+			Email: deref(member.Email),
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if err := sh.db.SetSuploggEntry(id, entry.Id); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		suplogg.EntryId = &entry.Id
+		slog.Info(ru.GetRequestId(r), "suplogg kommentar", entry.Id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suplogg)
+}
+
+// suploggKommentar is the final tally as the message of a kommentar
+func suploggKommentar(s models.Suplogg) string {
+	results := newSuploggResults(s)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Suplogg %s: %d enheter", results.Name, results.Total)
+	for _, res := range results.Results {
+		fmt.Fprintf(&b, "\n%d. %s %d", res.Rank, res.Name, res.Enheter)
+	}
+	return b.String()
+}
+
+// readSuploggResultsHandler ranks the participants of a suplogg
+// GET /db/suplogg/{id}/results
+func (sh SuploggHandler) readSuploggResultsHandler(w http.ResponseWriter, r *http.Request) {
+	suplogg, ok := sh.loadSuplogg(w, r)
+	if !ok {
+		return
+	}
+	WriteJSONConditional(w, r, newSuploggResults(*suplogg), time.Time{})
+}
+
+// readSuploggLeaderboardHandler ranks everyone by their enheter in all
+// suploggs
+// GET /db/suplogg/leaderboard?take=20
+func (sh SuploggHandler) readSuploggLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	take := MakeDefaultInt(r, "take", "20")
+	results, err := sh.db.ReadSuploggLeaderboard(take)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSONConditional(w, r, results, time.Time{})
+}
+
+func (sh SuploggHandler) loadSuplogg(w http.ResponseWriter, r *http.Request) (*models.Suplogg, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	suplogg, err := sh.db.ReadSuplogg(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("unable to render the error page: %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+	if suplogg == nil {
+		http.Error(w, `{"error":"suplogg not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+	return suplogg, true
+}
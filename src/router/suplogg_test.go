@@ -0,0 +1,156 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+type suploggDatabase struct {
+	*fakeDatabase
+	suplogg  models.Suplogg
+	created  []models.Entry
+	entryErr error
+}
+
+func (d *suploggDatabase) ReadSuplogg(id int64) (*models.Suplogg, error) {
+	if id != d.suplogg.Id {
+		return nil, nil
+	}
+	s := d.suplogg
+	return &s, nil
+}
+
+func (d *suploggDatabase) AddSuploggEnheter(id int64, name string, count int64) (*models.SuploggDeltagare, error) {
+	if id != d.suplogg.Id {
+		return nil, nil
+	}
+	if d.suplogg.ClosedAt != nil {
+		return nil, models.ErrSuploggClosed
+	}
+	for i := range d.suplogg.Deltagare {
+		if d.suplogg.Deltagare[i].Name == name {
+			d.suplogg.Deltagare[i].Enheter += count
+			row := d.suplogg.Deltagare[i]
+			return &row, nil
+		}
+	}
+	d.suplogg.Deltagare = append(d.suplogg.Deltagare, models.SuploggDeltagare{Arr: id, Name: name, Enheter: count})
+	row := d.suplogg.Deltagare[len(d.suplogg.Deltagare)-1]
+	return &row, nil
+}
+
+func (d *suploggDatabase) CloseSuplogg(id int64, at time.Time) (*models.Suplogg, error) {
+	if id != d.suplogg.Id {
+		return nil, nil
+	}
+	if d.suplogg.ClosedAt != nil {
+		return nil, models.ErrSuploggClosed
+	}
+	d.suplogg.ClosedAt = &at
+	s := d.suplogg
+	return &s, nil
+}
+
+func (d *suploggDatabase) CreateEntry(entry *models.Entry) (*models.Entry, error) {
+	if d.entryErr != nil {
+		return nil, d.entryErr
+	}
+	entry.Id = 100
+	d.created = append(d.created, *entry)
+	return entry, nil
+}
+
+func (d *suploggDatabase) SetSuploggEntry(id int64, entryId int64) error {
+	d.suplogg.EntryId = &entryId
+	return nil
+}
+
+func TestSuplogg_EnheterAndKommentar(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := &suploggDatabase{fakeDatabase: newFakeDatabase(), suplogg: models.Suplogg{Id: 7, Name: "Kräftskiva"}}
+	sh := NewSuploggHandler(db)
+
+	call := func(handler http.HandlerFunc, id string, body string) *httptest.ResponseRecorder {
+		token, err := auth.GenerateJWT(8, "member@example.com", []string{auth.WriteArrScope}, "test", []byte(testJWTSecret))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.NewMiddleware(db).RequireAuth(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, call(sh.addEnheterHandler, "7", "").Code)
+	rec := call(sh.addEnheterHandler, "7", `{"count":2}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var deltagare models.SuploggDeltagare
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &deltagare))
+	assert.Equal(t, models.SuploggDeltagare{Name: "#8", Enheter: 3}, deltagare)
+
+	require.Equal(t, http.StatusOK, call(sh.addEnheterHandler, "7", `{"name":"GuiGui","count":5}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(sh.addEnheterHandler, "7", `{"name":"#9"}`).Code)
+	assert.Equal(t, http.StatusNotFound, call(sh.addEnheterHandler, "8", "").Code)
+
+	rec = call(sh.closeSuploggHandler, "7", `{"kommentar":true}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, db.created, 1)
+	assert.Equal(t, "#8", db.created[0].Sig)
+	assert.Equal(t, "Suplogg Kräftskiva: 8 enheter\n1. GuiGui 5\n2. #8 3", db.created[0].Msg)
+	assert.Equal(t, int64(100), *db.suplogg.EntryId)
+
+	assert.Equal(t, http.StatusConflict, call(sh.addEnheterHandler, "7", "").Code)
+}
+
+func TestSuplogg_RetryKommentar(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := &suploggDatabase{fakeDatabase: newFakeDatabase(), suplogg: models.Suplogg{Id: 7, Name: "Kräftskiva"}}
+	sh := NewSuploggHandler(db)
+
+	close := func(body string) int {
+		token, err := auth.GenerateJWT(8, "member@example.com", []string{auth.WriteArrScope}, "test", []byte(testJWTSecret))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "7"})
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.NewMiddleware(db).RequireAuth(http.HandlerFunc(sh.closeSuploggHandler)).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The suplogg is closed even though the kommentar failed
+	db.entryErr = errors.New("database is gone")
+	assert.Equal(t, http.StatusInternalServerError, close(`{"kommentar":true}`))
+	require.NotNil(t, db.suplogg.ClosedAt)
+	assert.Nil(t, db.suplogg.EntryId)
+
+	// Closing again only posts the missing kommentar
+	assert.Equal(t, http.StatusConflict, close(""))
+	db.entryErr = nil
+	assert.Equal(t, http.StatusOK, close(`{"kommentar":true}`))
+	require.Len(t, db.created, 1)
+	assert.Equal(t, int64(100), *db.suplogg.EntryId)
+
+	assert.Equal(t, http.StatusConflict, close(`{"kommentar":true}`))
+	assert.Len(t, db.created, 1)
+}
+
+func TestNewSuploggResults(t *testing.T) {
+	results := newSuploggResults(models.Suplogg{Id: 1, Name: "Arr", Deltagare: []models.SuploggDeltagare{
+		{Name: "#3", Enheter: 6}, {Name: "#8", Enheter: 6}, {Name: "#9", Enheter: 2},
+	}})
+	assert.Equal(t, int64(14), results.Total)
+	assert.Equal(t, []int{1, 1, 3}, []int{results.Results[0].Rank, results.Results[1].Rank, results.Results[2].Rank})
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/views"
+)
+
+// memberAudience is how the request sees the member with the given number
+func memberAudience(r *http.Request, number int64) views.Audience {
+	if auth.HasScope(r, auth.AdminScope) {
+		return views.Admin
+	}
+	if m := auth.GetMember(r); m != nil && m.Number == number {
+		return views.Self
+	}
+	if auth.HasScope(r, auth.ReadMemberScope) {
+		return views.Member
+	}
+	return views.Public
+}
+
+// readMemberAudience is the audience for member data requiring read:member,
+// such as prospects
+func readMemberAudience(r *http.Request) views.Audience {
+	if auth.HasScope(r, auth.AdminScope) {
+		return views.Admin
+	}
+	if auth.HasScope(r, auth.ReadMemberScope) {
+		return views.Member
+	}
+	return views.Public
+}
+
+// entryAudience is Public for unauthenticated requests and Member otherwise
+func entryAudience(r *http.Request) views.Audience {
+	if auth.HasScope(r, auth.AdminScope) {
+		return views.Admin
+	}
+	if auth.GetMember(r) != nil {
+		return views.Member
+	}
+	return views.Public
+}
+
+// arrAudience is Member for members, Guest with a token from the password of
+// the arr and Public otherwise
+func arrAudience(r *http.Request, arrId int64) views.Audience {
+	if auth.HasScope(r, auth.AdminScope) {
+		return views.Admin
+	}
+	if auth.GetMember(r) != nil {
+		return views.Member
+	}
+	if auth.HasScope(r, auth.ArrScope(arrId)) {
+		return views.Guest
+	}
+	return views.Public
+}
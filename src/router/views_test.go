@@ -0,0 +1,204 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/sebastiw/sidan-backend/src/auth"
+	"github.com/sebastiw/sidan-backend/src/data"
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+const testJWTSecret = "router-test-secret"
+
+// secrets are stored on every member and must never reach a response
+var secrets = []string{"bcrypthash", "classichash", "resetstring", "classicreset"}
+
+// fakeDatabase holds what the router tests share. Tests needing more embed
+// it and add the methods they use.
+type fakeDatabase struct {
+	data.Database
+	members   []models.Member
+	prospects []models.Prospect
+	entries   []models.Entry
+	arrs      map[int64]models.Arr
+}
+
+func (f *fakeDatabase) ReadMember(id int64) (*models.Member, error) {
+	for _, m := range f.members {
+		if m.Id == id {
+			return &m, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeDatabase) ReadMemberByNumber(number int64) (*models.Member, error) {
+	for _, m := range f.members {
+		if m.Number == number {
+			return &m, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeDatabase) ReadMemberByEmail(email string) (*models.Member, error) {
+	for _, m := range f.members {
+		if m.Email != nil && *m.Email == email {
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDatabase) ReadMembers(onlyValid bool) ([]models.Member, error) {
+	return f.members, nil
+}
+
+func (f *fakeDatabase) ReadProspect(id int64) (*models.Prospect, error) {
+	return &f.prospects[0], nil
+}
+
+func (f *fakeDatabase) ReadProspects(status string) ([]models.Prospect, error) {
+	return f.prospects, nil
+}
+
+func (f *fakeDatabase) ReadEntry(id int64) (*models.Entry, error) {
+	e := f.entries[0]
+	return &e, nil
+}
+
+func (f *fakeDatabase) ReadEntries(take int, skip int, filter string, sort string, fields []string) ([]models.Entry, error) {
+	return append([]models.Entry(nil), f.entries...), nil
+}
+
+func (f *fakeDatabase) ReadArr(id int64) (*models.Arr, error) {
+	arr, ok := f.arrs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &arr, nil
+}
+
+func (f *fakeDatabase) ReadHostPatterns() ([]models.HostPattern, error) {
+	return nil, nil
+}
+
+func newFakeDatabase() *fakeDatabase {
+	str := func(s string) *string { return &s }
+	member := func(id, number int64) models.Member {
+		return models.Member{
+			Id:                           id,
+			Number:                       number,
+			Name:                         str("Namn"),
+			Email:                        str("member@example.com"),
+			Password:                     str("$2a$10$bcrypthash"),
+			Password_classic:             str("classichash"),
+			Password_resetstring:         str("resetstring"),
+			Password_classic_resetstring: str("classicreset"),
+		}
+	}
+	return &fakeDatabase{
+		members:   []models.Member{member(1, 8), member(2, 9)},
+		prospects: []models.Prospect{{Id: 1, Number: 1234, Name: "Prospekt", Email: "prospect@example.com", Phone: "0701234567"}},
+		entries:   []models.Entry{{Id: 1, Msg: "hej", Sig: "#8", Email: "entry@example.com", Ip: str("10.0.0.1"), Host: str("host.example.com")}},
+	}
+}
+
+// serve calls handler through OptionalAuth, as member number with scopes
+// when number is non-zero
+func serve(t *testing.T, db data.Database, handler http.HandlerFunc, number int64, scopes ...string) string {
+	t.Helper()
+	t.Setenv("JWT_SECRET", testJWTSecret)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	if number != 0 {
+		token, err := auth.GenerateJWT(number, "member@example.com", scopes, "test", []byte(testJWTSecret))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	auth.NewMiddleware(db).OptionalAuth(handler).ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	return rec.Body.String()
+}
+
+func TestMemberHandlers_NeverExposeSecrets(t *testing.T) {
+	db := newFakeDatabase()
+	mh := NewMemberHandler(db)
+
+	handlers := map[string]http.HandlerFunc{
+		"read":            mh.readMemberHandler,
+		"readUnauthed":    mh.readMemberUnauthedHandler,
+		"readAll":         mh.readAllMemberHandler,
+		"readAllUnauthed": mh.readAllMemberUnauthedHandler,
+		"me":              mh.readMeHandler,
+	}
+	viewers := map[string]func(http.HandlerFunc) string{
+		"self": func(h http.HandlerFunc) string {
+			return serve(t, db, h, 8, auth.ReadMemberScope)
+		},
+		"member": func(h http.HandlerFunc) string {
+			return serve(t, db, h, 9, auth.ReadMemberScope)
+		},
+		"admin": func(h http.HandlerFunc) string {
+			return serve(t, db, h, 9, auth.ReadMemberScope, auth.AdminScope)
+		},
+	}
+
+	for name, handler := range handlers {
+		for viewer, call := range viewers {
+			body := call(handler)
+			for _, secret := range secrets {
+				assert.NotContains(t, body, secret, name+" as "+viewer)
+			}
+			assert.NotContains(t, body, `"password`, name+" as "+viewer)
+		}
+	}
+}
+
+func TestMemberHandlers_Audience(t *testing.T) {
+	db := newFakeDatabase()
+	mh := NewMemberHandler(db)
+
+	assert.NotContains(t, serve(t, db, mh.readMemberUnauthedHandler, 0), "member@example.com")
+	assert.NotContains(t, serve(t, db, mh.readMemberHandler, 9, auth.ReadMemberScope), "has_password")
+	assert.Contains(t, serve(t, db, mh.readMemberHandler, 8, auth.ReadMemberScope), `"has_password":true`)
+	assert.Contains(t, serve(t, db, mh.readMemberHandler, 9, auth.AdminScope), `"has_password":true`)
+}
+
+func TestProspectHandlers_PublicHidesContact(t *testing.T) {
+	db := newFakeDatabase()
+	ph := NewProspectHandler(db)
+
+	for _, h := range []http.HandlerFunc{ph.readProspectUnauthedHandler, ph.readAllProspectUnauthedHandler} {
+		body := serve(t, db, h, 0)
+		assert.NotContains(t, body, "prospect@example.com")
+		assert.NotContains(t, body, "0701234567")
+	}
+	assert.Contains(t, serve(t, db, ph.readProspectHandler, 8, auth.ReadMemberScope), "prospect@example.com")
+}
+
+func TestEntryHandlers_PublicHidesAddresses(t *testing.T) {
+	db := newFakeDatabase()
+	eh := NewEntryHandler(db)
+
+	for _, h := range []http.HandlerFunc{eh.readEntryHandler, eh.readAllEntryHandler} {
+		body := serve(t, db, h, 0)
+		assert.Contains(t, body, "hej")
+		assert.NotContains(t, body, "entry@example.com")
+		assert.NotContains(t, body, "10.0.0.1")
+		assert.NotContains(t, body, "host.example.com")
+
+		body = serve(t, db, h, 8)
+		assert.Contains(t, body, "entry@example.com")
+	}
+}
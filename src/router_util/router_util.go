@@ -2,7 +2,11 @@ package router_util
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 )
 
 type key int
@@ -32,3 +36,75 @@ func GetRequestId(r *http.Request) string {
 	}
 	return requestID
 }
+
+var trustedProxies []netip.Prefix
+
+// SetTrustedProxies configures the proxies (IPs or CIDRs) whose
+// X-Forwarded-For header is believed by ClientIP
+func SetTrustedProxies(proxies []string) error {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	trustedProxies = prefixes
+	return nil
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client without the port. If the
+// connection comes from a trusted proxy, X-Forwarded-For is walked from the
+// right and the first address that is not a trusted proxy is the client.
+func ClientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(header, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				forwarded = append(forwarded, ip)
+			}
+		}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if _, err := netip.ParseAddr(forwarded[i]); err != nil {
+			// Garbage from the client, stop trusting the chain here
+			break
+		}
+		if !isTrustedProxy(forwarded[i]) {
+			return forwarded[i]
+		}
+		remote = forwarded[i]
+	}
+	return remote
+}
@@ -0,0 +1,36 @@
+package router_util
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	assert.NoError(t, SetTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8"}))
+	defer SetTrustedProxies(nil)
+
+	request := func(remoteAddr string, xff ...string) string {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		for _, h := range xff {
+			r.Header.Add("X-Forwarded-For", h)
+		}
+		return ClientIP(r)
+	}
+
+	// Direct connection, X-Forwarded-For is not believed
+	assert.Equal(t, "203.0.113.7", request("203.0.113.7:4000", "198.51.100.1"))
+
+	// Through trusted proxies, the rightmost untrusted address is the client
+	assert.Equal(t, "198.51.100.1", request("127.0.0.1:4000", "198.51.100.1"))
+	assert.Equal(t, "198.51.100.1", request("127.0.0.1:4000", "6.6.6.6, 198.51.100.1, 10.1.2.3"))
+	assert.Equal(t, "198.51.100.1", request("127.0.0.1:4000", "6.6.6.6", "198.51.100.1"))
+
+	// Only trusted hops, or no header, falls back to the last proxy
+	assert.Equal(t, "10.1.2.3", request("127.0.0.1:4000", "10.1.2.3"))
+	assert.Equal(t, "127.0.0.1", request("127.0.0.1:4000"))
+
+	assert.Error(t, SetTrustedProxies([]string{"not-an-ip"}))
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/sebastiw/sidan-backend/src/fdroid"
 	"github.com/sebastiw/sidan-backend/src/logger"
 	r "github.com/sebastiw/sidan-backend/src/router"
+	ru "github.com/sebastiw/sidan-backend/src/router_util"
 )
 
 func main() {
@@ -35,6 +36,10 @@ func main() {
 		slog.Error(err.Error())
 	}
 
+	if err := ru.SetTrustedProxies(config.GetServer().TrustedProxies); err != nil {
+		slog.Error(err.Error())
+	}
+
 	address := fmt.Sprintf(":%v", config.GetServer().Port)
 	slog.Info("Starting backend service", slog.String("address", address))
 
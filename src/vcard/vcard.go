@@ -0,0 +1,142 @@
+package vcard
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ProdID        = "-//Chalmers Losers//sidan-backend//SV"
+	maxLineOctets = 75
+)
+
+// Card is one RFC 6350 vCard 4.0 contact
+type Card struct {
+	UID      string
+	FullName string
+	Nickname string
+	Email    string
+	Phone    string
+	Address  string
+	Photo    string
+}
+
+// WriteCards renders the cards one after another, as in a .vcf file
+func WriteCards(w io.Writer, cards []Card) error {
+	bw := bufio.NewWriter(w)
+	for _, c := range cards {
+		writeCard(bw, c)
+	}
+	return bw.Flush()
+}
+
+// Marshal renders a single card
+func Marshal(c Card) []byte {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	writeCard(bw, c)
+	bw.Flush()
+	return buf.Bytes()
+}
+
+func writeCard(w *bufio.Writer, c Card) {
+	fullName := c.FullName
+	if fullName == "" {
+		fullName = c.Nickname
+	}
+
+	writeLine(w, "BEGIN:VCARD")
+	writeLine(w, "VERSION:4.0")
+	writeLine(w, "PRODID:"+ProdID)
+	writeLine(w, "UID:"+c.UID)
+	writeLine(w, "KIND:individual")
+	writeLine(w, "FN:"+EscapeText(fullName))
+	family, given := splitName(c.FullName)
+	writeLine(w, "N:"+EscapeText(family)+";"+EscapeText(given)+";;;")
+	if c.Nickname != "" {
+		writeLine(w, "NICKNAME:"+EscapeText(c.Nickname))
+	}
+	if c.Email != "" {
+		writeLine(w, "EMAIL:"+EscapeText(c.Email))
+	}
+	if tel := telURI(c.Phone); tel != "" {
+		writeLine(w, "TEL;VALUE=uri:tel:"+tel)
+	}
+	if c.Address != "" {
+		// The address is free text, so it all goes in the street component
+		writeLine(w, `ADR;LABEL="`+quoteParam(c.Address)+`":;;`+EscapeText(c.Address)+";;;;")
+	}
+	if c.Photo != "" {
+		writeLine(w, "PHOTO:"+c.Photo)
+	}
+	writeLine(w, "END:VCARD")
+}
+
+// EscapeText escapes a text value according to RFC 6350 section 3.4
+func EscapeText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// splitName guesses family and given names from a full name, taking the
+// last word as family name
+func splitName(name string) (family, given string) {
+	name = strings.TrimSpace(name)
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name, ""
+	}
+	return name[i+1:], strings.TrimSpace(name[:i])
+}
+
+// telURI keeps the characters allowed in a tel URI
+func telURI(phone string) string {
+	var b strings.Builder
+	for i, r := range phone {
+		if (r >= '0' && r <= '9') || r == '-' || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// quoteParam makes s safe inside a quoted parameter value (RFC 6868)
+func quoteParam(s string) string {
+	r := strings.NewReplacer(
+		"^", "^^",
+		"\r\n", "^n",
+		"\n", "^n",
+		"\r", "^n",
+		`"`, "^'",
+	)
+	return r.Replace(s)
+}
+
+// writeLine folds content lines longer than 75 octets without splitting
+// UTF-8 sequences and terminates them with CRLF
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines lose one octet to the leading space
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,52 @@
+package vcard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	card := string(Marshal(Card{
+		UID:      "member-8@test",
+		FullName: "Anna Maria Svensson",
+		Nickname: "#8",
+		Email:    "anna@example.com",
+		Phone:    "070-123 45 67",
+		Address:  "Gatan 1, Göteborg",
+		Photo:    "https://example.com/file/avatars/8/abc-256.png",
+	}))
+
+	assert.True(t, strings.HasPrefix(card, "BEGIN:VCARD\r\nVERSION:4.0\r\n"))
+	assert.Contains(t, card, "FN:Anna Maria Svensson\r\n")
+	assert.Contains(t, card, "N:Svensson;Anna Maria;;;\r\n")
+	assert.Contains(t, card, "NICKNAME:#8\r\n")
+	assert.Contains(t, card, "EMAIL:anna@example.com\r\n")
+	assert.Contains(t, card, "TEL;VALUE=uri:tel:070-1234567\r\n")
+	assert.Contains(t, card, `ADR;LABEL="Gatan 1, Göteborg":;;Gatan 1\, Göteborg;;;;`+"\r\n")
+	assert.Contains(t, card, "PHOTO:https://example.com/file/avatars/8/abc-256.png\r\n")
+	assert.True(t, strings.HasSuffix(card, "END:VCARD\r\n"))
+}
+
+func TestMarshal_WithoutName(t *testing.T) {
+	card := string(Marshal(Card{UID: "member-9@test", Nickname: "#9"}))
+	assert.Contains(t, card, "FN:#9\r\n")
+	assert.NotContains(t, card, "EMAIL")
+	assert.NotContains(t, card, "TEL")
+}
+
+func TestWriteCards_Folds(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteCards(&buf, []Card{
+		{UID: "a", FullName: strings.Repeat("å", 60)},
+		{UID: "b", FullName: "B"},
+	}))
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "BEGIN:VCARD"))
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+	}
+}
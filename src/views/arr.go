@@ -0,0 +1,76 @@
+package views
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ArrPublic is what anyone may see of an arr. Where it is and who comes are
+// left out; HasPassword tells whether a password lets guests see them.
+type ArrPublic struct {
+	Id          int64      `json:"id"`
+	Namn        *string    `json:"namn"`
+	StartDate   *string    `json:"start_date"`
+	StartsAt    *time.Time `json:"starts_at"`
+	Organisator *string    `json:"organisator"`
+	Fularr      *string    `json:"fularr"`
+	HasPassword bool       `json:"has_password"`
+}
+
+// ArrDetails adds the place and the attendance, shown to guests who gave
+// the password of the arr
+type ArrDetails struct {
+	ArrPublic
+	Plats      *string                `json:"plats"`
+	Deltagare  *string                `json:"deltagare"`
+	Kanske     *string                `json:"kanske"`
+	Hetsade    *string                `json:"hetsade"`
+	Attendance []models.ArrAttendance `json:"attendance"`
+	Guests     []models.Hetsa         `json:"guests"`
+}
+
+// ArrMember adds the password, which members hand out to guests
+type ArrMember struct {
+	ArrDetails
+	Losen *string `json:"losen"`
+}
+
+// NewArr returns the representation of a for the audience
+func NewArr(a models.Arr, aud Audience) interface{} {
+	public := ArrPublic{
+		Id:          a.Id,
+		Namn:        a.Namn,
+		StartDate:   a.StartDate,
+		StartsAt:    a.StartsAt,
+		Organisator: a.Organisator,
+		Fularr:      a.Fularr,
+		HasPassword: isSet(a.Losen),
+	}
+	if aud == Public {
+		return public
+	}
+	details := ArrDetails{
+		ArrPublic:  public,
+		Plats:      a.Plats,
+		Deltagare:  a.Deltagare,
+		Kanske:     a.Kanske,
+		Hetsade:    a.Hetsade,
+		Attendance: a.Attendance,
+		Guests:     a.Guests,
+	}
+	if aud == Guest {
+		return details
+	}
+	return ArrMember{ArrDetails: details, Losen: a.Losen}
+}
+
+// NewArrs renders a list where the audience may differ per arr, e.g. Guest
+// for the one arr a guest token is for
+func NewArrs(arrs []models.Arr, audienceOf func(models.Arr) Audience) []interface{} {
+	out := make([]interface{}, len(arrs))
+	for i, a := range arrs {
+		out[i] = NewArr(a, audienceOf(a))
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+// Package views is the representation layer of the API. Models are never
+// serialised directly; handlers convert them to the view of the audience
+// asking, so fields such as password hashes cannot leak by accident.
+package views
+
+// Audience is who a representation is rendered for
+type Audience int
+
+const (
+	// Public is anyone, including unauthenticated clients
+	Public Audience = iota
+	// Guest is someone outside the club let in to a single arr by its
+	// password. Everything but arr is rendered as for Public.
+	Guest
+	// Member is an authenticated member looking at someone else
+	Member
+	// Self is a member looking at their own data
+	Self
+	// Admin is a member with the admin scope
+	Admin
+)
+
+func (a Audience) String() string {
+	switch a {
+	case Guest:
+		return "guest"
+	case Member:
+		return "member"
+	case Self:
+		return "self"
+	case Admin:
+		return "admin"
+	}
+	return "public"
+}
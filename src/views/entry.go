@@ -0,0 +1,76 @@
+package views
+
+import (
+	"time"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// EntryPublic is a guestbook entry as anyone may see it. Secret entries must
+// already have been redacted for the viewer (router.FilterEntryMessage).
+type EntryPublic struct {
+	Id             int64             `json:"id"`
+	Date           string            `json:"date"`
+	Time           string            `json:"time"`
+	DateTime       time.Time         `json:"datetime"`
+	Msg            string            `json:"msg"`
+	Status         *int64            `json:"status"`
+	Cl             int64             `json:"cl"`
+	Sig            string            `json:"sig"`
+	Place          string            `json:"place"`
+	HostName       string            `json:"host_name"`
+	Olsug          *int64            `json:"olsug"`
+	Enheter        int64             `json:"enheter"`
+	Lat            *float64          `json:"lat"`
+	Lon            *float64          `json:"lon"`
+	Report         bool              `json:"report"`
+	Likes          int64             `json:"likes"`
+	Secret         bool              `json:"secret"`
+	PersonalSecret bool              `json:"personal_secret"`
+	SideKicks      []models.SideKick `json:"sidekicks"`
+}
+
+// EntryDetails adds the author's email and address, shown to members
+type EntryDetails struct {
+	EntryPublic
+	Email string  `json:"email"`
+	Ip    *string `json:"ip"`
+	Host  *string `json:"host"`
+}
+
+// NewEntry returns the representation of e for the audience
+func NewEntry(e models.Entry, a Audience) interface{} {
+	public := EntryPublic{
+		Id:             e.Id,
+		Date:           e.Date,
+		Time:           e.Time,
+		DateTime:       e.DateTime,
+		Msg:            e.Msg,
+		Status:         e.Status,
+		Cl:             e.Cl,
+		Sig:            e.Sig,
+		Place:          e.Place,
+		HostName:       e.HostName,
+		Olsug:          e.Olsug,
+		Enheter:        e.Enheter,
+		Lat:            e.Lat,
+		Lon:            e.Lon,
+		Report:         e.Report,
+		Likes:          e.Likes,
+		Secret:         e.Secret,
+		PersonalSecret: e.PersonalSecret,
+		SideKicks:      e.SideKicks,
+	}
+	if a == Public || a == Guest {
+		return public
+	}
+	return EntryDetails{EntryPublic: public, Email: e.Email, Ip: e.Ip, Host: e.Host}
+}
+
+func NewEntries(entries []models.Entry, a Audience) []interface{} {
+	out := make([]interface{}, len(entries))
+	for i, e := range entries {
+		out[i] = NewEntry(e, a)
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package views
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// MemberPublic is what anyone may see of a member
+type MemberPublic struct {
+	Id     int64   `json:"id"`
+	Number int64   `json:"number"`
+	Title  *string `json:"title"`
+}
+
+// MemberDetails is a member as seen by other members
+type MemberDetails struct {
+	Id         int64   `json:"id"`
+	Number     int64   `json:"number"`
+	Name       *string `json:"name"`
+	Email      *string `json:"email"`
+	Im         string  `json:"im"`
+	Phone      *string `json:"phone"`
+	Address    *string `json:"address"`
+	AddressURL *string `json:"address_url"`
+	Title      *string `json:"title"`
+	History    *string `json:"history"`
+	Picture    *string `json:"picture"`
+	IsValid    *bool   `json:"is_valid"`
+}
+
+// MemberAccount adds account state for the member themself and admins.
+// Passwords and reset strings are only ever reported as present or not.
+type MemberAccount struct {
+	MemberDetails
+	HasPassword        bool `json:"has_password"`
+	HasClassicPassword bool `json:"has_classic_password"`
+}
+
+// NewMember returns the representation of m for the audience
+func NewMember(m models.Member, a Audience) interface{} {
+	switch a {
+	case Public, Guest:
+		return MemberPublic{Id: m.Id, Number: m.Number, Title: m.Title}
+	case Member:
+		return newMemberDetails(m)
+	}
+	return MemberAccount{
+		MemberDetails:      newMemberDetails(m),
+		HasPassword:        isSet(m.Password),
+		HasClassicPassword: isSet(m.Password_classic),
+	}
+}
+
+// NewMembers renders a list where the audience may differ per member, e.g.
+// Self for the viewer's own row
+func NewMembers(members []models.Member, audienceOf func(models.Member) Audience) []interface{} {
+	out := make([]interface{}, len(members))
+	for i, m := range members {
+		out[i] = NewMember(m, audienceOf(m))
+	}
+	return out
+}
+
+func newMemberDetails(m models.Member) MemberDetails {
+	return MemberDetails{
+		Id:         m.Id,
+		Number:     m.Number,
+		Name:       m.Name,
+		Email:      m.Email,
+		Im:         m.Im,
+		Phone:      m.Phone,
+		Address:    m.Adress,
+		AddressURL: m.Adressurl,
+		Title:      m.Title,
+		History:    m.History,
+		Picture:    m.Picture,
+		IsValid:    m.Isvalid,
+	}
+}
+
+func isSet(s *string) bool {
+	return s != nil && *s != ""
+}
@@ -0,0 +1,48 @@
+package views
+
+import (
+	"github.com/sebastiw/sidan-backend/src/models"
+)
+
+// ProspectPublic is what anyone may see of a prospect
+type ProspectPublic struct {
+	Id      int64  `json:"id"`
+	Status  string `json:"status"`
+	Number  int64  `json:"number"`
+	History string `json:"history"`
+}
+
+// ProspectDetails is a prospect as seen by members
+type ProspectDetails struct {
+	Id      int64  `json:"id"`
+	Status  string `json:"status"`
+	Number  int64  `json:"number"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone"`
+	History string `json:"history"`
+}
+
+// NewProspect returns the representation of p for the audience
+func NewProspect(p models.Prospect, a Audience) interface{} {
+	if a == Public || a == Guest {
+		return ProspectPublic{Id: p.Id, Status: p.Status, Number: p.Number, History: p.History}
+	}
+	return ProspectDetails{
+		Id:      p.Id,
+		Status:  p.Status,
+		Number:  p.Number,
+		Name:    p.Name,
+		Email:   p.Email,
+		Phone:   p.Phone,
+		History: p.History,
+	}
+}
+
+func NewProspects(prospects []models.Prospect, a Audience) []interface{} {
+	out := make([]interface{}, len(prospects))
+	for i, p := range prospects {
+		out[i] = NewProspect(p, a)
+	}
+	return out
+}
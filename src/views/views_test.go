@@ -0,0 +1,136 @@
+package views_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastiw/sidan-backend/src/models"
+	"github.com/sebastiw/sidan-backend/src/views"
+)
+
+var audiences = []views.Audience{views.Public, views.Member, views.Self, views.Admin}
+
+func strPtr(s string) *string { return &s }
+
+func secretMember() models.Member {
+	return models.Member{
+		Id:                           1,
+		Number:                       8,
+		Name:                         strPtr("Åtta"),
+		Email:                        strPtr("atta@example.com"),
+		Title:                        strPtr("Kassör"),
+		Password:                     strPtr("$2a$10$bcrypthash"),
+		Password_classic:             strPtr("classichash"),
+		Password_resetstring:         strPtr("resetstring"),
+		Password_classic_resetstring: strPtr("classicreset"),
+	}
+}
+
+func render(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &out))
+	return out
+}
+
+func TestNewMember_NeverExposesSecrets(t *testing.T) {
+	for _, a := range audiences {
+		b, err := json.Marshal(views.NewMember(secretMember(), a))
+		require.NoError(t, err)
+		for _, secret := range []string{"bcrypthash", "classichash", "resetstring", "classicreset"} {
+			assert.NotContains(t, string(b), secret, a.String())
+		}
+		out := render(t, views.NewMember(secretMember(), a))
+		for _, key := range []string{"password", "password_classic", "password_resetstring", "password_classic_resetstring"} {
+			assert.NotContains(t, out, key, a.String())
+		}
+	}
+}
+
+func TestNewMember_Audiences(t *testing.T) {
+	public := render(t, views.NewMember(secretMember(), views.Public))
+	assert.Equal(t, float64(8), public["number"])
+	assert.NotContains(t, public, "email")
+	assert.NotContains(t, public, "name")
+
+	member := render(t, views.NewMember(secretMember(), views.Member))
+	assert.Equal(t, "atta@example.com", member["email"])
+	assert.NotContains(t, member, "has_password")
+
+	self := render(t, views.NewMember(secretMember(), views.Self))
+	assert.Equal(t, true, self["has_password"])
+	assert.Equal(t, true, self["has_classic_password"])
+}
+
+func TestNewMembers_PerMemberAudience(t *testing.T) {
+	other := secretMember()
+	other.Number = 9
+	out := views.NewMembers([]models.Member{secretMember(), other}, func(m models.Member) views.Audience {
+		if m.Number == 8 {
+			return views.Self
+		}
+		return views.Public
+	})
+	assert.IsType(t, views.MemberAccount{}, out[0])
+	assert.IsType(t, views.MemberPublic{}, out[1])
+}
+
+func TestNewProspect_PublicHidesContact(t *testing.T) {
+	p := models.Prospect{Id: 1, Number: 1234, Name: "Prospekt", Email: "p@example.com", Phone: "0701234567"}
+
+	public := render(t, views.NewProspect(p, views.Public))
+	assert.NotContains(t, public, "name")
+	assert.NotContains(t, public, "email")
+	assert.NotContains(t, public, "phone")
+
+	member := render(t, views.NewProspect(p, views.Member))
+	assert.Equal(t, "p@example.com", member["email"])
+}
+
+func TestNewEntry_PublicHidesAddresses(t *testing.T) {
+	e := models.Entry{Id: 1, Msg: "hej", Email: "atta@example.com", Ip: strPtr("10.0.0.1"), Host: strPtr("host.example.com"), HostName: "Hemma"}
+
+	public := render(t, views.NewEntry(e, views.Public))
+	assert.NotContains(t, public, "email")
+	assert.NotContains(t, public, "ip")
+	assert.NotContains(t, public, "host")
+	assert.Equal(t, "Hemma", public["host_name"])
+
+	member := render(t, views.NewEntry(e, views.Member))
+	assert.Equal(t, "atta@example.com", member["email"])
+	assert.Equal(t, "10.0.0.1", member["ip"])
+}
+
+func TestNewArr_Audiences(t *testing.T) {
+	a := models.Arr{Id: 3, Namn: strPtr("Fest"), Plats: strPtr("Hubben"), Deltagare: strPtr("#8"), Losen: strPtr("hemligt"),
+		Attendance: []models.ArrAttendance{{MemberNumber: 8, Status: models.AttendanceYes}}}
+
+	public := render(t, views.NewArr(a, views.Public))
+	for _, key := range []string{"plats", "deltagare", "kanske", "hetsade", "attendance", "guests", "losen"} {
+		assert.NotContains(t, public, key)
+	}
+	assert.Equal(t, "Fest", public["namn"])
+	assert.Equal(t, true, public["has_password"])
+
+	guest := render(t, views.NewArr(a, views.Guest))
+	assert.Equal(t, "Hubben", guest["plats"])
+	assert.Equal(t, "#8", guest["deltagare"])
+	assert.NotContains(t, guest, "losen")
+
+	for _, audience := range []views.Audience{views.Member, views.Self, views.Admin} {
+		assert.Equal(t, "hemligt", render(t, views.NewArr(a, audience))["losen"], audience.String())
+	}
+}
+
+func TestGuest_SeesOnlyPublicOfOtherResources(t *testing.T) {
+	assert.Equal(t, views.NewMember(secretMember(), views.Public), views.NewMember(secretMember(), views.Guest))
+	e := models.Entry{Id: 1, Email: "atta@example.com"}
+	assert.Equal(t, views.NewEntry(e, views.Public), views.NewEntry(e, views.Guest))
+	p := models.Prospect{Id: 1, Email: "prospect@example.com"}
+	assert.Equal(t, views.NewProspect(p, views.Public), views.NewProspect(p, views.Guest))
+}